@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/api/v1"
+)
+
+// exportFilters are the flags of the export subcommand passed as filters of the export endpoint
+var exportFilters = []struct {
+	flag, param, usage string
+}{
+	{"direction", "direction", "keep the transactions going this way: in, out or self"},
+	{"counterparty", "counterparty", "keep the transactions exchanged with this address"},
+	{"from-block", "fromBlock", "first block of the statement"},
+	{"to-block", "toBlock", "last block of the statement"},
+	{"from-time", "fromTime", "start of the statement, RFC 3339 or unix seconds"},
+	{"to-time", "toTime", "end of the statement, RFC 3339 or unix seconds"},
+	{"status", "status", "keep the transactions of this status: success or failed"},
+}
+
+// runExport downloads the statement of an address from a running server, tx-parser export -address ...
+// The statement is streamed to stdout, or to the -o file which only appears once it is complete.
+func runExport(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("tx-parser export", flag.ContinueOnError)
+	server := fs.String("server", "http://localhost:8080", "base URL of the tx-parser server")
+	address := fs.String("address", "", "address of the statement (required)")
+	chain := fs.String("chain", "", "chain of the address, the default chain of the server when empty")
+	format := fs.String("format", "csv", "format of the statement: csv or ndjson")
+	tz := fs.String("tz", "UTC", "IANA time zone of the timestamps, e.g. Europe/Paris")
+	output := fs.String("o", "", "file of the statement, stdout when empty")
+	filters := make(map[string]*string, len(exportFilters))
+	for _, f := range exportFilters {
+		filters[f.param] = fs.String(f.flag, "", f.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *address == "" {
+		return errors.New("-address is required")
+	}
+
+	query := url.Values{"address": {*address}, "format": {*format}, "tz": {*tz}}
+	if *chain != "" {
+		query.Set("chain", *chain)
+	}
+	for param, value := range filters {
+		if *value != "" {
+			query.Set(param, *value)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(*server, "/")+api.RouteExport+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error.Code == "" {
+			return fmt.Errorf("export failed: %s", resp.Status)
+		}
+		return fmt.Errorf("export failed: %s: %s", body.Error.Code, body.Error.Message)
+	}
+
+	if *output == "" {
+		if _, err := io.Copy(stdout, resp.Body); err != nil {
+			return fmt.Errorf("export cut: %w", err)
+		}
+		return nil
+	}
+	return writeFile(*output, resp.Body)
+}
+
+// writeFile writes the statement next to path and renames it once it is complete, so that a cut
+// statement is never taken for a whole one
+func writeFile(path string, r io.Reader) error {
+	partial := path + ".part"
+	f, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("export cut: %w", err)
+	}
+
+	return os.Rename(partial, path)
+}
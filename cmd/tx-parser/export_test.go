@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const statement = "block,timestamp,hash,direction,from,to,value,status,type\n16,2024-01-19T16:12:11Z,0x01,inbound,0xa,0xb,1.5,success,0x2\n"
+
+func TestRunExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/transactions/export", r.URL.Path)
+		query := r.URL.Query()
+		switch query.Get("address") {
+		case "0xa":
+			assert.Equal(t, "csv", query.Get("format"))
+			assert.Equal(t, "Europe/Paris", query.Get("tz"))
+			assert.Equal(t, "in", query.Get("direction"))
+			assert.Equal(t, "16", query.Get("fromBlock"))
+			w.Write([]byte(statement))
+		case "0xcut":
+			w.Write([]byte(statement))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"address_not_found","message":"address not found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	err := runExport(context.Background(), []string{"-server", srv.URL, "-address", "0xa", "-tz", "Europe/Paris",
+		"-direction", "in", "-from-block", "16"}, &stdout)
+	assert.NoError(t, err)
+	assert.Equal(t, statement, stdout.String())
+
+	path := filepath.Join(t.TempDir(), "statement.csv")
+	err = runExport(context.Background(), []string{"-server", srv.URL, "-address", "0xa", "-tz", "Europe/Paris",
+		"-direction", "in", "-from-block", "16", "-o", path}, &stdout)
+	assert.NoError(t, err)
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, statement, string(written))
+
+	err = runExport(context.Background(), []string{"-server", srv.URL, "-address", "0xb"}, &stdout)
+	assert.EqualError(t, err, "export failed: address_not_found: address not found")
+
+	// a cut statement leaves no file
+	path = filepath.Join(t.TempDir(), "cut.csv")
+	err = runExport(context.Background(), []string{"-server", srv.URL, "-address", "0xcut", "-o", path}, &stdout)
+	assert.ErrorContains(t, err, "export cut")
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, path+".part")
+
+	err = runExport(context.Background(), []string{"-server", srv.URL}, &stdout)
+	assert.EqualError(t, err, "-address is required")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+// shutdownFn releases a resource, it must return before ctx is done
+type shutdownFn func(ctx context.Context) error
+
+// closeShare is the part of the shutdown timeout reserved to the closers, a third of it, so that
+// a drain using up its time does not leave them an expired context
+const closeShare = 3
+
+// lifecycle starts the crawler runners and the HTTP server, then shuts them down
+// gracefully on SIGINT/SIGTERM within the shutdown timeout.
+type lifecycle struct {
+	server  *http.Server
+	runners []*runner
+	// drainTimeout bounds the drain of the server and the runners, closeTimeout the closers after it
+	drainTimeout time.Duration
+	closeTimeout time.Duration
+	// drainers run once the server and the runners are stopped, within the drain timeout, e.g. the bulk jobs
+	drainers []shutdownFn
+	// closers run once the drain is over, e.g. flushing storage
+	closers []shutdownFn
+}
+
+func newLifecycle(server *http.Server, shutdownTimeout time.Duration, runners ...*runner) *lifecycle {
+	closeTimeout := shutdownTimeout / closeShare
+	return &lifecycle{
+		server:       server,
+		runners:      runners,
+		drainTimeout: shutdownTimeout - closeTimeout,
+		closeTimeout: closeTimeout,
+	}
+}
+
+// OnDrain registers a function to drain the work started by the requests, it runs after the server
+// and the runners are stopped and before the closers
+func (l *lifecycle) OnDrain(fn shutdownFn) {
+	l.drainers = append(l.drainers, fn)
+}
+
+// OnShutdown registers a function to run after the drain
+func (l *lifecycle) OnShutdown(fn shutdownFn) {
+	l.closers = append(l.closers, fn)
+}
+
+// Run blocks until a termination signal is received or the server fails, then shuts down.
+func (l *lifecycle) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return l.run(ctx)
+}
+
+func (l *lifecycle) run(ctx context.Context) error {
+	for _, r := range l.runners {
+		r.Start()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", slog.String("addr", l.server.Addr))
+		if err := l.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("received termination signal, shutting down")
+	case runErr = <-serveErr:
+		slog.Error("server stopped with error", logging.Err(runErr))
+	}
+
+	return errors.Join(runErr, l.shutdown())
+}
+
+func (l *lifecycle) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.drainTimeout)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		serverErr  error
+		runnerErrs = make([]error, len(l.runners))
+	)
+	wg.Add(1 + len(l.runners))
+	go func() {
+		defer wg.Done()
+		// stop accepting connections and drain in-flight requests
+		serverErr = l.server.Shutdown(ctx)
+	}()
+	for i, r := range l.runners {
+		i, r := i, r
+		go func() {
+			defer wg.Done()
+			// let the current crawl tick finish, cancel it if it exceeds the timeout
+			runnerErrs[i] = r.Stop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	// the server no longer starts work, drain what the requests left running
+	errs := append([]error{serverErr}, runnerErrs...)
+	drainErrs := make([]error, len(l.drainers))
+	wg.Add(len(l.drainers))
+	for i, drainFn := range l.drainers {
+		i, drainFn := i, drainFn
+		go func() {
+			defer wg.Done()
+			drainErrs[i] = drainFn(ctx)
+		}()
+	}
+	wg.Wait()
+	errs = append(errs, drainErrs...)
+
+	// the closers get their own time, whatever the drain took
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), l.closeTimeout)
+	defer cancelClose()
+	for _, closeFn := range l.closers {
+		errs = append(errs, closeFn(closeCtx))
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		slog.Error("shutdown with error", logging.Err(err))
+		return err
+	}
+
+	slog.Info("shutdown completed")
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedInterval schedules the ticks on a fixed interval
+func fixedInterval(interval time.Duration) scheduleFn {
+	return func(error) time.Duration { return interval }
+}
+
+func TestRunner_StopWaitsForTick(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var finished atomic.Bool
+	r := newRunner(func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return ctx.Err()
+	}, fixedInterval(time.Millisecond), time.Second)
+	r.Start()
+	<-started
+
+	err := r.Stop(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, finished.Load())
+}
+
+func TestRunner_StopCancelsTick(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var tickErr atomic.Value
+	r := newRunner(func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		tickErr.Store(ctx.Err())
+		return ctx.Err()
+	}, fixedInterval(time.Millisecond), time.Minute)
+	r.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := r.Stop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, tickErr.Load().(error), context.Canceled)
+}
+
+func TestLifecycle_Shutdown(t *testing.T) {
+	server := &http.Server{Addr: "127.0.0.1:0", Handler: http.NewServeMux()}
+	var ethTicks, bscTicks atomic.Int32
+	eth := newRunner(func(ctx context.Context) error { ethTicks.Add(1); return nil }, fixedInterval(time.Millisecond), time.Second)
+	bsc := newRunner(func(ctx context.Context) error { bscTicks.Add(1); return nil }, fixedInterval(time.Millisecond), time.Second)
+
+	app := newLifecycle(server, time.Second, eth, bsc)
+	var flushed atomic.Bool
+	app.OnShutdown(func(ctx context.Context) error {
+		flushed.Store(true)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.run(ctx) }()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("lifecycle did not shut down")
+	}
+	assert.True(t, flushed.Load())
+	assert.Positive(t, ethTicks.Load())
+	assert.Positive(t, bscTicks.Load())
+	assert.ErrorIs(t, server.ListenAndServe(), http.ErrServerClosed)
+}
+
+func TestLifecycle_Shutdown_slowDrain(t *testing.T) {
+	server := &http.Server{Addr: "127.0.0.1:0", Handler: http.NewServeMux()}
+	started := make(chan struct{}, 1)
+	// the tick ignores the stop until it is canceled, the drain uses up its time
+	slow := newRunner(func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, fixedInterval(time.Millisecond), time.Minute)
+
+	app := newLifecycle(server, 300*time.Millisecond, slow)
+	var flushErr error
+	var flushLeft time.Duration
+	app.OnShutdown(func(ctx context.Context) error {
+		flushErr = ctx.Err()
+		deadline, _ := ctx.Deadline()
+		flushLeft = time.Until(deadline)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.run(ctx) }()
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("lifecycle did not shut down")
+	}
+	// the flush still has the time reserved to the closers
+	assert.NoError(t, flushErr)
+	assert.Greater(t, flushLeft, 50*time.Millisecond)
+}
+
+func TestLifecycle_Shutdown_drainBeforeClose(t *testing.T) {
+	server := &http.Server{Addr: "127.0.0.1:0", Handler: http.NewServeMux()}
+	app := newLifecycle(server, time.Second)
+	var drained, drainedBeforeClose atomic.Bool
+	app.OnDrain(func(ctx context.Context) error {
+		// the server no longer accepts requests
+		assert.ErrorIs(t, server.ListenAndServe(), http.ErrServerClosed)
+		time.Sleep(20 * time.Millisecond)
+		drained.Store(true)
+		return nil
+	})
+	app.OnShutdown(func(ctx context.Context) error {
+		drainedBeforeClose.Store(drained.Load())
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.run(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("lifecycle did not shut down")
+	}
+	assert.True(t, drainedBeforeClose.Load())
+}
@@ -2,60 +2,441 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
+	// the time zones of the statements do not depend on the system database
+	_ "time/tzdata"
 
+	"github.com/TrustWallet/tx-parser/internal/abi"
+	"github.com/TrustWallet/tx-parser/internal/alerting"
+	"github.com/TrustWallet/tx-parser/internal/analytics"
 	"github.com/TrustWallet/tx-parser/internal/api/v1"
+	"github.com/TrustWallet/tx-parser/internal/cache"
+	"github.com/TrustWallet/tx-parser/internal/config"
 	"github.com/TrustWallet/tx-parser/internal/crawler"
+	"github.com/TrustWallet/tx-parser/internal/health"
+	"github.com/TrustWallet/tx-parser/internal/labels"
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/metrics"
 	"github.com/TrustWallet/tx-parser/internal/parser"
 	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/utils"
 )
 
-type runFn func(ctx context.Context) error
-
 func main() {
-	repo := repository.NewInMemRepo()
-	cli := crawler.NewEthereumClient(crawler.EthNodeUrl)
-	crawler := crawler.NewEthereumCrawler(repo, cli)
-	parser := parser.NewParserService(repo)
-	register := api.NewRegister(parser)
-
-	// Run the interval job
-	_ = runner(crawler.Run)
-
-	// Run the APIs
-	http.HandleFunc("/subscribe", register.SubscribeHandler)
-	http.HandleFunc("/current-block", register.GetCurrentBlockHandler)
-	http.HandleFunc("/transactions", register.GetTransactionsHandler)
-
-	err := http.ListenAndServe(":8080", nil)
-	if err != nil {
-		panic(err)
-	}
-}
-
-func runner(fn runFn) chan<- struct{} {
-	// Create a ticker that ticks every 4 seconds.
-	ticker := time.NewTicker(4 * time.Second)
-	// Use a channel to signal the stop of the program.
-	quit := make(chan struct{})
-
-	// Start a goroutine that executes job.
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				err := fn(context.Background())
-				if err != nil {
-					log.Printf("Error executing job: %v", err)
-				}
-			case <-quit:
-				ticker.Stop()
-				return
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runExport(ctx, os.Args[2:], os.Stdout)
+		stop()
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fatal("invalid configuration", err)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("invalid log configuration", err)
+	}
+	slog.SetDefault(logger)
+	slog.Info("effective configuration", slog.String("config", cfg.String()))
+
+	// One repository, crawler and parser per chain
+	chains := cfg.ChainList()
+	parsers := make(map[string]parser.Parser, len(chains))
+	var (
+		repos        []repository.Repository
+		runners      []*runner
+		healthChains []health.Chain
+		// stopBulkJobs drain the asynchronous bulk jobs of the parsers
+		stopBulkJobs []shutdownFn
+	)
+	// The chains calling the same endpoint share its budget, the chains share the cache
+	limiter, err := newRateLimiter(cfg.RateLimits, cfg.QuotaFile)
+	if err != nil {
+		fatal("error loading quota usage", err)
+	}
+	resultCache, err := newCache(cfg.Cache)
+	if err != nil {
+		fatal("error creating cache", err)
+	}
+	alerts, err := newAlerts(cfg.Alerts)
+	if err != nil {
+		fatal("error loading alert rules", err)
+	}
+	book, err := newLabelBook(cfg.Labels)
+	if err != nil {
+		fatal("error loading label book", err)
+	}
+	abis, err := newABIRegistry(cfg.ABI)
+	if err != nil {
+		fatal("error loading ABIs", err)
+	}
+	for _, chainCfg := range chains {
+		repo, err := newRepository(cfg.Storage)
+		if err != nil {
+			fatal("error creating repository", err)
+		}
+		// the stats of the addresses are updated as the crawler saves the blocks
+		tracker := analytics.NewTracker(repo)
+		alerter, err := alerts.engine(chainCfg)
+		if err != nil {
+			fatal("error creating alerts", err)
+		}
+		chainCrawler, parserOpts, err := newChainCrawler(tracker, chainCfg, alerter, limiter, resultCache, cfg.Cache.Finality, cfg.Crawler.TickTimeout.Std())
+		if err != nil {
+			fatal("error creating crawler", err)
+		}
+		chainAttr := slog.String(logging.KeyChain, chainCfg.Name)
+		run := func(ctx context.Context) error {
+			return chainCrawler.Run(logging.WithAttrs(ctx, chainAttr))
+		}
+
+		pacer := crawler.NewPacer(crawler.PacerOptions{
+			Chain:     chainCfg.Name,
+			Min:       cfg.Crawler.MinPollInterval.Std(),
+			Max:       cfg.Crawler.MaxPollInterval.Std(),
+			BlockTime: chainCfg.BlockTime.Std(),
+			Initial:   chainCfg.PollInterval(cfg.Crawler.PollInterval),
+		})
+		schedule := func(err error) time.Duration {
+			return pacer.Next(chainCrawler.Progress(), err, time.Now())
+		}
+
+		repos = append(repos, repo)
+		parserOpts = append(parserOpts, parser.WithLabelBook(book.ForChain(chainCfg.Name)))
+		parserSvc := parser.NewParserService(tracker, parserOpts...)
+		parsers[chainCfg.Name] = parserSvc
+		stopBulkJobs = append(stopBulkJobs, parserSvc.StopBulkJobs)
+		runners = append(runners, newRunner(run, schedule, cfg.Crawler.TickTimeout.Std()))
+		healthChains = append(healthChains, health.Chain{
+			Name:          chainCfg.Name,
+			Crawler:       chainCrawler,
+			Repo:          repo,
+			Confirmations: chainCfg.Confirmations,
+		})
+		slog.Info("chain configured", chainAttr, slog.String("type", chainCfg.Type), slog.Uint64("chain_id", chainCfg.ChainID))
+	}
+
+	registerOpts := []api.RegisterOption{api.WithABIRegistry(abis)}
+	if alerts.feed != nil {
+		registerOpts = append(registerOpts, api.WithAlertFeed(alerts.feed))
+	}
+	register := api.NewRegister(parsers, chains[0].Name, api.RouteTimeouts{
+		Default: cfg.Server.RequestTimeout.Std(),
+		Routes: map[string]time.Duration{
+			api.RouteBulkSubscriptions: cfg.Server.BulkRequestTimeout.Std(),
+			api.RouteExport:            cfg.Server.ExportRequestTimeout.Std(),
+		},
+	}, registerOpts...)
+
+	// The APIs
+	mux := http.NewServeMux()
+	handle := func(route string, handler http.HandlerFunc) {
+		mux.HandleFunc(route, api.Instrument(route, handler))
+	}
+	handle(api.RouteSubscribe, register.SubscribeHandler)
+	handle(api.RouteCurrentBlock, register.GetCurrentBlockHandler)
+	handle(api.RouteTransactions, register.GetTransactionsHandler)
+	handle(api.RouteTransaction, register.GetTransactionHandler)
+	handle(api.RouteExport, register.ExportTransactionsHandler)
+	handle(api.RouteBlocks, register.GetBlockTransactionsHandler)
+	handle(api.RouteAddresses, register.AddressHandler)
+	handle(api.RouteAlerts, register.GetAlertsHandler)
+	handle(api.RouteBulkSubscriptions, register.BulkSubscriptionsHandler)
+	handle(api.RouteBulkJobs, register.GetBulkJobHandler)
+	handle(api.RouteBulkJobIssues, register.GetBulkJobIssuesHandler)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	checker := health.NewChecker(health.Options{
+		MaxCrawlAge: cfg.Health.MaxCrawlAge.Std(),
+		MaxBlockLag: cfg.Health.MaxBlockLag,
+	}, healthChains...)
+	mux.HandleFunc("/healthz", checker.LivenessHandler)
+	mux.HandleFunc("/readyz", checker.ReadinessHandler)
+	registerSubscriptionGauge(repos)
+
+	server := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      api.RequestID(mux),
+		ReadTimeout:  cfg.Server.ReadTimeout.Std(),
+		WriteTimeout: cfg.Server.WriteTimeout.Std(),
+		IdleTimeout:  cfg.Server.IdleTimeout.Std(),
+	}
+
+	app := newLifecycle(server, cfg.Server.ShutdownTimeout.Std(), runners...)
+	// the bulk jobs save subscriptions, they finish before the storage is flushed
+	for _, stop := range stopBulkJobs {
+		app.OnDrain(stop)
+	}
+	for _, repo := range repos {
+		if flusher, ok := repo.(repository.Flusher); ok {
+			app.OnShutdown(flusher.Flush)
+		}
+	}
+	app.OnShutdown(limiter.Flush)
+	if alerts.webhook != nil {
+		app.OnShutdown(alerts.webhook.Close)
+	}
+
+	if err := app.Run(); err != nil {
+		fatal("exit with error", err)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.Err(err))
+	os.Exit(1)
+}
+
+// newRepository creates the repository of the configured storage backend
+func newRepository(cfg config.StorageConfig) (repository.Repository, error) {
+	switch cfg.Backend {
+	case config.StorageMemory:
+		return repository.NewInMemRepo(repository.WithRetainedBlocks(cfg.RetainBlocks)), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", cfg.Backend)
+	}
+}
+
+// chainCrawler is the crawler of a chain, whatever its type
+type chainCrawler interface {
+	crawler.Crawler
+	health.CrawlStatus
+	Progress() crawler.Progress
+}
+
+// newChainCrawler creates the client and the crawler of the chain type, the parser
+// options validate the addresses of the chain
+func newChainCrawler(repo repository.Repository, chainCfg config.ChainConfig, alerter crawler.Alerter, limiter *crawler.RateLimiter,
+	resultCache cache.Cache, finality uint64, verifyTimeout time.Duration) (
+	chainCrawler, []parser.Option, error) {
+	opts := crawler.Options{
+		Chain:         chainCfg.Name,
+		StartBlock:    chainCfg.StartBlock,
+		Confirmations: chainCfg.Confirmations,
+		Alerter:       alerter,
+
+		Traces:                   chainCfg.Traces,
+		AutoSubscribeDeployments: chainCfg.AutoSubscribeDeployments,
+	}
+	cacheOpts := crawler.CacheOptions{Chain: chainCfg.Name, Finality: finality}
+	if chainCfg.Finality > 0 {
+		cacheOpts.Finality = chainCfg.Finality
+	}
+
+	switch chainCfg.Type {
+	case config.ChainTypeBitcoin:
+		rpcCli := crawler.NewBitcoinClient(chainCfg.Endpoints[0], chainCfg.Endpoints[1:]...)
+		rpcCli.SetRateLimiter(limiter)
+		var cli crawler.BitcoinClient = rpcCli
+		if resultCache != nil {
+			genesis, err := genesisHash(rpcCli, chainCfg, verifyTimeout)
+			if err != nil {
+				return nil, nil, err
 			}
+			cacheOpts.Network = genesis
+			cli = crawler.NewCachingBitcoinClient(rpcCli, resultCache, cacheOpts)
 		}
-	}()
+		parserOpts := []parser.Option{parser.WithAddressValidator(utils.ValidateBitcoinAddress), parser.WithDecimals(chainDecimals(chainCfg)),
+			parser.WithBareTxHashes()}
+		return crawler.NewBitcoinCrawler(repo, cli, opts), parserOpts, nil
+	default:
+		rpcCli := crawler.NewEthereumClient(chainCfg.Endpoints[0], chainCfg.Endpoints[1:]...)
+		rpcCli.SetRateLimiter(limiter)
+		if err := verifyChainID(rpcCli, chainCfg, verifyTimeout); err != nil {
+			return nil, nil, err
+		}
+		var cli crawler.Client = rpcCli
+		if resultCache != nil {
+			cacheOpts.Network = strconv.FormatUint(chainCfg.ChainID, 10)
+			cli = crawler.NewCachingClient(rpcCli, resultCache, cacheOpts)
+		}
+		// the transactions which are not stored are looked up on the node, without the cache
+		// since they may still be pending
+		parserOpts := []parser.Option{parser.WithTransactionFetcher(rpcCli.GetTransactionByHash)}
+		return crawler.NewEthereumCrawler(repo, cli, opts), parserOpts, nil
+	}
+}
+
+// chainDecimals return the number of decimals of the native unit of the chain
+func chainDecimals(chainCfg config.ChainConfig) int {
+	if chainCfg.Type == config.ChainTypeBitcoin {
+		return 8
+	}
+	return 18
+}
+
+// alerts are the rules and the sinks of the alerts shared by the chains
+type alerts struct {
+	rules []alerting.Rule
+	sinks []alerting.Sink
+	// feed and webhook are nil when they are disabled
+	feed    *alerting.Feed
+	webhook *alerting.WebhookSink
+}
+
+// newAlerts loads the alert rules and creates their sinks, the alerts are disabled without rules file
+func newAlerts(cfg config.AlertsConfig) (*alerts, error) {
+	a := &alerts{}
+	if cfg.RulesFile == "" {
+		return a, nil
+	}
+
+	rules, err := alerting.LoadRules(cfg.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	a.rules = rules
+	a.sinks = []alerting.Sink{alerting.LogSink{}}
+	if cfg.FeedSize > 0 {
+		a.feed = alerting.NewFeed(int(cfg.FeedSize))
+		a.sinks = append(a.sinks, a.feed)
+	}
+	if cfg.Webhook != "" {
+		a.webhook = alerting.NewWebhookSink(cfg.Webhook, alerting.WebhookOptions{Retries: 3})
+		a.sinks = append(a.sinks, a.webhook)
+	}
+	slog.Info("alerts enabled", slog.Int("rules", len(rules)), slog.Bool("webhook", a.webhook != nil))
+
+	return a, nil
+}
 
-	return quit
+// engine return the alerter of the chain, nil when the alerts are disabled
+func (a *alerts) engine(chainCfg config.ChainConfig) (crawler.Alerter, error) {
+	if len(a.rules) == 0 {
+		return nil, nil
+	}
+	return alerting.NewEngine(a.rules, a.sinks, alerting.Options{Chain: chainCfg.Name, Decimals: chainDecimals(chainCfg)})
+}
+
+// newLabelBook loads the label book of the well-known addresses, nil when there is none
+func newLabelBook(cfg config.LabelsConfig) (*labels.Book, error) {
+	if cfg.BookFile == "" {
+		return nil, nil
+	}
+
+	book, err := labels.LoadBook(cfg.BookFile)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("label book loaded", slog.Int("addresses", book.Len()))
+	return book, nil
+}
+
+// newABIRegistry creates the registry of the built-in ABIs and of the ABI files of the directory
+func newABIRegistry(cfg config.ABIConfig) (*abi.Registry, error) {
+	abis, err := abi.Builtin()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Dir != "" {
+		if err := abis.LoadDir(cfg.Dir); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("ABIs loaded", slog.Int("entries", abis.Len()))
+	return abis, nil
+}
+
+// newCache creates the cache of the immutable RPC results, nil when it is disabled
+func newCache(cfg config.CacheConfig) (cache.Cache, error) {
+	if cfg.MemoryBytes == 0 {
+		return nil, nil
+	}
+	memory := cache.NewMemory(int64(cfg.MemoryBytes))
+	if cfg.Dir == "" {
+		return memory, nil
+	}
+
+	disk, err := cache.NewDisk(cfg.Dir, int64(cfg.DiskBytes))
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewTiered(memory, disk), nil
+}
+
+// newRateLimiter creates the limiter of the RPC endpoints with a request budget, their quota usage
+// is kept in quotaFile when it is set
+func newRateLimiter(limits map[string]config.RateLimitConfig, quotaFile string) (*crawler.RateLimiter, error) {
+	rateLimits := make(map[string]crawler.RateLimit, len(limits))
+	for host, limit := range limits {
+		rateLimits[host] = crawler.RateLimit{
+			Rate:         limit.Rate,
+			Burst:        limit.Burst,
+			Quota:        limit.Quota,
+			QuotaPeriod:  limit.QuotaPeriod.Std(),
+			QuotaStart:   limit.QuotaStart,
+			QuotaMonthly: limit.QuotaMonthly,
+			Weights:      limit.Weights,
+		}
+	}
+	if quotaFile == "" {
+		return crawler.NewRateLimiter(rateLimits), nil
+	}
+
+	store, err := crawler.OpenQuotaFile(quotaFile)
+	if err != nil {
+		return nil, err
+	}
+	return crawler.NewRateLimiter(rateLimits, crawler.WithQuotaStore(store)), nil
+}
+
+// verifyChainID fails when the RPC endpoints do not serve the configured chain
+func verifyChainID(cli crawler.Client, chainCfg config.ChainConfig, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := crawler.VerifyChainID(ctx, cli, chainCfg.ChainID); err != nil {
+		return fmt.Errorf("chain %s: %w", chainCfg.Name, err)
+	}
+	return nil
+}
+
+// genesisHash return the hash of the genesis block of the bitcoin chain, which identifies its network
+func genesisHash(cli crawler.BitcoinClient, chainCfg config.ChainConfig, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	hash, err := cli.GetBlockHash(ctx, 0)
+	if err != nil {
+		return "", fmt.Errorf("chain %s: get genesis block hash: %w", chainCfg.Name, err)
+	}
+	return hash, nil
+}
+
+// registerSubscriptionGauge exposes the number of subscribed addresses of all chains,
+// computed on every scrape
+func registerSubscriptionGauge(repos []repository.Repository) {
+	metrics.Default.NewGaugeFunc("txparser_subscribed_addresses", "Number of subscribed addresses.", func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		total := 0
+		for _, repo := range repos {
+			addresses, err := repo.GetAddresses(ctx)
+			if err != nil {
+				return math.NaN()
+			}
+			total += len(addresses)
+		}
+		return float64(total)
+	})
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+type runFn func(ctx context.Context) error
+
+// scheduleFn return the delay before the next tick given the error of the last one.
+type scheduleFn func(err error) time.Duration
+
+// runner executes a job one tick at a time, each tick scheduled after the previous one.
+type runner struct {
+	fn      runFn
+	next    scheduleFn
+	timeout time.Duration
+
+	// ctx is the parent of every tick, it is canceled to abort the in-flight tick
+	ctx    context.Context
+	cancel context.CancelFunc
+	quit   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newRunner(fn runFn, next scheduleFn, timeout time.Duration) *runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &runner{
+		fn:      fn,
+		next:    next,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start runs the job in background until Stop is called.
+func (r *runner) Start() {
+	// The first tick is scheduled as if the previous one succeeded.
+	timer := time.NewTimer(r.next(nil))
+
+	// Start a goroutine that executes job.
+	go func() {
+		defer close(r.done)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				timer.Reset(r.next(r.tick()))
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (r *runner) tick() error {
+	// Bound every tick so a stuck RPC call can not block the next ones.
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+	// Correlate the log records of the tick, i.e. of the parsed block.
+	ctx = logging.WithAttrs(ctx, slog.String(logging.KeyCorrelationID, logging.NewID()))
+
+	err := r.fn(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error executing job", logging.Err(err))
+	}
+	return err
+}
+
+// Stop stops scheduling new ticks and waits for the in-flight one to finish.
+// The in-flight tick is canceled when ctx is done before it finishes.
+func (r *runner) Stop(ctx context.Context) error {
+	r.once.Do(func() { close(r.quit) })
+
+	select {
+	case <-r.done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		// the tick observes the cancellation, wait for it to return
+		<-r.done
+		return ctx.Err()
+	}
+}
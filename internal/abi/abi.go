@@ -0,0 +1,135 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Method is a contract function
+type Method struct {
+	Name   string
+	Inputs []Argument
+	// Signature is the canonical signature, e.g. "transfer(address,uint256)"
+	Signature string
+	// Selector is the first 4 bytes of the keccak256 hash of Signature
+	Selector [4]byte
+}
+
+// Event is a contract event
+type Event struct {
+	Name      string
+	Inputs    []Argument
+	Anonymous bool
+	// Signature is the canonical signature, e.g. "Transfer(address,address,uint256)"
+	Signature string
+	// ID is the keccak256 hash of Signature, the first topic of its logs
+	ID [32]byte
+}
+
+// ABI is the functions and the events of a contract
+type ABI struct {
+	Methods []Method
+	Events  []Event
+}
+
+// jsonArgument is a parameter of the JSON ABI
+type jsonArgument struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Indexed    bool           `json:"indexed"`
+	Components []jsonArgument `json:"components"`
+}
+
+// jsonEntry is an entry of the JSON ABI, the constructors, errors, fallback and receive entries are ignored
+type jsonEntry struct {
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	Inputs    []jsonArgument `json:"inputs"`
+	Anonymous bool           `json:"anonymous"`
+}
+
+// Parse decodes a JSON ABI, either the array of entries or a compiler artifact with an "abi" field
+func Parse(r io.Reader) (*ABI, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read abi: %w", err)
+	}
+
+	var entries []jsonEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var artifact struct {
+			ABI []jsonEntry `json:"abi"`
+		}
+		if artifactErr := json.Unmarshal(data, &artifact); artifactErr != nil || artifact.ABI == nil {
+			return nil, fmt.Errorf("parse abi: %w", err)
+		}
+		entries = artifact.ABI
+	}
+
+	var (
+		contract ABI
+		errs     []error
+	)
+	for i, entry := range entries {
+		switch entry.Type {
+		case "function", "":
+			inputs, err := parseArguments(entry.Inputs)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("abi[%d] %s: %w", i, entry.Name, err))
+				continue
+			}
+			contract.Methods = append(contract.Methods, newMethod(entry.Name, inputs))
+		case "event":
+			inputs, err := parseArguments(entry.Inputs)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("abi[%d] %s: %w", i, entry.Name, err))
+				continue
+			}
+			contract.Events = append(contract.Events, newEvent(entry.Name, inputs, entry.Anonymous))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return &contract, nil
+}
+
+func parseArguments(args []jsonArgument) ([]Argument, error) {
+	parsed := make([]Argument, len(args))
+	for i, arg := range args {
+		typ, err := parseType(arg.Type, arg.Components)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = Argument{Name: arg.Name, Type: typ, Indexed: arg.Indexed}
+	}
+	return parsed, nil
+}
+
+func newMethod(name string, inputs []Argument) Method {
+	m := Method{Name: name, Inputs: inputs, Signature: name + "(" + joinTypes(inputs) + ")"}
+	copy(m.Selector[:], utils.Keccak256([]byte(m.Signature)))
+	return m
+}
+
+func newEvent(name string, inputs []Argument, anonymous bool) Event {
+	e := Event{Name: name, Inputs: inputs, Anonymous: anonymous, Signature: name + "(" + joinTypes(inputs) + ")"}
+	copy(e.ID[:], utils.Keccak256([]byte(e.Signature)))
+	return e
+}
+
+// SelectorHex return the 0x-hex selector of the method
+func (m Method) SelectorHex() string {
+	return "0x" + hex.EncodeToString(m.Selector[:])
+}
+
+// IDHex return the 0x-hex topic of the event
+func (e Event) IDHex() string {
+	return "0x" + hex.EncodeToString(e.ID[:])
+}
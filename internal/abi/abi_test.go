@@ -0,0 +1,72 @@
+package abi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		typ        string
+		components []jsonArgument
+		want       string
+		dynamic    bool
+		err        string
+	}{
+		{"uint", nil, "uint256", false, ""},
+		{"int24", nil, "int24", false, ""},
+		{"bytes32", nil, "bytes32", false, ""},
+		{"bytes", nil, "bytes", true, ""},
+		{"address[]", nil, "address[]", true, ""},
+		{"uint256[2][]", nil, "uint256[2][]", true, ""},
+		{"string[3]", nil, "string[3]", true, ""},
+		{"tuple", []jsonArgument{{Name: "a", Type: "address"}, {Name: "fee", Type: "uint24"}}, "(address,uint24)", false, ""},
+		{"tuple[]", []jsonArgument{{Name: "path", Type: "bytes"}}, "(bytes)[]", true, ""},
+		{"uint7", nil, "", false, `invalid type "uint7"`},
+		{"bytes33", nil, "", false, `invalid type "bytes33"`},
+		{"uint256[0]", nil, "", false, "invalid array length"},
+		{"fixed128x18", nil, "", false, `unsupported type "fixed128x18"`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.typ, func(t *testing.T) {
+			typ, err := parseType(tt.typ, tt.components)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, typ.String())
+			assert.Equal(t, tt.dynamic, typ.dynamic())
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	contract, err := Parse(strings.NewReader(`{"contractName": "Vault", "abi": [
+		{"type": "constructor", "inputs": [{"name": "owner", "type": "address"}]},
+		{"type": "function", "name": "transfer", "inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint"}]},
+		{"type": "event", "name": "Transfer", "inputs": [
+			{"name": "from", "type": "address", "indexed": true},
+			{"name": "to", "type": "address", "indexed": true},
+			{"name": "value", "type": "uint256"}
+		]},
+		{"type": "fallback"}
+	]}`))
+	assert.NoError(t, err)
+	if assert.Len(t, contract.Methods, 1) {
+		assert.Equal(t, "transfer(address,uint256)", contract.Methods[0].Signature)
+		assert.Equal(t, "0xa9059cbb", contract.Methods[0].SelectorHex())
+	}
+	if assert.Len(t, contract.Events, 1) {
+		assert.Equal(t, "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", contract.Events[0].IDHex())
+	}
+
+	_, err = Parse(strings.NewReader(`[{"type": "function", "name": "swap", "inputs": [{"name": "x", "type": "fixed"}]}]`))
+	assert.ErrorContains(t, err, `abi[0] swap: unsupported type "fixed"`)
+
+	_, err = Parse(strings.NewReader(`{"functions": []}`))
+	assert.ErrorContains(t, err, "parse abi")
+}
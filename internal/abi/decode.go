@@ -0,0 +1,244 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+var (
+	ErrInvalidData     = errors.New("invalid abi data")
+	ErrUnknownSelector = errors.New("unknown selector")
+	ErrUnknownEvent    = errors.New("unknown event")
+	ErrTooLarge        = errors.New("decoded values too large")
+)
+
+// wordSize is the size of an ABI slot
+const wordSize = 32
+
+// Arg is a decoded argument. Value is a decimal string for the integers, an EIP-55 string for the addresses,
+// a 0x-hex string for the bytes, a bool, a string, a []interface{} for the arrays and a []Arg for the tuples.
+type Arg struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// maxDecodedSize caps the size of the values decoded of a call or a log, in characters of their strings,
+// so that forged data can't be rendered into a large response
+const maxDecodedSize = 1 << 20
+
+// DecodeArguments decodes the values of args encoded in data
+func DecodeArguments(args []Argument, data []byte) ([]Arg, error) {
+	types := make([]Type, len(args))
+	for i, arg := range args {
+		types[i] = arg.Type
+	}
+	d := decoder{budget: maxDecodedSize}
+	values, _, err := d.decodeSequence(types, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return namedArgs(args, values), nil
+}
+
+func namedArgs(args []Argument, values []interface{}) []Arg {
+	decoded := make([]Arg, len(args))
+	for i, arg := range args {
+		decoded[i] = Arg{Name: arg.Name, Type: arg.Type.String(), Value: values[i]}
+	}
+	return decoded
+}
+
+// decoder decodes the values of a call or a log within a budget of decoded characters
+type decoder struct {
+	budget int
+}
+
+// charge spends n characters of the budget
+func (d *decoder) charge(n int) error {
+	if n > d.budget {
+		return ErrTooLarge
+	}
+	d.budget -= n
+	return nil
+}
+
+// decodeSequence decodes the values of a tuple or of an array, data starts at their head. It returns the end
+// of their encoding: the values of the dynamic types follow the head in order and don't overlap, an offset
+// which points back into the head or into a value already decoded is forged.
+func (d *decoder) decodeSequence(types []Type, data []byte) ([]interface{}, int, error) {
+	values := make([]interface{}, len(types))
+	end := 0
+	for _, t := range types {
+		end += t.headSize()
+	}
+	if end > len(data) {
+		return nil, 0, fmt.Errorf("%w: head out of bounds", ErrInvalidData)
+	}
+
+	head := 0
+	for i, t := range types {
+		if !t.dynamic() {
+			v, _, err := d.decodeValue(t, data[head:])
+			if err != nil {
+				return nil, 0, err
+			}
+			values[i] = v
+			head += t.headSize()
+			continue
+		}
+
+		offset, err := readLength(data, head)
+		if err != nil {
+			return nil, 0, err
+		}
+		if offset < end {
+			return nil, 0, fmt.Errorf("%w: %s offset overlaps a previous value", ErrInvalidData, t)
+		}
+		v, size, err := d.decodeValue(t, data[offset:])
+		if err != nil {
+			return nil, 0, err
+		}
+		values[i] = v
+		end = offset + size
+		head += wordSize
+	}
+
+	return values, end, nil
+}
+
+// decodeValue decodes the value of t, data starts at its encoding. It returns the size of its encoding.
+func (d *decoder) decodeValue(t Type, data []byte) (interface{}, int, error) {
+	switch t.Kind {
+	case KindUint, KindInt, KindAddress, KindBool, KindFixedBytes:
+		if len(data) < wordSize {
+			return nil, 0, fmt.Errorf("%w: %s value out of bounds", ErrInvalidData, t)
+		}
+		v, err := decodeWord(t, data[:wordSize])
+		if err != nil {
+			return nil, 0, err
+		}
+		// a bool is charged as a word too
+		if s, ok := v.(string); ok {
+			err = d.charge(len(s))
+		} else {
+			err = d.charge(wordSize)
+		}
+		return v, wordSize, err
+	case KindBytes, KindString:
+		size, err := readLength(data, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		if wordSize+size > len(data) {
+			return nil, 0, fmt.Errorf("%w: %s value out of bounds", ErrInvalidData, t)
+		}
+		content := data[wordSize : wordSize+size]
+		if t.Kind == KindString {
+			return string(content), wordSize + size, d.charge(size)
+		}
+		if err = d.charge(2 + 2*size); err != nil {
+			return nil, 0, err
+		}
+		return "0x" + hex.EncodeToString(content), wordSize + size, nil
+	case KindSlice:
+		size, err := readLength(data, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		// every element takes a slot at least, a larger length is forged
+		if size > (len(data)-wordSize)/wordSize {
+			return nil, 0, fmt.Errorf("%w: %s length out of bounds", ErrInvalidData, t)
+		}
+		values, end, err := d.decodeSequence(repeat(*t.Elem, size), data[wordSize:])
+		return values, wordSize + end, err
+	case KindArray:
+		return d.decodeSequence(repeat(*t.Elem, t.Size), data)
+	case KindTuple:
+		types := make([]Type, len(t.Components))
+		for i, field := range t.Components {
+			types[i] = field.Type
+		}
+		values, end, err := d.decodeSequence(types, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		return namedArgs(t.Components, values), end, nil
+	}
+
+	return nil, 0, fmt.Errorf("%w: unsupported type %s", ErrInvalidData, t)
+}
+
+// decodeWord decodes a value that fits a slot, the padding must be clean to tell apart the selectors
+// and the events which collide
+func decodeWord(t Type, word []byte) (interface{}, error) {
+	switch t.Kind {
+	case KindUint:
+		v := new(big.Int).SetBytes(word)
+		if v.BitLen() > t.Size {
+			return nil, fmt.Errorf("%w: %s overflow", ErrInvalidData, t)
+		}
+		return v.String(), nil
+	case KindInt:
+		v := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if v.Cmp(limit) >= 0 || v.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%w: %s overflow", ErrInvalidData, t)
+		}
+		return v.String(), nil
+	case KindAddress:
+		if !isZero(word[:12]) {
+			return nil, fmt.Errorf("%w: dirty address padding", ErrInvalidData)
+		}
+		return utils.ToChecksumAddress("0x" + hex.EncodeToString(word[12:])), nil
+	case KindBool:
+		if !isZero(word[:wordSize-1]) || word[wordSize-1] > 1 {
+			return nil, fmt.Errorf("%w: invalid bool", ErrInvalidData)
+		}
+		return word[wordSize-1] == 1, nil
+	case KindFixedBytes:
+		if !isZero(word[t.Size:]) {
+			return nil, fmt.Errorf("%w: dirty %s padding", ErrInvalidData, t)
+		}
+		return "0x" + hex.EncodeToString(word[:t.Size]), nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidData, t)
+}
+
+// readLength read the offset or the length at pos, it must be inside data
+func readLength(data []byte, pos int) (int, error) {
+	if pos+wordSize > len(data) {
+		return 0, fmt.Errorf("%w: offset out of bounds", ErrInvalidData)
+	}
+	v := new(big.Int).SetBytes(data[pos : pos+wordSize])
+	if !v.IsInt64() || v.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("%w: offset out of bounds", ErrInvalidData)
+	}
+	return int(v.Int64()), nil
+}
+
+func repeat(t Type, n int) []Type {
+	types := make([]Type, n)
+	for i := range types {
+		types[i] = t
+	}
+	return types
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
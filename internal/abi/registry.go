@@ -0,0 +1,291 @@
+package abi
+
+import (
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// builtinFS holds the ABIs of ERC-20, ERC-721, ERC-1155, WETH and the Uniswap routers
+//
+//go:embed builtin/*.json
+var builtinFS embed.FS
+
+// Call is a decoded contract call, Function and Arguments are empty when the selector is unknown
+type Call struct {
+	Selector  string `json:"selector"`
+	Function  string `json:"function,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Arguments []Arg  `json:"arguments,omitempty"`
+}
+
+// Log is a decoded event log, the dynamic indexed arguments are the 0x-hex hash of their value
+type Log struct {
+	Topic     string `json:"topic"`
+	Event     string `json:"event,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Arguments []Arg  `json:"arguments,omitempty"`
+}
+
+// Registry is a set of methods and events indexed by selector and topic, it is safe for concurrent
+// reads once loaded
+type Registry struct {
+	methods map[[4]byte][]Method
+	events  map[[32]byte][]Event
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		methods: make(map[[4]byte][]Method),
+		events:  make(map[[32]byte][]Event),
+	}
+}
+
+// Builtin creates a registry of the built-in ABIs
+func Builtin() (*Registry, error) {
+	r := NewRegistry()
+	paths, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range paths {
+		f, err := builtinFS.Open("builtin/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		contract, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("builtin abi %s: %w", entry.Name(), err)
+		}
+		r.Add(contract)
+	}
+
+	return r, nil
+}
+
+// LoadDir adds the ABIs of the *.json files of a directory
+func (r *Registry) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("list abi files: %w", err)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		if err := r.LoadFile(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadFile adds the ABI of a JSON file
+func (r *Registry) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open abi: %w", err)
+	}
+	defer f.Close()
+
+	contract, err := Parse(f)
+	if err != nil {
+		return fmt.Errorf("abi %s: %w", path, err)
+	}
+	r.Add(contract)
+	return nil
+}
+
+// Add registers the methods and the events of a contract, the signatures already known are skipped.
+// The anonymous events have no topic to look them up and are skipped too.
+func (r *Registry) Add(contract *ABI) {
+	for _, m := range contract.Methods {
+		if !containsMethod(r.methods[m.Selector], m) {
+			r.methods[m.Selector] = append(r.methods[m.Selector], m)
+		}
+	}
+	for _, e := range contract.Events {
+		if !e.Anonymous && !containsEvent(r.events[e.ID], e) {
+			r.events[e.ID] = append(r.events[e.ID], e)
+		}
+	}
+}
+
+// Len return the number of methods and events
+func (r *Registry) Len() int {
+	n := 0
+	for _, methods := range r.methods {
+		n += len(methods)
+	}
+	for _, events := range r.events {
+		n += len(events)
+	}
+	return n
+}
+
+// DecodeCall decodes the call data of a transaction. The selector is set whenever the input holds one,
+// even when it is unknown or the arguments don't match any method of the selector.
+func (r *Registry) DecodeCall(input string) (Call, error) {
+	data, err := decodeHex(input)
+	if err != nil {
+		return Call{}, err
+	}
+	if len(data) < 4 {
+		return Call{}, fmt.Errorf("%w: call data has no selector", ErrInvalidData)
+	}
+
+	var selector [4]byte
+	copy(selector[:], data)
+	call := Call{Selector: "0x" + hex.EncodeToString(selector[:])}
+	methods := r.methods[selector]
+	if len(methods) == 0 {
+		return call, ErrUnknownSelector
+	}
+
+	// the colliding selectors are told apart by the first method the data decodes with
+	err = ErrInvalidData
+	for _, m := range methods {
+		args, decodeErr := DecodeArguments(m.Inputs, data[4:])
+		if decodeErr != nil {
+			err = decodeErr
+			continue
+		}
+		call.Function, call.Signature, call.Arguments = m.Name, m.Signature, args
+		return call, nil
+	}
+
+	return call, err
+}
+
+// DecodeLog decodes a log of its topics and its data. The topic is set whenever the log has one,
+// even when the event is unknown.
+func (r *Registry) DecodeLog(topics []string, data string) (Log, error) {
+	if len(topics) == 0 {
+		return Log{}, fmt.Errorf("%w: log has no topic", ErrInvalidData)
+	}
+	words := make([][]byte, len(topics))
+	for i, topic := range topics {
+		word, err := decodeHex(topic)
+		if err != nil || len(word) != wordSize {
+			return Log{}, fmt.Errorf("%w: topic %d is not 32 bytes", ErrInvalidData, i)
+		}
+		words[i] = word
+	}
+	body, err := decodeHex(data)
+	if err != nil {
+		return Log{}, err
+	}
+
+	var id [32]byte
+	copy(id[:], words[0])
+	log := Log{Topic: "0x" + hex.EncodeToString(id[:])}
+	events := r.events[id]
+	if len(events) == 0 {
+		return log, ErrUnknownEvent
+	}
+
+	// the events of the same signature are told apart by their indexed arguments,
+	// e.g. Transfer of ERC-20 and ERC-721
+	err = ErrInvalidData
+	for _, e := range events {
+		args, decodeErr := decodeEvent(e, words[1:], body)
+		if decodeErr != nil {
+			err = decodeErr
+			continue
+		}
+		log.Event, log.Signature, log.Arguments = e.Name, e.Signature, args
+		return log, nil
+	}
+
+	return log, err
+}
+
+// decodeEvent decodes the arguments of an event, the indexed ones are in the topics and the others in data
+func decodeEvent(e Event, topics [][]byte, data []byte) ([]Arg, error) {
+	var indexed, plain []Argument
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		} else {
+			plain = append(plain, arg)
+		}
+	}
+	if len(indexed) != len(topics) {
+		return nil, fmt.Errorf("%w: %s has %d indexed arguments, log has %d topics", ErrInvalidData, e.Signature, len(indexed), len(topics))
+	}
+
+	values, err := DecodeArguments(plain, data)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]Arg, 0, len(e.Inputs))
+	for _, arg := range e.Inputs {
+		if !arg.Indexed {
+			args = append(args, values[0])
+			values = values[1:]
+			continue
+		}
+		topic := topics[0]
+		topics = topics[1:]
+		// the dynamic values are hashed into their topic
+		if arg.Type.dynamic() || arg.Type.Kind == KindArray || arg.Type.Kind == KindTuple {
+			args = append(args, Arg{Name: arg.Name, Type: arg.Type.String(), Value: "0x" + hex.EncodeToString(topic)})
+			continue
+		}
+		v, err := decodeWord(arg.Type, topic)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, Arg{Name: arg.Name, Type: arg.Type.String(), Value: v})
+	}
+
+	return args, nil
+}
+
+func decodeHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return data, nil
+}
+
+func containsMethod(methods []Method, m Method) bool {
+	for _, known := range methods {
+		if known.Signature == m.Signature {
+			return true
+		}
+	}
+	return false
+}
+
+// containsEvent compare the events by signature and indexed arguments, which the signature leaves out
+func containsEvent(events []Event, e Event) bool {
+	for _, known := range events {
+		if known.Signature == e.Signature && indexedMask(known) == indexedMask(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexedMask(e Event) string {
+	var b strings.Builder
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,242 @@
+package abi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	holder      = "0x28C6c06298d514Db089934071355E5743bf21d60"
+	weth        = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	usdc        = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+	transferLog = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+// word left-pad a hex value to an ABI slot
+func word(v string) string {
+	v = strings.TrimPrefix(v, "0x")
+	return strings.Repeat("0", 64-len(v)) + strings.ToLower(v)
+}
+
+// calldata concatenate a selector and the slots
+func calldata(selector string, words ...string) string {
+	return selector + strings.Join(words, "")
+}
+
+func builtin(t *testing.T) *Registry {
+	r, err := Builtin()
+	assert.NoError(t, err)
+	return r
+}
+
+func TestBuiltin_selectors(t *testing.T) {
+	r := builtin(t)
+	selectors := map[string]string{
+		"0xa9059cbb": "transfer(address,uint256)",
+		"0x23b872dd": "transferFrom(address,address,uint256)",
+		"0x095ea7b3": "approve(address,uint256)",
+		"0x42842e0e": "safeTransferFrom(address,address,uint256)",
+		"0xb88d4fde": "safeTransferFrom(address,address,uint256,bytes)",
+		"0xf242432a": "safeTransferFrom(address,address,uint256,uint256,bytes)",
+		"0x2eb2c2d6": "safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)",
+		"0xa22cb465": "setApprovalForAll(address,bool)",
+		"0xd0e30db0": "deposit()",
+		"0x2e1a7d4d": "withdraw(uint256)",
+		"0x7ff36ab5": "swapExactETHForTokens(uint256,address[],address,uint256)",
+		"0x38ed1739": "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+		"0x414bf389": "exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))",
+		"0x04e45aaf": "exactInputSingle((address,address,uint24,address,uint256,uint256,uint160))",
+		"0xac9650d8": "multicall(bytes[])",
+		"0x5ae401dc": "multicall(uint256,bytes[])",
+		"0x3593564c": "execute(bytes,bytes[],uint256)",
+	}
+	for selector, signature := range selectors {
+		var found bool
+		for _, m := range r.methods[[4]byte(mustHex(t, selector))] {
+			found = found || m.Signature == signature
+		}
+		assert.True(t, found, "%s %s", selector, signature)
+	}
+
+	// ERC-20 and ERC-721 share transferFrom, it is registered once
+	assert.Len(t, r.methods[[4]byte(mustHex(t, "0x23b872dd"))], 1)
+	// but their Transfer events differ by the indexed value
+	assert.Len(t, r.events[[32]byte(mustHex(t, transferLog))], 2)
+}
+
+func TestRegistry_DecodeCall(t *testing.T) {
+	r := builtin(t)
+
+	call, err := r.DecodeCall(calldata("0xa9059cbb", word(holder), word("0de0b6b3a7640000")))
+	assert.NoError(t, err)
+	assert.Equal(t, Call{
+		Selector:  "0xa9059cbb",
+		Function:  "transfer",
+		Signature: "transfer(address,uint256)",
+		Arguments: []Arg{
+			{Name: "to", Type: "address", Value: holder},
+			{Name: "value", Type: "uint256", Value: "1000000000000000000"},
+		},
+	}, call)
+
+	// a dynamic array behind an offset
+	call, err = r.DecodeCall(calldata("0x7ff36ab5",
+		word("64"), word("80"), word(holder), word("65a9c000"),
+		word("2"), word(weth), word(usdc)))
+	assert.NoError(t, err)
+	assert.Equal(t, "swapExactETHForTokens", call.Function)
+	assert.Equal(t, []Arg{
+		{Name: "amountOutMin", Type: "uint256", Value: "100"},
+		{Name: "path", Type: "address[]", Value: []interface{}{weth, usdc}},
+		{Name: "to", Type: "address", Value: holder},
+		{Name: "deadline", Type: "uint256", Value: "1705623552"},
+	}, call.Arguments)
+
+	// a static tuple is encoded in place
+	call, err = r.DecodeCall(calldata("0x04e45aaf",
+		word(weth), word(usdc), word("bb8"), word(holder), word("3e8"), word("0"), word("0")))
+	assert.NoError(t, err)
+	assert.Equal(t, "exactInputSingle((address,address,uint24,address,uint256,uint256,uint160))", call.Signature)
+	if assert.Len(t, call.Arguments, 1) {
+		params := call.Arguments[0].Value.([]Arg)
+		assert.Equal(t, Arg{Name: "fee", Type: "uint24", Value: "3000"}, params[2])
+		assert.Equal(t, Arg{Name: "amountIn", Type: "uint256", Value: "1000"}, params[4])
+	}
+
+	// nested dynamic values, bytes[] holds the offsets of its items
+	call, err = r.DecodeCall(calldata("0x5ae401dc",
+		word("65a9c000"), word("40"),
+		word("1"), word("20"), word("4"), "d0e30db0"+strings.Repeat("0", 56)))
+	assert.NoError(t, err)
+	assert.Equal(t, []Arg{
+		{Name: "deadline", Type: "uint256", Value: "1705623552"},
+		{Name: "data", Type: "bytes[]", Value: []interface{}{"0xd0e30db0"}},
+	}, call.Arguments)
+
+	call, err = r.DecodeCall("0xd0e30db0")
+	assert.NoError(t, err)
+	assert.Equal(t, Call{Selector: "0xd0e30db0", Function: "deposit", Signature: "deposit()", Arguments: []Arg{}}, call)
+}
+
+func TestRegistry_DecodeCall_invalid(t *testing.T) {
+	r := builtin(t)
+
+	call, err := r.DecodeCall(calldata("0x12345678", word("1")))
+	assert.ErrorIs(t, err, ErrUnknownSelector)
+	assert.Equal(t, Call{Selector: "0x12345678"}, call)
+
+	tests := map[string]string{
+		"no selector":     "0xa905",
+		"not hex":         "0xzz",
+		"truncated":       calldata("0xa9059cbb", word(holder)),
+		"dirty address":   calldata("0xa9059cbb", "ff"+word(holder)[2:], word("1")),
+		"uint24 overflow": calldata("0x04e45aaf", word(weth), word(usdc), word("1000000"), word(holder), word("1"), word("0"), word("0")),
+		"forged length":   calldata("0x7ff36ab5", word("64"), word("80"), word(holder), word("1"), word("ffffffff")),
+		"forged offset":   calldata("0x7ff36ab5", word("64"), word("ffffffffffffffffffff"), word(holder), word("1")),
+		// the items of bytes[] point at the same bytes
+		"overlapping offsets": calldata("0xac9650d8", word("20"), word("2"), word("40"), word("40"), word("4"), "d0e30db0"+strings.Repeat("0", 56)),
+		"offset into head":    calldata("0xac9650d8", word("0")),
+	}
+	for name, input := range tests {
+		input := input
+		t.Run(name, func(t *testing.T) {
+			call, err := r.DecodeCall(input)
+			assert.ErrorIs(t, err, ErrInvalidData)
+			assert.Empty(t, call.Function)
+		})
+	}
+}
+
+func TestRegistry_DecodeCall_tooLarge(t *testing.T) {
+	r := builtin(t)
+
+	// a thousand items pointing at the same 64 KB would decode into 128 MB of hex
+	blob := word("10000") + strings.Repeat("ab", 1<<16)
+	offsets := make([]string, 1000)
+	for i := range offsets {
+		offsets[i] = word("7d00")
+	}
+	call, err := r.DecodeCall(calldata("0xac9650d8", word("20"), word("3e8"), strings.Join(offsets, ""), blob))
+	assert.ErrorIs(t, err, ErrInvalidData)
+	assert.Equal(t, Call{Selector: "0xac9650d8"}, call)
+
+	// the decoded values are capped even when the data is well formed
+	blob = word("80000") + strings.Repeat("ab", 1<<19)
+	call, err = r.DecodeCall(calldata("0xac9650d8", word("20"), word("1"), word("20"), blob))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, Call{Selector: "0xac9650d8"}, call)
+}
+
+func TestRegistry_DecodeLog(t *testing.T) {
+	r := builtin(t)
+	from, to := "0x"+word(holder), "0x"+word(weth)
+
+	// ERC-20, the value is in the data
+	log, err := r.DecodeLog([]string{transferLog, from, to}, "0x"+word("3e8"))
+	assert.NoError(t, err)
+	assert.Equal(t, Log{
+		Topic:     transferLog,
+		Event:     "Transfer",
+		Signature: "Transfer(address,address,uint256)",
+		Arguments: []Arg{
+			{Name: "from", Type: "address", Value: holder},
+			{Name: "to", Type: "address", Value: weth},
+			{Name: "value", Type: "uint256", Value: "1000"},
+		},
+	}, log)
+
+	// ERC-721, the token id is indexed
+	log, err = r.DecodeLog([]string{transferLog, from, to, "0x" + word("7")}, "0x")
+	assert.NoError(t, err)
+	assert.Equal(t, Arg{Name: "tokenId", Type: "uint256", Value: "7"}, log.Arguments[2])
+
+	// ERC-1155 URI, a string in the data before an indexed id
+	uri := "0x6bb7ff708619ba0610cba295a58592e0451dee2622938c8755667688daf3529b"
+	log, err = r.DecodeLog([]string{uri, "0x" + word("1")}, "0x"+word("20")+word("4")+"69706673"+strings.Repeat("0", 56))
+	assert.NoError(t, err)
+	assert.Equal(t, []Arg{
+		{Name: "value", Type: "string", Value: "ipfs"},
+		{Name: "id", Type: "uint256", Value: "1"},
+	}, log.Arguments)
+
+	log, err = r.DecodeLog([]string{"0x" + word("1")}, "0x")
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+	assert.Equal(t, "0x"+word("1"), log.Topic)
+
+	_, err = r.DecodeLog([]string{transferLog, from}, "0x")
+	assert.ErrorIs(t, err, ErrInvalidData)
+	_, err = r.DecodeLog(nil, "0x")
+	assert.ErrorIs(t, err, ErrInvalidData)
+	_, err = r.DecodeLog([]string{"0x01"}, "0x")
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
+
+func TestRegistry_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "vault.json"), []byte(`{"abi": [
+		{"type": "function", "name": "stake", "inputs": [{"name": "amount", "type": "uint256"}, {"name": "lock", "type": "bool"}]}
+	]}`), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an abi"), 0o600))
+
+	r := NewRegistry()
+	assert.NoError(t, r.LoadDir(dir))
+	assert.Equal(t, 1, r.Len())
+
+	call, err := r.DecodeCall(calldata("0xabe50f19", word("5"), word("1")))
+	assert.NoError(t, err)
+	assert.Equal(t, "stake(uint256,bool)", call.Signature)
+	assert.Equal(t, Arg{Name: "lock", Type: "bool", Value: true}, call.Arguments[1])
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"abi": [`), 0o600))
+	assert.ErrorContains(t, r.LoadDir(dir), "broken.json")
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := decodeHex(s)
+	assert.NoError(t, err)
+	return b
+}
@@ -0,0 +1,178 @@
+// Package abi decodes the call data and the logs of EVM contracts with their JSON ABI
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the family of a Solidity type
+type Kind int
+
+const (
+	KindUint Kind = iota
+	KindInt
+	KindAddress
+	KindBool
+	KindFixedBytes
+	KindBytes
+	KindString
+	KindSlice
+	KindArray
+	KindTuple
+)
+
+// Type is a Solidity ABI type
+type Type struct {
+	Kind Kind
+	// Size is the bits of an integer, the length of fixed bytes or of a fixed array
+	Size int
+	// Elem is the element type of an array
+	Elem *Type
+	// Components are the fields of a tuple
+	Components []Argument
+}
+
+// Argument is a named parameter of a function or an event
+type Argument struct {
+	Name    string
+	Type    Type
+	Indexed bool
+}
+
+// parseType parse a type of the JSON ABI, components are the fields of a tuple type
+func parseType(typ string, components []jsonArgument) (Type, error) {
+	if strings.HasSuffix(typ, "]") {
+		i := strings.LastIndex(typ, "[")
+		if i <= 0 {
+			return Type{}, fmt.Errorf("invalid type %q", typ)
+		}
+		elem, err := parseType(typ[:i], components)
+		if err != nil {
+			return Type{}, err
+		}
+		dim := typ[i+1 : len(typ)-1]
+		if dim == "" {
+			return Type{Kind: KindSlice, Elem: &elem}, nil
+		}
+		size, err := strconv.Atoi(dim)
+		if err != nil || size <= 0 {
+			return Type{}, fmt.Errorf("invalid array length of type %q", typ)
+		}
+		return Type{Kind: KindArray, Size: size, Elem: &elem}, nil
+	}
+
+	switch typ {
+	case "address":
+		return Type{Kind: KindAddress}, nil
+	case "bool":
+		return Type{Kind: KindBool}, nil
+	case "string":
+		return Type{Kind: KindString}, nil
+	case "bytes":
+		return Type{Kind: KindBytes}, nil
+	case "uint":
+		return Type{Kind: KindUint, Size: 256}, nil
+	case "int":
+		return Type{Kind: KindInt, Size: 256}, nil
+	case "tuple":
+		fields, err := parseArguments(components)
+		if err != nil {
+			return Type{}, err
+		}
+		return Type{Kind: KindTuple, Components: fields}, nil
+	}
+
+	for _, prefix := range []struct {
+		name     string
+		kind     Kind
+		min, max int
+		step     int
+	}{
+		{"uint", KindUint, 8, 256, 8},
+		{"int", KindInt, 8, 256, 8},
+		{"bytes", KindFixedBytes, 1, 32, 1},
+	} {
+		if !strings.HasPrefix(typ, prefix.name) {
+			continue
+		}
+		size, err := strconv.Atoi(typ[len(prefix.name):])
+		if err != nil || size < prefix.min || size > prefix.max || size%prefix.step != 0 {
+			return Type{}, fmt.Errorf("invalid type %q", typ)
+		}
+		return Type{Kind: prefix.kind, Size: size}, nil
+	}
+
+	return Type{}, fmt.Errorf("unsupported type %q", typ)
+}
+
+// String return the canonical name of the type, as used in the signatures
+func (t Type) String() string {
+	switch t.Kind {
+	case KindUint:
+		return "uint" + strconv.Itoa(t.Size)
+	case KindInt:
+		return "int" + strconv.Itoa(t.Size)
+	case KindAddress:
+		return "address"
+	case KindBool:
+		return "bool"
+	case KindFixedBytes:
+		return "bytes" + strconv.Itoa(t.Size)
+	case KindBytes:
+		return "bytes"
+	case KindString:
+		return "string"
+	case KindSlice:
+		return t.Elem.String() + "[]"
+	case KindArray:
+		return t.Elem.String() + "[" + strconv.Itoa(t.Size) + "]"
+	case KindTuple:
+		return "(" + joinTypes(t.Components) + ")"
+	}
+	return ""
+}
+
+// dynamic report whether the value is encoded in the tail, behind an offset
+func (t Type) dynamic() bool {
+	switch t.Kind {
+	case KindBytes, KindString, KindSlice:
+		return true
+	case KindArray:
+		return t.Elem.dynamic()
+	case KindTuple:
+		for _, field := range t.Components {
+			if field.Type.dynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize return the bytes a static value takes in the head, 32 for the offset of a dynamic one
+func (t Type) headSize() int {
+	if t.dynamic() {
+		return 32
+	}
+	switch t.Kind {
+	case KindArray:
+		return t.Size * t.Elem.headSize()
+	case KindTuple:
+		size := 0
+		for _, field := range t.Components {
+			size += field.Type.headSize()
+		}
+		return size
+	}
+	return 32
+}
+
+func joinTypes(args []Argument) string {
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = arg.Type.String()
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,216 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// maxFired bounds the number of alert IDs remembered to not send an alert twice when a block is parsed again
+const maxFired = 10000
+
+// Alert is a rule matched by a transaction of a subscribed address
+type Alert struct {
+	// ID identifies the alert, it is the same when the transaction is evaluated again
+	ID          string          `json:"id"`
+	Rule        string          `json:"rule"`
+	Severity    Severity        `json:"severity"`
+	Description string          `json:"description,omitempty"`
+	Chain       string          `json:"chain"`
+	Address     string          `json:"address"`
+	Direction   types.Direction `json:"direction"`
+	// Transaction is the hash of the transaction, the one which exceeded the rate of a rate rule
+	Transaction string    `json:"transaction"`
+	Block       uint64    `json:"block"`
+	Timestamp   time.Time `json:"timestamp"`
+	// Value is the value of the transaction in the native unit of the chain, e.g. "1.5"
+	Value          string   `json:"value"`
+	Counterparties []string `json:"counterparties,omitempty"`
+	// Method is the selector called by an EVM transaction, empty for a plain transfer
+	Method string `json:"method,omitempty"`
+	// Count is the number of matching transactions within the window of a rate rule
+	Count int `json:"count,omitempty"`
+}
+
+// Sink receives the alerts
+type Sink interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// Options configures the engine of a chain
+type Options struct {
+	// Chain is the name of the evaluated chain
+	Chain string
+	// Decimals is the number of decimals of the native unit of the chain, the values of the rules are in this unit
+	Decimals int
+}
+
+// Engine evaluates the rules of a chain against its matched transactions
+type Engine struct {
+	chain    string
+	decimals int
+	rules    []compiledRule
+	sinks    []Sink
+
+	mu sync.Mutex
+	// windows are the matching transactions of the rate rules by rule and address, oldest first
+	windows map[string][]event
+	// fired are the IDs of the sent alerts, firedOrder evicts the oldest ones
+	fired      map[string]bool
+	firedOrder []string
+}
+
+// event is a transaction counted in the window of a rate rule
+type event struct {
+	hash string
+	time time.Time
+}
+
+// NewEngine creates the engine of a chain, the rules which do not watch the chain are ignored
+func NewEngine(rules []Rule, sinks []Sink, opts Options) (*Engine, error) {
+	e := &Engine{
+		chain:    opts.Chain,
+		decimals: opts.Decimals,
+		sinks:    sinks,
+		windows:  make(map[string][]event),
+		fired:    make(map[string]bool),
+	}
+	for _, rule := range rules {
+		compiled, err := compile(rule, opts.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: %w", opts.Chain, err)
+		}
+		if len(compiled.Chains) > 0 && !containsString(compiled.Chains, opts.Chain) {
+			continue
+		}
+		e.rules = append(e.rules, compiled)
+	}
+
+	return e, nil
+}
+
+// Evaluate evaluates the rules against the transactions of a block for each subscribed address they matched,
+// and sends the alerts to the sinks. A transaction evaluated again does not alert twice.
+func (e *Engine) Evaluate(ctx context.Context, txns []repository.IndexedTransaction) {
+	if len(e.rules) == 0 {
+		return
+	}
+
+	for _, alert := range e.match(txns) {
+		alertsFired.With(e.chain, alert.Rule, string(alert.Severity)).Inc()
+		for _, sink := range e.sinks {
+			if err := sink.Send(ctx, alert); err != nil {
+				slog.ErrorContext(ctx, "error sending alert",
+					slog.String("rule", alert.Rule), slog.String("alert", alert.ID), logging.Err(err))
+			}
+		}
+	}
+}
+
+// match return the new alerts of the transactions
+func (e *Engine) match(txns []repository.IndexedTransaction) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []Alert
+	for _, indexed := range txns {
+		tx := indexed.Transaction
+		for _, address := range indexed.Addresses {
+			for _, rule := range e.rules {
+				if !rule.appliesTo(e.chain, address) || !rule.match(address, tx) {
+					continue
+				}
+
+				count := 0
+				if rule.Rate != nil {
+					if count = e.count(rule, address, tx); count <= rule.Rate.Count {
+						continue
+					}
+				}
+
+				alert := e.newAlert(rule, address, tx, count)
+				if e.fired[alert.ID] {
+					continue
+				}
+				e.remember(alert.ID)
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	return alerts
+}
+
+// count adds the transaction to the window of the rate rule and return the number of transactions
+// within it, the window starts again once the rate is exceeded
+func (e *Engine) count(rule compiledRule, address string, tx types.Transaction) int {
+	key := rule.Name + "|" + utils.NormalizeAddress(address)
+	at := time.Unix(int64(tx.Timestamp), 0).UTC()
+
+	window := e.windows[key]
+	kept := window[:0]
+	for _, ev := range window {
+		if ev.hash == tx.Hash {
+			// evaluated again
+			return 0
+		}
+		if at.Sub(ev.time) < rule.window {
+			kept = append(kept, ev)
+		}
+	}
+	kept = append(kept, event{hash: tx.Hash, time: at})
+
+	count := len(kept)
+	if count > rule.Rate.Count {
+		// the alert covers the transactions of the window
+		kept = kept[:0]
+	}
+	if len(kept) == 0 {
+		delete(e.windows, key)
+	} else {
+		e.windows[key] = kept
+	}
+
+	return count
+}
+
+func (e *Engine) newAlert(rule compiledRule, address string, tx types.Transaction, count int) Alert {
+	value := tx.Value
+	if amount, err := tx.ValueInt(); err == nil {
+		value = utils.FormatUnits(amount, e.decimals)
+	}
+
+	return Alert{
+		ID:             strings.Join([]string{strings.ToLower(rule.Name), e.chain, utils.NormalizeAddress(address), strings.ToLower(tx.Hash)}, ":"),
+		Rule:           rule.Name,
+		Severity:       rule.Severity,
+		Description:    rule.Description,
+		Chain:          e.chain,
+		Address:        address,
+		Direction:      tx.Direction(address),
+		Transaction:    tx.Hash,
+		Block:          uint64(tx.BlockNumber),
+		Timestamp:      time.Unix(int64(tx.Timestamp), 0).UTC(),
+		Value:          value,
+		Counterparties: tx.Counterparties(address),
+		Method:         tx.MethodSelector(),
+		Count:          count,
+	}
+}
+
+func (e *Engine) remember(id string) {
+	e.fired[id] = true
+	e.firedOrder = append(e.firedOrder, id)
+	if len(e.firedOrder) > maxFired {
+		delete(e.fired, e.firedOrder[0])
+		e.firedOrder = e.firedOrder[1:]
+	}
+}
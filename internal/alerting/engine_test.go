@@ -0,0 +1,143 @@
+package alerting
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	treasury = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	bob      = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	mixer    = "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+
+	oneEther = "0xde0b6b3a7640000"
+	// tenEther is 10 ETH and elevenEther 11 ETH
+	tenEther    = "0x8ac7230489e80000"
+	elevenEther = "0x98a7d9b8314c0000"
+)
+
+// recorder is a sink recording the alerts
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Send(_ context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *recorder) rules() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rules []string
+	for _, alert := range r.alerts {
+		rules = append(rules, alert.Rule)
+	}
+	return rules
+}
+
+func indexed(address string, tx types.Transaction) repository.IndexedTransaction {
+	return repository.IndexedTransaction{Transaction: tx, Addresses: []string{address}}
+}
+
+func evmTx(hash, from, to, value string, timestamp uint64) types.Transaction {
+	return types.Transaction{Hash: hash, From: from, To: to, Value: value, Input: "0x",
+		BlockNumber: 10, Timestamp: utils.HexUint64(timestamp)}
+}
+
+func newTestEngine(t *testing.T, rulesJSON string) (*Engine, *recorder) {
+	rules, err := ParseRules(strings.NewReader(rulesJSON))
+	assert.NoError(t, err)
+	sink := &recorder{}
+	engine, err := NewEngine(rules, []Sink{sink}, Options{Chain: "ethereum", Decimals: 18})
+	assert.NoError(t, err)
+	return engine, sink
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	ctx := context.TODO()
+	engine, sink := newTestEngine(t, `{"rules": [
+		{"name": "treasury-outflow", "addresses": ["`+strings.ToUpper(treasury)+`"], "direction": "outbound", "minValue": "10"},
+		{"name": "blocklist", "counterparties": ["`+mixer+`"], "severity": "critical"},
+		{"name": "approve", "methods": ["0x095ea7b3"]},
+		{"name": "bitcoin-only", "chains": ["bitcoin"]}
+	]}`)
+
+	approve := evmTx("0x04", treasury, bob, "0x0", 100)
+	approve.Input = "0x095EA7B3000000000000000000000000"
+	engine.Evaluate(ctx, []repository.IndexedTransaction{
+		// below the threshold
+		indexed(treasury, evmTx("0x01", treasury, bob, oneEther, 100)),
+		indexed(treasury, evmTx("0x02", treasury, bob, elevenEther, 100)),
+		// inbound
+		indexed(treasury, evmTx("0x03", bob, treasury, elevenEther, 100)),
+		indexed(treasury, approve),
+		{Transaction: evmTx("0x05", mixer, bob, tenEther, 100), Addresses: []string{bob}},
+	})
+	assert.Equal(t, []string{"treasury-outflow", "approve", "blocklist"}, sink.rules())
+
+	alert := sink.alerts[0]
+	assert.Equal(t, "treasury-outflow:ethereum:"+treasury+":0x02", alert.ID)
+	assert.Equal(t, SeverityWarning, alert.Severity)
+	assert.Equal(t, types.DirectionOutbound, alert.Direction)
+	assert.Equal(t, "11", alert.Value)
+	assert.Equal(t, []string{bob}, alert.Counterparties)
+	assert.Equal(t, "", alert.Method)
+	assert.Equal(t, "0x095ea7b3", sink.alerts[1].Method)
+	assert.Equal(t, SeverityCritical, sink.alerts[2].Severity)
+
+	// the block parsed again does not alert twice
+	engine.Evaluate(ctx, []repository.IndexedTransaction{indexed(treasury, evmTx("0x02", treasury, bob, elevenEther, 100))})
+	assert.Len(t, sink.alerts, 3)
+}
+
+func TestEngine_Rate(t *testing.T) {
+	ctx := context.TODO()
+	engine, sink := newTestEngine(t, `{"rules": [
+		{"name": "burst", "direction": "outbound", "rate": {"count": 2, "window": "1m"}}
+	]}`)
+
+	engine.Evaluate(ctx, []repository.IndexedTransaction{
+		indexed(treasury, evmTx("0x01", treasury, bob, oneEther, 0)),
+		indexed(treasury, evmTx("0x02", treasury, bob, oneEther, 30)),
+		// out of the window of the first one
+		indexed(treasury, evmTx("0x03", treasury, bob, oneEther, 70)),
+		// inbound transactions do not count
+		indexed(treasury, evmTx("0x04", bob, treasury, oneEther, 75)),
+	})
+	assert.Empty(t, sink.alerts)
+
+	// evaluated again, it does not count twice
+	engine.Evaluate(ctx, []repository.IndexedTransaction{indexed(treasury, evmTx("0x03", treasury, bob, oneEther, 70))})
+	assert.Empty(t, sink.alerts)
+
+	engine.Evaluate(ctx, []repository.IndexedTransaction{indexed(treasury, evmTx("0x05", treasury, bob, oneEther, 80))})
+	if assert.Len(t, sink.alerts, 1) {
+		assert.Equal(t, "0x05", sink.alerts[0].Transaction)
+		assert.Equal(t, 3, sink.alerts[0].Count)
+	}
+
+	// the window starts again after the alert
+	engine.Evaluate(ctx, []repository.IndexedTransaction{indexed(treasury, evmTx("0x06", treasury, bob, oneEther, 81))})
+	assert.Len(t, sink.alerts, 1)
+}
+
+func TestNewEngine_decimals(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`{"rules": [{"name": "dust", "maxValue": "0.000000001"}]}`))
+	assert.NoError(t, err)
+
+	_, err = NewEngine(rules, nil, Options{Chain: "bitcoin", Decimals: 8})
+	assert.ErrorContains(t, err, "maxValue")
+	_, err = NewEngine(rules, nil, Options{Chain: "ethereum", Decimals: 18})
+	assert.NoError(t, err)
+}
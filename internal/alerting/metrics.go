@@ -0,0 +1,12 @@
+package alerting
+
+import (
+	"github.com/TrustWallet/tx-parser/internal/metrics"
+)
+
+var (
+	alertsFired = metrics.Default.NewCounterVec("txparser_alerts_total",
+		"Alerts fired by chain, rule and severity.", "chain", "rule", "severity")
+	webhookDeliveries = metrics.Default.NewCounterVec("txparser_alert_webhook_posts_total",
+		"Alerts posted to the webhook by outcome.", "status")
+)
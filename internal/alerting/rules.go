@@ -0,0 +1,246 @@
+// Package alerting evaluates declarative rules against the transactions of the subscribed addresses
+// as the crawler matches them, and sends the alerts to pluggable sinks
+package alerting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Severity ranks the alerts of a rule
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Rule is a condition on the transactions of the subscribed addresses, every condition which is set must hold:
+//
+//	{"name": "treasury-outflow", "addresses": ["0x..."], "direction": "outbound", "minValue": "10"}
+type Rule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Severity is warning when it is not set
+	Severity Severity `json:"severity,omitempty"`
+	// Chains and Addresses restrict the rule to these chains and subscribed addresses, all when empty
+	Chains    []string `json:"chains,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	// Direction is the way the transaction goes for the address: inbound, outbound or self
+	Direction types.Direction `json:"direction,omitempty"`
+	// MinValue and MaxValue bound the value in the native unit of the chain, e.g. "10" ETH, inclusive
+	MinValue string `json:"minValue,omitempty"`
+	MaxValue string `json:"maxValue,omitempty"`
+	// Counterparties match a transaction exchanged with any of these addresses, e.g. a blocklist
+	Counterparties []string `json:"counterparties,omitempty"`
+	// Methods match an EVM transaction calling any of these 4-byte selectors, "0x" is a plain transfer
+	Methods []string `json:"methods,omitempty"`
+	// Rate fires when more than Count transactions of an address match the rule within Window
+	Rate *Rate `json:"rate,omitempty"`
+}
+
+// Rate bounds the number of matching transactions of an address in a sliding window of block time
+type Rate struct {
+	Count int `json:"count"`
+	// Window is a duration, e.g. "1m"
+	Window string `json:"window"`
+}
+
+// rulesFile is the JSON document of the rules
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadRules reads the rules of a JSON file
+func LoadRules(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open rules file: %w", err)
+	}
+	defer f.Close()
+
+	rules, err := ParseRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules decodes and validates the rules of a JSON document, {"rules": [...]}
+func ParseRules(r io.Reader) ([]Rule, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var file rulesFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+
+	var errs []error
+	names := make(map[string]bool, len(file.Rules))
+	for i, rule := range file.Rules {
+		if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("rules[%d]: duplicate name %q", i, rule.Name))
+		}
+		names[rule.Name] = true
+		// the values are checked with the decimals of ether, the chains with less decimals check them again
+		if _, err := compile(rule, 18); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: %w", i, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return file.Rules, nil
+}
+
+// compiledRule is a rule with its values parsed for a chain
+type compiledRule struct {
+	Rule
+	minValue, maxValue *big.Int
+	addresses          map[string]bool
+	counterparties     map[string]bool
+	methods            map[string]bool
+	window             time.Duration
+}
+
+func compile(rule Rule, decimals int) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+	if rule.Name == "" {
+		return c, errors.New("name is required")
+	}
+	switch rule.Severity {
+	case "":
+		c.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return c, fmt.Errorf("%s: severity must be info, warning or critical", rule.Name)
+	}
+	switch rule.Direction {
+	case "", types.DirectionInbound, types.DirectionOutbound, types.DirectionSelf:
+	default:
+		return c, fmt.Errorf("%s: direction must be inbound, outbound or self", rule.Name)
+	}
+
+	var err error
+	if rule.MinValue != "" {
+		if c.minValue, err = utils.ParseUnits(rule.MinValue, decimals); err != nil {
+			return c, fmt.Errorf("%s: minValue: %w", rule.Name, err)
+		}
+	}
+	if rule.MaxValue != "" {
+		if c.maxValue, err = utils.ParseUnits(rule.MaxValue, decimals); err != nil {
+			return c, fmt.Errorf("%s: maxValue: %w", rule.Name, err)
+		}
+	}
+	if c.minValue != nil && c.maxValue != nil && c.minValue.Cmp(c.maxValue) > 0 {
+		return c, fmt.Errorf("%s: minValue must not be above maxValue", rule.Name)
+	}
+
+	c.addresses = addressSet(rule.Addresses)
+	c.counterparties = addressSet(rule.Counterparties)
+	c.methods = lowerSet(rule.Methods)
+	for method := range c.methods {
+		if method == "0x" {
+			continue
+		}
+		if raw, err := hex.DecodeString(strings.TrimPrefix(method, "0x")); err != nil || len(raw) != 4 || !strings.HasPrefix(method, "0x") {
+			return c, fmt.Errorf("%s: method %q must be a 0x-prefixed 4-byte selector or 0x", rule.Name, method)
+		}
+	}
+
+	if rule.Rate != nil {
+		if rule.Rate.Count < 1 {
+			return c, fmt.Errorf("%s: rate.count must be positive", rule.Name)
+		}
+		if c.window, err = time.ParseDuration(rule.Rate.Window); err != nil || c.window <= 0 {
+			return c, fmt.Errorf("%s: rate.window must be a positive duration, e.g. 1m", rule.Name)
+		}
+	}
+
+	return c, nil
+}
+
+// appliesTo reports whether the rule watches the address on the chain
+func (r compiledRule) appliesTo(chain, address string) bool {
+	if len(r.Chains) > 0 && !containsString(r.Chains, chain) {
+		return false
+	}
+	return len(r.addresses) == 0 || r.addresses[utils.NormalizeAddress(address)]
+}
+
+// match reports whether the transaction of the address meets the conditions of the rule, the rate aside
+func (r compiledRule) match(address string, tx types.Transaction) bool {
+	if r.Direction != "" && tx.Direction(address) != r.Direction {
+		return false
+	}
+	if len(r.counterparties) > 0 && !anyIn(tx.Counterparties(address), r.counterparties) {
+		return false
+	}
+	if len(r.methods) > 0 {
+		method := tx.MethodSelector()
+		if method == "" {
+			method = "0x"
+		}
+		if !r.methods[method] {
+			return false
+		}
+	}
+	if r.minValue != nil || r.maxValue != nil {
+		value, err := tx.ValueInt()
+		if err != nil {
+			return false
+		}
+		if (r.minValue != nil && value.Cmp(r.minValue) < 0) || (r.maxValue != nil && value.Cmp(r.maxValue) > 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
+
+// addressSet return the set of the normalized addresses
+func addressSet(addresses []string) map[string]bool {
+	set := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		set[utils.NormalizeAddress(address)] = true
+	}
+	return set
+}
+
+func anyIn(addresses []string, set map[string]bool) bool {
+	for _, address := range addresses {
+		if set[utils.NormalizeAddress(address)] {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package alerting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`{"rules": [
+		{"name": "treasury-outflow", "addresses": ["0xA"], "direction": "outbound", "minValue": "10", "severity": "critical"},
+		{"name": "burst", "direction": "outbound", "rate": {"count": 5, "window": "1m"}}
+	]}`))
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, SeverityCritical, rules[0].Severity)
+	assert.Equal(t, &Rate{Count: 5, Window: "1m"}, rules[1].Rate)
+
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"unknown field", `{"rules": [{"name": "a", "threshold": "1"}]}`, `unknown field "threshold"`},
+		{"no name", `{"rules": [{"direction": "inbound"}]}`, "name is required"},
+		{"duplicate name", `{"rules": [{"name": "a"}, {"name": "a"}]}`, `duplicate name "a"`},
+		{"severity", `{"rules": [{"name": "a", "severity": "high"}]}`, "severity must be"},
+		{"direction", `{"rules": [{"name": "a", "direction": "in"}]}`, "direction must be"},
+		{"value", `{"rules": [{"name": "a", "minValue": "ten"}]}`, "minValue"},
+		{"value bounds", `{"rules": [{"name": "a", "minValue": "2", "maxValue": "1"}]}`, "must not be above"},
+		{"method", `{"rules": [{"name": "a", "methods": ["a9059cbb"]}]}`, "4-byte selector"},
+		{"rate count", `{"rules": [{"name": "a", "rate": {"count": 0, "window": "1m"}}]}`, "rate.count"},
+		{"rate window", `{"rules": [{"name": "a", "rate": {"count": 1, "window": "soon"}}]}`, "rate.window"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRules(strings.NewReader(tt.json))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+var (
+	// ErrQueueFull is returned by the webhook sink when the alert is dropped since the webhook lags
+	ErrQueueFull = errors.New("alert queue is full")
+	// ErrSinkClosed is returned by the webhook sink once it is closed
+	ErrSinkClosed = errors.New("alert sink is closed")
+)
+
+// LogSink logs the alerts at warning level
+type LogSink struct{}
+
+func (LogSink) Send(ctx context.Context, alert Alert) error {
+	slog.WarnContext(ctx, "alert",
+		slog.String("id", alert.ID),
+		slog.String("rule", alert.Rule),
+		slog.String("severity", string(alert.Severity)),
+		slog.String(logging.KeyChain, alert.Chain),
+		slog.String("address", alert.Address),
+		slog.String("transaction", alert.Transaction),
+		slog.Uint64(logging.KeyBlockNumber, alert.Block),
+		slog.String("value", alert.Value))
+	return nil
+}
+
+// WebhookOptions tunes the delivery of the webhook sink
+type WebhookOptions struct {
+	// QueueSize bounds the alerts waiting to be posted, the alerts are dropped when it is full
+	QueueSize int
+	// Timeout bounds one POST request
+	Timeout time.Duration
+	// Retries is the number of times a failed POST is retried, after Backoff doubled on each retry
+	Retries int
+	Backoff time.Duration
+}
+
+// WebhookSink posts the alerts as JSON to a URL, in the background so that a slow webhook does not
+// delay the crawler. A POST fails on a network error or a non-2xx status.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	opts   WebhookOptions
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan Alert
+	done   chan struct{}
+}
+
+// NewWebhookSink starts the sink posting to url, it runs until Close
+func NewWebhookSink(url string, opts WebhookOptions) *WebhookSink {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	s := &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		queue:  make(chan Alert, opts.QueueSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+
+	return s
+}
+
+// Send queues the alert, ErrQueueFull when the queue is full
+func (s *WebhookSink) Send(_ context.Context, alert Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case s.queue <- alert:
+		return nil
+	default:
+		webhookDeliveries.With("dropped").Inc()
+		return ErrQueueFull
+	}
+}
+
+// Close posts the queued alerts and stops the sink, the alerts still queued when ctx is done are lost
+func (s *WebhookSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("alert webhook: %d alerts not posted: %w", len(s.queue), ctx.Err())
+	}
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for alert := range s.queue {
+		if err := s.post(alert); err != nil {
+			webhookDeliveries.With("failed").Inc()
+			slog.Error("error posting alert to webhook", slog.String("alert", alert.ID), logging.Err(err))
+			continue
+		}
+		webhookDeliveries.With("delivered").Inc()
+	}
+}
+
+// post posts the alert, retrying the failures
+func (s *WebhookSink) post(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		err = s.postOnce(body)
+		if err == nil || attempt >= s.opts.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *WebhookSink) postOnce(body []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// FeedItem is an alert of the feed with its sequence number, which grows with each alert
+type FeedItem struct {
+	Seq uint64 `json:"seq"`
+	Alert
+}
+
+// Feed keeps the latest alerts in memory
+type Feed struct {
+	mu    sync.RWMutex
+	items []FeedItem
+	// next is the index of items the next alert is written to once it is full
+	next int
+	seq  uint64
+}
+
+// NewFeed creates a feed keeping the size latest alerts
+func NewFeed(size int) *Feed {
+	return &Feed{items: make([]FeedItem, 0, size)}
+}
+
+func (f *Feed) Send(_ context.Context, alert Alert) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	item := FeedItem{Seq: f.seq, Alert: alert}
+	if len(f.items) < cap(f.items) {
+		f.items = append(f.items, item)
+		return nil
+	}
+	if cap(f.items) == 0 {
+		return nil
+	}
+	f.items[f.next] = item
+	f.next = (f.next + 1) % len(f.items)
+
+	return nil
+}
+
+// List return the alerts after the sequence number after which pass filter, newest first, at most limit
+// when it is positive. A nil filter lists every alert.
+func (f *Feed) List(after uint64, limit int, filter func(Alert) bool) []FeedItem {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	items := []FeedItem{}
+	for i := len(f.items) - 1; i >= 0; i-- {
+		item := f.items[(f.next+i)%len(f.items)]
+		if item.Seq <= after {
+			break
+		}
+		if filter != nil && !filter(item.Alert) {
+			continue
+		}
+		items = append(items, item)
+		if limit > 0 && len(items) == limit {
+			break
+		}
+	}
+
+	return items
+}
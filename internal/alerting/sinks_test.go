@@ -0,0 +1,96 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Alert
+		calls    int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// the first POST fails and is retried
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var alert Alert
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		received = append(received, alert)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, WebhookOptions{Retries: 1, Backoff: time.Millisecond})
+	assert.NoError(t, sink.Send(context.TODO(), Alert{ID: "a", Rule: "rule"}))
+	assert.NoError(t, sink.Send(context.TODO(), Alert{ID: "b", Rule: "rule"}))
+	// Close posts the queued alerts
+	assert.NoError(t, sink.Close(context.TODO()))
+	assert.ErrorIs(t, sink.Send(context.TODO(), Alert{ID: "c"}), ErrSinkClosed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, calls)
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, "a", received[0].ID)
+		assert.Equal(t, "b", received[1].ID)
+	}
+}
+
+func TestWebhookSink_queueFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, WebhookOptions{QueueSize: 1})
+	ctx := context.TODO()
+	assert.NoError(t, sink.Send(ctx, Alert{ID: "a"}))
+	// the worker holds a, b waits in the queue
+	assert.Eventually(t, func() bool { return sink.Send(ctx, Alert{ID: "b"}) == nil }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, sink.Send(ctx, Alert{ID: "c"}), ErrQueueFull)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sink.Close(closeCtx), context.DeadlineExceeded)
+	close(release)
+}
+
+func TestFeed_List(t *testing.T) {
+	ctx := context.TODO()
+	feed := NewFeed(3)
+	assert.Empty(t, feed.List(0, 0, nil))
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, feed.Send(ctx, Alert{ID: id, Chain: "ethereum"}))
+	}
+	assert.NoError(t, feed.Send(ctx, Alert{ID: "e", Chain: "bitcoin"}))
+
+	ids := func(items []FeedItem) []string {
+		var ids []string
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	// the oldest alerts are evicted
+	assert.Equal(t, []string{"e", "d", "c"}, ids(feed.List(0, 0, nil)))
+	assert.Equal(t, uint64(5), feed.List(0, 1, nil)[0].Seq)
+	assert.Equal(t, []string{"e"}, ids(feed.List(4, 0, nil)))
+	assert.Equal(t, []string{"d", "c"}, ids(feed.List(0, 0, func(a Alert) bool { return a.Chain == "ethereum" })))
+	assert.Equal(t, []string{"d"}, ids(feed.List(0, 1, func(a Alert) bool { return a.Chain == "ethereum" })))
+}
@@ -0,0 +1,362 @@
+// Package analytics keeps per-address activity aggregates of the saved transactions, they are updated
+// as the blocks are saved rather than recomputed on every request
+package analytics
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// errScanStopped stops a scan at the first transaction
+var errScanStopped = errors.New("scan stopped")
+
+const (
+	day  = int64(24 * time.Hour / time.Second)
+	week = 7 * day
+)
+
+// Stats are the aggregates of the transactions of an address, the amounts are in the smallest unit of the chain
+type Stats struct {
+	Received *big.Int
+	Sent     *big.Int
+	// FeesPaid are the fees of the transactions the address sent
+	FeesPaid *big.Int
+	TxCount  int
+	// Counterparties is the number of distinct addresses the address exchanged with
+	Counterparties int
+	// FirstActivity and LastActivity are nil until the address has a transaction
+	FirstActivity *Activity
+	LastActivity  *Activity
+	// Daily and Weekly are the volume buckets with transactions in UTC, oldest first, the weeks start on Monday
+	Daily  []Bucket
+	Weekly []Bucket
+}
+
+// Activity is the block of a transaction
+type Activity struct {
+	Block uint64
+	Time  time.Time
+}
+
+// Bucket is the volume of a period
+type Bucket struct {
+	Start    time.Time
+	Received *big.Int
+	Sent     *big.Int
+	TxCount  int
+}
+
+// Tracker is a repository.Repository which updates the stats of the subscribed addresses of the
+// transactions it saves, the stats of an unsubscribed address are dropped
+type Tracker struct {
+	repository.Repository
+	// saveMu serializes the saves, mu guards the stats
+	saveMu sync.Mutex
+	mu     sync.RWMutex
+	stats  map[string]*addressStats
+	// last is the last saved block and undo its changes, they are reverted when the block is saved again
+	last struct {
+		saved bool
+		block uint64
+		undo  []change
+	}
+}
+
+// NewTracker tracks the stats of the transactions saved to repo from now on
+func NewTracker(repo repository.Repository) *Tracker {
+	return &Tracker{Repository: repo, stats: make(map[string]*addressStats)}
+}
+
+// SaveTransactions saves the transactions to the repository then adds them to the stats of their addresses
+func (t *Tracker) SaveTransactions(ctx context.Context, blockNumber uint64, txns []types.Transaction) error {
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	if err := t.Repository.SaveTransactions(ctx, blockNumber, txns); err != nil {
+		return err
+	}
+	// the repository tells the subscribed addresses the transactions matched
+	indexed, err := t.Repository.GetBlockTransactions(ctx, blockNumber)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.last.saved && t.last.block == blockNumber {
+		for i := len(t.last.undo) - 1; i >= 0; i-- {
+			t.last.undo[i].revert(t.stats)
+		}
+	}
+	var undo []change
+	for _, tx := range indexed {
+		for _, address := range tx.Addresses {
+			key := utils.NormalizeAddress(address)
+			stats, ok := t.stats[key]
+			if !ok {
+				stats = newAddressStats()
+				t.stats[key] = stats
+			}
+			c := change{address: key, contribution: newContribution(address, tx.Transaction), first: stats.first, last: stats.last}
+			stats.apply(c.contribution, 1)
+			undo = append(undo, c)
+		}
+	}
+	t.last.saved, t.last.block, t.last.undo = true, blockNumber, undo
+
+	return nil
+}
+
+// RemoveAddresses removes the addresses from the repository and drops their stats
+func (t *Tracker) RemoveAddresses(ctx context.Context, addresses []string) ([]bool, error) {
+	removed, err := t.Repository.RemoveAddresses(ctx, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, address := range addresses {
+		if removed[i] {
+			delete(t.stats, utils.NormalizeAddress(address))
+		}
+	}
+
+	return removed, nil
+}
+
+// Stats return the stats of an address, compared case-insensitively, repository.ErrAddressNotFound
+// when it is not subscribed
+func (t *Tracker) Stats(ctx context.Context, address string) (Stats, error) {
+	t.mu.RLock()
+	var snapshot Stats
+	stats, ok := t.stats[utils.NormalizeAddress(address)]
+	if ok {
+		snapshot = stats.snapshot()
+	}
+	t.mu.RUnlock()
+	if ok {
+		return snapshot, nil
+	}
+
+	// a subscribed address without transactions has empty stats, the scan only checks the subscription
+	err := t.Repository.ScanTransactions(ctx, address, repository.TransactionFilter{}, func(types.Transaction) error {
+		return errScanStopped
+	})
+	if err != nil && !errors.Is(err, errScanStopped) {
+		return Stats{}, err
+	}
+	return newAddressStats().snapshot(), nil
+}
+
+// contribution is what a transaction adds to the stats of one of its addresses
+type contribution struct {
+	received, sent, fee *big.Int
+	counterparties      []string
+	activity            Activity
+}
+
+func newContribution(address string, tx types.Transaction) contribution {
+	c := contribution{
+		received: new(big.Int),
+		sent:     new(big.Int),
+		fee:      new(big.Int),
+		activity: Activity{Block: uint64(tx.BlockNumber), Time: time.Unix(int64(tx.Timestamp), 0).UTC()},
+	}
+	// a self-send has no counterparty but the address itself
+	if tx.Direction(address) != types.DirectionSelf {
+		c.counterparties = lower(tx.Counterparties(address))
+	}
+
+	paid := false
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 {
+		// the amounts of a UTXO transaction are the outputs it spends from the address and pays to it
+		for _, in := range tx.Inputs {
+			if utils.SameAddress(in.Address, address) {
+				c.sent.Add(c.sent, parseAmount(in.Value))
+				paid = true
+			}
+		}
+		for _, out := range tx.Outputs {
+			if utils.SameAddress(out.Address, address) {
+				c.received.Add(c.received, parseAmount(out.Value))
+			}
+		}
+	} else {
+		paid = utils.SameAddress(tx.From, address)
+		// a failed EVM transaction moves no value, its fee is still paid
+		if tx.Status != types.StatusFailed {
+			value := parseAmount(tx.Value)
+			if paid {
+				c.sent.Set(value)
+			}
+			if utils.SameAddress(tx.To, address) {
+				c.received.Set(value)
+			}
+		}
+	}
+	if paid {
+		if fee, err := tx.FeeInt(); err == nil {
+			c.fee = fee
+		}
+	}
+
+	return c
+}
+
+// change is a contribution added to the stats of an address, with the activity bounds it replaced
+type change struct {
+	address      string
+	contribution contribution
+	first, last  *Activity
+}
+
+func (c change) revert(stats map[string]*addressStats) {
+	s := stats[c.address]
+	if s == nil {
+		// the address was unsubscribed since
+		return
+	}
+	s.apply(c.contribution, -1)
+	s.first, s.last = c.first, c.last
+}
+
+type addressStats struct {
+	received, sent, fees *big.Int
+	txCount              int
+	// counterparties count the transactions with each counterparty
+	counterparties map[string]int
+	first, last    *Activity
+	// daily and weekly are keyed by the unix time of the start of the period
+	daily, weekly map[int64]*bucket
+}
+
+type bucket struct {
+	received, sent *big.Int
+	txCount        int
+}
+
+func newAddressStats() *addressStats {
+	return &addressStats{
+		received:       new(big.Int),
+		sent:           new(big.Int),
+		fees:           new(big.Int),
+		counterparties: make(map[string]int),
+		daily:          make(map[int64]*bucket),
+		weekly:         make(map[int64]*bucket),
+	}
+}
+
+// apply adds the contribution when sign is 1 and removes it when sign is -1
+func (s *addressStats) apply(c contribution, sign int) {
+	addSigned(s.received, c.received, sign)
+	addSigned(s.sent, c.sent, sign)
+	addSigned(s.fees, c.fee, sign)
+	s.txCount += sign
+	for _, counterparty := range c.counterparties {
+		s.counterparties[counterparty] += sign
+		if s.counterparties[counterparty] <= 0 {
+			delete(s.counterparties, counterparty)
+		}
+	}
+
+	unix := c.activity.Time.Unix()
+	addToBucket(s.daily, unix-mod(unix, day), c, sign)
+	// the unix epoch is a Thursday, the weeks start 3 days before
+	addToBucket(s.weekly, unix-mod(unix+3*day, week), c, sign)
+
+	if sign > 0 {
+		activity := c.activity
+		if s.first == nil || activity.Block < s.first.Block {
+			s.first = &activity
+		}
+		if s.last == nil || activity.Block >= s.last.Block {
+			s.last = &activity
+		}
+	}
+}
+
+func addToBucket(periods map[int64]*bucket, start int64, c contribution, sign int) {
+	b, ok := periods[start]
+	if !ok {
+		b = &bucket{received: new(big.Int), sent: new(big.Int)}
+		periods[start] = b
+	}
+	addSigned(b.received, c.received, sign)
+	addSigned(b.sent, c.sent, sign)
+	b.txCount += sign
+	if b.txCount <= 0 {
+		delete(periods, start)
+	}
+}
+
+func (s *addressStats) snapshot() Stats {
+	stats := Stats{
+		Received:       new(big.Int).Set(s.received),
+		Sent:           new(big.Int).Set(s.sent),
+		FeesPaid:       new(big.Int).Set(s.fees),
+		TxCount:        s.txCount,
+		Counterparties: len(s.counterparties),
+		Daily:          buckets(s.daily),
+		Weekly:         buckets(s.weekly),
+	}
+	if s.first != nil {
+		first, last := *s.first, *s.last
+		stats.FirstActivity, stats.LastActivity = &first, &last
+	}
+
+	return stats
+}
+
+func buckets(periods map[int64]*bucket) []Bucket {
+	list := make([]Bucket, 0, len(periods))
+	for start, b := range periods {
+		list = append(list, Bucket{
+			Start:    time.Unix(start, 0).UTC(),
+			Received: new(big.Int).Set(b.received),
+			Sent:     new(big.Int).Set(b.sent),
+			TxCount:  b.txCount,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+
+	return list
+}
+
+// parseAmount parses an amount of the transaction, an invalid one counts as zero
+func parseAmount(value string) *big.Int {
+	amount, err := utils.ParseBigInt(value)
+	if err != nil {
+		return new(big.Int)
+	}
+	return amount
+}
+
+func addSigned(total, amount *big.Int, sign int) {
+	if sign > 0 {
+		total.Add(total, amount)
+	} else {
+		total.Sub(total, amount)
+	}
+}
+
+func mod(a, b int64) int64 {
+	return ((a % b) + b) % b
+}
+
+func lower(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = utils.NormalizeAddress(v)
+	}
+	return lowered
+}
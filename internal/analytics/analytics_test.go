@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/repository/repotest"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alice = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	bob   = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	carol = "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+
+	// friday is 2024-01-19T16:12:11Z, monday is 2024-01-22T12:00:00Z
+	friday = 1705680731
+	monday = 1705924800
+)
+
+func TestTracker_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return NewTracker(repository.NewInMemRepo())
+	})
+}
+
+func evmTx(hash, from, to, value, fee string, block, timestamp uint64) types.Transaction {
+	return types.Transaction{Hash: hash, From: from, To: to, Value: value, Fee: fee,
+		BlockNumber: utils.HexUint64(block), Timestamp: utils.HexUint64(timestamp), Status: types.StatusSuccess}
+}
+
+func date(value string) time.Time {
+	t, _ := time.Parse(time.DateOnly, value)
+	return t
+}
+
+func TestTracker_Stats(t *testing.T) {
+	ctx := context.TODO()
+	tracker := NewTracker(repository.NewInMemRepo())
+	_, err := tracker.AddAddresses(ctx, []string{alice, bob})
+	assert.NoError(t, err)
+
+	failed := evmTx("0x03", alice, carol, "0x64", "0x5", 10, friday)
+	failed.Status = types.StatusFailed
+	assert.NoError(t, tracker.SaveTransactions(ctx, 10, []types.Transaction{
+		// 1 ETH to bob with a fee of 0.01 ETH
+		evmTx("0x01", alice, bob, "0xde0b6b3a7640000", "0x2386f26fc10000", 10, friday),
+		evmTx("0x02", strings.ToUpper(carol), alice, "0x3e8", "0x1", 10, friday),
+		failed,
+	}))
+	assert.NoError(t, tracker.SaveTransactions(ctx, 11, []types.Transaction{
+		evmTx("0x04", alice, alice, "0x10", "0x2", 11, monday),
+	}))
+
+	stats, err := tracker.Stats(ctx, strings.ToUpper(alice))
+	assert.NoError(t, err)
+	assert.Equal(t, "1000000000000000016", stats.Sent.String())
+	assert.Equal(t, "1016", stats.Received.String())
+	assert.Equal(t, "10000000000000007", stats.FeesPaid.String())
+	assert.Equal(t, 4, stats.TxCount)
+	// bob and carol, the self-send has no counterparty
+	assert.Equal(t, 2, stats.Counterparties)
+	assert.Equal(t, &Activity{Block: 10, Time: time.Unix(friday, 0).UTC()}, stats.FirstActivity)
+	assert.Equal(t, &Activity{Block: 11, Time: time.Unix(monday, 0).UTC()}, stats.LastActivity)
+	assert.Equal(t, []Bucket{
+		{Start: date("2024-01-19"), Received: big.NewInt(1000), Sent: big.NewInt(1000000000000000000), TxCount: 3},
+		{Start: date("2024-01-22"), Received: big.NewInt(16), Sent: big.NewInt(16), TxCount: 1},
+	}, stats.Daily)
+	assert.Equal(t, []Bucket{
+		{Start: date("2024-01-15"), Received: big.NewInt(1000), Sent: big.NewInt(1000000000000000000), TxCount: 3},
+		{Start: date("2024-01-22"), Received: big.NewInt(16), Sent: big.NewInt(16), TxCount: 1},
+	}, stats.Weekly)
+
+	// bob received without paying fees
+	stats, err = tracker.Stats(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, "1000000000000000000", stats.Received.String())
+	assert.Equal(t, 0, stats.Sent.Sign())
+	assert.Equal(t, 0, stats.FeesPaid.Sign())
+	assert.Equal(t, 1, stats.Counterparties)
+
+	_, err = tracker.Stats(ctx, carol)
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+}
+
+func TestTracker_SaveAgain(t *testing.T) {
+	ctx := context.TODO()
+	tracker := NewTracker(repository.NewInMemRepo())
+	_, err := tracker.AddAddresses(ctx, []string{alice})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tracker.SaveTransactions(ctx, 10, []types.Transaction{evmTx("0x01", alice, bob, "0x1", "", 10, friday)}))
+	assert.NoError(t, tracker.SaveTransactions(ctx, 11, []types.Transaction{evmTx("0x02", bob, alice, "0x2", "", 11, monday)}))
+	// the retried block replaces its transactions in the stats
+	assert.NoError(t, tracker.SaveTransactions(ctx, 11, []types.Transaction{evmTx("0x03", carol, alice, "0x3", "", 11, monday)}))
+
+	stats, err := tracker.Stats(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.TxCount)
+	assert.Equal(t, "3", stats.Received.String())
+	assert.Equal(t, 2, stats.Counterparties)
+	assert.Equal(t, uint64(11), stats.LastActivity.Block)
+
+	// an empty retry leaves the previous activity
+	assert.NoError(t, tracker.SaveTransactions(ctx, 11, nil))
+	stats, err = tracker.Stats(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.TxCount)
+	assert.Equal(t, 0, stats.Received.Sign())
+	assert.Equal(t, uint64(10), stats.LastActivity.Block)
+	assert.Len(t, stats.Daily, 1)
+}
+
+func TestTracker_UTXO(t *testing.T) {
+	ctx := context.TODO()
+	const btcAlice, btcBob = "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	tracker := NewTracker(repository.NewInMemRepo())
+	_, err := tracker.AddAddresses(ctx, []string{btcAlice})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tracker.SaveTransactions(ctx, 1, []types.Transaction{{
+		Hash: "f1", From: btcAlice, To: btcBob, Value: "69000000", Fee: "1000000", BlockNumber: 1, Timestamp: friday,
+		Inputs:  []types.UTXO{{Address: btcAlice, Value: "70000000"}},
+		Outputs: []types.UTXO{{Address: btcBob, Value: "50000000"}, {Address: btcAlice, Value: "19000000"}},
+	}}))
+
+	// the change output is received back
+	stats, err := tracker.Stats(ctx, btcAlice)
+	assert.NoError(t, err)
+	assert.Equal(t, "70000000", stats.Sent.String())
+	assert.Equal(t, "19000000", stats.Received.String())
+	assert.Equal(t, "1000000", stats.FeesPaid.String())
+	assert.Equal(t, 1, stats.Counterparties)
+
+	// a Base58 address differing only in case is another address
+	_, err = tracker.Stats(ctx, strings.ToLower(btcAlice))
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+}
+
+func TestTracker_RemoveAddresses(t *testing.T) {
+	ctx := context.TODO()
+	tracker := NewTracker(repository.NewInMemRepo())
+	_, err := tracker.AddAddresses(ctx, []string{alice})
+	assert.NoError(t, err)
+
+	// a subscribed address without transactions has empty stats
+	stats, err := tracker.Stats(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.TxCount)
+	assert.Nil(t, stats.FirstActivity)
+	assert.Empty(t, stats.Daily)
+
+	assert.NoError(t, tracker.SaveTransactions(ctx, 10, []types.Transaction{evmTx("0x01", alice, bob, "0x1", "", 10, friday)}))
+	removed, err := tracker.RemoveAddresses(ctx, []string{strings.ToUpper(alice), bob})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, removed)
+	_, err = tracker.Stats(ctx, alice)
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/TrustWallet/tx-parser/internal/alerting"
+)
+
+const (
+	defaultAlertsLimit = 100
+	maxAlertsLimit     = 1000
+)
+
+// GetAlertsHandler return the latest alerts, newest first, GET /alerts?after={seq}&limit={n}&chain={chain}.
+// A client polls the new alerts with the seq of the newest alert it got as after.
+func (reg *register) GetAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if reg.alerts == nil {
+		writeError(w, http.StatusNotImplemented, codeAlertsDisabled, "Alerts are not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	var after uint64
+	if raw := query.Get("after"); raw != "" {
+		var err error
+		if after, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid after, expected a sequence number")
+			return
+		}
+	}
+	limit := defaultAlertsLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxAlertsLimit {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Invalid limit, expected 1 to %d", maxAlertsLimit))
+			return
+		}
+		limit = n
+	}
+
+	var filter func(alerting.Alert) bool
+	if chain := query.Get("chain"); chain != "" {
+		if _, ok := reg.parsers[chain]; !ok {
+			writeError(w, http.StatusBadRequest, codeUnknownChain, fmt.Sprintf("Unknown chain %q", chain))
+			return
+		}
+		filter = func(alert alerting.Alert) bool { return alert.Chain == chain }
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"alerts": reg.alerts.List(after, limit, filter)})
+}
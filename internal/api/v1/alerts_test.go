@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/alerting"
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAlertsHandler(t *testing.T) {
+	ctx := context.TODO()
+	feed := alerting.NewFeed(10)
+	for _, alert := range []alerting.Alert{
+		{ID: "a", Rule: "treasury-outflow", Chain: testChain, Address: addr1, Value: "11"},
+		{ID: "b", Rule: "treasury-outflow", Chain: "bitcoin", Address: addr1, Value: "1"},
+		{ID: "c", Rule: "blocklist", Chain: testChain, Address: addr2, Value: "0.5"},
+	} {
+		assert.NoError(t, feed.Send(ctx, alert))
+	}
+	parsers := map[string]parser.Parser{
+		testChain: parser.NewParserService(mocks.NewRepository(t)),
+		"bitcoin": parser.NewParserService(mocks.NewRepository(t)),
+	}
+	reg := NewRegister(parsers, testChain, RouteTimeouts{}, WithAlertFeed(feed))
+
+	ids := func(w *httptest.ResponseRecorder) []string {
+		var body struct {
+			Alerts []struct {
+				Seq uint64 `json:"seq"`
+				ID  string `json:"id"`
+			} `json:"alerts"`
+		}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+		ids := []string{}
+		for _, alert := range body.Alerts {
+			ids = append(ids, alert.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		name  string
+		query string
+		ids   []string
+	}{
+		{"all", "", []string{"c", "b", "a"}},
+		{"after", "?after=1", []string{"c", "b"}},
+		{"limit", "?limit=1", []string{"c"}},
+		{"chain", "?chain=" + testChain, []string{"c", "a"}},
+		{"none", "?after=3", []string{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.GetAlertsHandler(w, httptest.NewRequest(http.MethodGet, "/alerts"+tt.query, nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.ids, ids(w))
+		})
+	}
+
+	errorTests := []struct {
+		name   string
+		query  string
+		status int
+		code   errorCode
+	}{
+		{"invalid after", "?after=-1", http.StatusBadRequest, codeInvalidRequest},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, codeInvalidRequest},
+		{"unknown chain", "?chain=solana", http.StatusBadRequest, codeUnknownChain},
+	}
+	for _, tt := range errorTests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.GetAlertsHandler(w, httptest.NewRequest(http.MethodGet, "/alerts"+tt.query, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+
+	// the alerts are not enabled
+	reg = NewRegister(parsers, testChain, RouteTimeouts{})
+	w := httptest.NewRecorder()
+	reg.GetAlertsHandler(w, httptest.NewRequest(http.MethodGet, "/alerts", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Equal(t, codeAlertsDisabled, decodeError(t, w).Code)
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/TrustWallet/tx-parser/internal/parser"
+)
+
+const (
+	// maxSyncBulkAddresses is the limit of addresses processed within a request,
+	// larger imports must run as an asynchronous job
+	maxSyncBulkAddresses = 10_000
+	// maxAsyncBulkAddresses is the limit of addresses accepted by an asynchronous job
+	maxAsyncBulkAddresses = 1_000_000
+
+	defaultJobIssuesLimit = 100
+	maxJobIssuesLimit     = 1000
+)
+
+var errTooManyAddresses = errors.New("too many addresses")
+
+type bulkResponse struct {
+	Total   int                               `json:"total"`
+	Counts  map[parser.SubscriptionStatus]int `json:"counts"`
+	Results []parser.SubscriptionResult       `json:"results"`
+}
+
+// BulkSubscriptionsHandler subscribe or unsubscribe a JSON array or NDJSON stream of addresses.
+// The action is selected by `action` query parameter (subscribe by default), `async=true`
+// starts a background job instead of waiting for the results.
+func (reg *register) BulkSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	chain, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteBulkSubscriptions)
+	defer cancel()
+
+	action := parser.BulkAction(r.URL.Query().Get("action"))
+	if action == "" {
+		action = parser.BulkSubscribe
+	}
+	if action != parser.BulkSubscribe && action != parser.BulkUnsubscribe {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Action must be subscribe or unsubscribe")
+		return
+	}
+	async := r.URL.Query().Get("async") == "true"
+
+	limit := maxSyncBulkAddresses
+	if async {
+		limit = maxAsyncBulkAddresses
+	}
+	addresses, err := decodeAddresses(r.Body, limit)
+	if err != nil {
+		if errors.Is(err, errTooManyAddresses) {
+			writeError(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("Too many addresses, limit is %d", limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Error parsing request body")
+		return
+	}
+
+	if async {
+		job, err := parserSvc.StartBulkJob(ctx, action, addresses)
+		if errors.Is(err, parser.ErrTooManyJobs) {
+			writeError(w, http.StatusTooManyRequests, codeTooManyJobs, "Too many bulk jobs running, retry later")
+			return
+		}
+		if errors.Is(err, parser.ErrJobsStopped) {
+			writeError(w, http.StatusServiceUnavailable, codeShuttingDown, "Shutting down, retry later")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "Error starting bulk job")
+			return
+		}
+
+		location := url.Values{"id": {job.ID}, "chain": {chain}}
+		w.Header().Set("Location", RouteBulkJobs+"?"+location.Encode())
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	var results []parser.SubscriptionResult
+	if action == parser.BulkUnsubscribe {
+		results = parserSvc.BulkUnsubscribe(ctx, addresses)
+	} else {
+		results = parserSvc.BulkSubscribe(ctx, addresses)
+	}
+
+	resp := bulkResponse{
+		Total:   len(results),
+		Counts:  make(map[parser.SubscriptionStatus]int),
+		Results: checksumResults(results),
+	}
+	for _, res := range results {
+		resp.Counts[res.Status]++
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetBulkJobHandler return the progress of an asynchronous bulk job
+func (reg *register) GetBulkJobHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteBulkJobs)
+	defer cancel()
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Id parameter is missing")
+		return
+	}
+
+	job, ok := parserSvc.GetBulkJob(ctx, id)
+	if !ok {
+		writeError(w, http.StatusNotFound, codeJobNotFound, "Job not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// GetBulkJobIssuesHandler return a page of the failed and invalid results of an asynchronous bulk job,
+// GET /subscriptions/jobs/issues?id={id}&offset={n}&limit={n}
+func (reg *register) GetBulkJobIssuesHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteBulkJobIssues)
+	defer cancel()
+
+	query := r.URL.Query()
+	id := query.Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Id parameter is missing")
+		return
+	}
+	var offset int
+	if raw := query.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid offset, expected a non-negative number")
+			return
+		}
+		offset = n
+	}
+	limit := defaultJobIssuesLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxJobIssuesLimit {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Invalid limit, expected 1 to %d", maxJobIssuesLimit))
+			return
+		}
+		limit = n
+	}
+
+	issues, ok := parserSvc.GetBulkJobIssues(ctx, id, offset, limit)
+	if !ok {
+		writeError(w, http.StatusNotFound, codeJobNotFound, "Job not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"offset": offset, "results": checksumResults(issues)})
+}
+
+// decodeAddresses read addresses from either a JSON array or a stream of newline
+// delimited JSON values. Each element is an address string or an object with
+// `address` field.
+func decodeAddresses(body io.Reader, limit int) ([]string, error) {
+	reader := bufio.NewReader(body)
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(reader)
+	isArray := first == '['
+	if isArray {
+		// consume the opening bracket
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	var addresses []string
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		address, err := decodeAddress(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(addresses) == limit {
+			return nil, errTooManyAddresses
+		}
+		addresses = append(addresses, address)
+	}
+
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	return addresses, nil
+}
+
+func decodeAddress(raw json.RawMessage) (string, error) {
+	var address string
+	if err := json.Unmarshal(raw, &address); err == nil {
+		return address, nil
+	}
+
+	var item struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return "", err
+	}
+
+	return item.Address, nil
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		return b, reader.UnreadByte()
+	}
+}
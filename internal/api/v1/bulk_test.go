@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDecodeAddresses(t *testing.T) {
+	addresses, err := decodeAddresses(strings.NewReader(` ["a", {"address": "b"}] `), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, addresses)
+
+	addresses, err = decodeAddresses(strings.NewReader("\"a\"\n{\"address\":\"b\"}\n\"c\"\n"), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, addresses)
+
+	_, err = decodeAddresses(strings.NewReader(`["a", "b", "c"]`), 2)
+	assert.ErrorIs(t, err, errTooManyAddresses)
+
+	_, err = decodeAddresses(strings.NewReader(`["a", 1]`), 10)
+	assert.Error(t, err)
+
+	_, err = decodeAddresses(strings.NewReader(`["a"`), 10)
+	assert.Error(t, err)
+}
+
+func TestBulkSubscriptionsHandler(t *testing.T) {
+	reg := NewRegister(singleChain(parser.NewParserService(repository.NewInMemRepo())), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	body := `["` + addr1 + `", "` + addr1Checksum + `", "hello"]`
+	reg.BulkSubscriptionsHandler(w, httptest.NewRequest(http.MethodPost, "/subscriptions/bulk", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp bulkResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, map[parser.SubscriptionStatus]int{
+		parser.StatusAdded:             1,
+		parser.StatusAlreadySubscribed: 1,
+		parser.StatusInvalid:           1,
+	}, resp.Counts)
+	assert.Equal(t, addr1Checksum, resp.Results[0].Address)
+	assert.Equal(t, "hello", resp.Results[2].Address)
+
+	w = httptest.NewRecorder()
+	reg.BulkSubscriptionsHandler(w, httptest.NewRequest(http.MethodPost, "/subscriptions/bulk?action=remove", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, codeInvalidRequest, decodeError(t, w).Code)
+}
+
+func TestBulkSubscriptionsHandler_async(t *testing.T) {
+	reg := NewRegister(singleChain(parser.NewParserService(repository.NewInMemRepo())), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	body := "\"" + addr1 + "\"\n\"" + addr2 + "\"\n\"hello\"\n"
+	reg.BulkSubscriptionsHandler(w, httptest.NewRequest(http.MethodPost, "/subscriptions/bulk?async=true", strings.NewReader(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var job parser.BulkJob
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/subscriptions/jobs?chain=ethereum&id="+job.ID, w.Header().Get("Location"))
+
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		reg.GetBulkJobHandler(w, httptest.NewRequest(http.MethodGet, "/subscriptions/jobs?chain=ethereum&id="+job.ID, nil))
+		if w.Code != http.StatusOK {
+			return false
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job.State == parser.JobCompleted
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, job.Counts[parser.StatusAdded])
+	assert.Equal(t, 1, job.Counts[parser.StatusInvalid])
+	// the job only reports its progress, the results needing attention are paged
+	assert.NotContains(t, w.Body.String(), "results")
+
+	w = httptest.NewRecorder()
+	reg.GetBulkJobIssuesHandler(w, httptest.NewRequest(http.MethodGet, "/subscriptions/jobs/issues?id="+job.ID+"&limit=10", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"offset": 0, "results": [{"address": "hello", "status": "invalid", "error": "invalid address"}]}`, w.Body.String())
+
+	for _, target := range []string{"/subscriptions/jobs/issues?id=" + job.ID + "&limit=0", "/subscriptions/jobs/issues?id=" + job.ID + "&offset=-1"} {
+		w = httptest.NewRecorder()
+		reg.GetBulkJobIssuesHandler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+	w = httptest.NewRecorder()
+	reg.GetBulkJobIssuesHandler(w, httptest.NewRequest(http.MethodGet, "/subscriptions/jobs/issues?id=unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	reg.GetBulkJobHandler(w, httptest.NewRequest(http.MethodGet, "/subscriptions/jobs?id=unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, codeJobNotFound, decodeError(t, w).Code)
+}
+
+func TestBulkSubscriptionsHandler_tooManyJobs(t *testing.T) {
+	release := make(chan time.Time)
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, []string{addr1}).WaitUntil(release).Return([]bool{true}, nil)
+	parserSvc := parser.NewParserService(repo)
+	reg := NewRegister(singleChain(parserSvc), testChain, RouteTimeouts{})
+
+	var ids []string
+	for {
+		w := httptest.NewRecorder()
+		reg.BulkSubscriptionsHandler(w, httptest.NewRequest(http.MethodPost, "/subscriptions/bulk?async=true", strings.NewReader(`["`+addr1+`"]`)))
+		if w.Code != http.StatusAccepted {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, codeTooManyJobs, decodeError(t, w).Code)
+			break
+		}
+		var job parser.BulkJob
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		ids = append(ids, job.ID)
+	}
+	assert.NotEmpty(t, ids)
+
+	close(release)
+	for _, id := range ids {
+		assert.Eventually(t, func() bool {
+			job, _ := parserSvc.GetBulkJob(context.TODO(), id)
+			return job.State == parser.JobCompleted
+		}, time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestBulkSubscriptionsHandler_jobsStopped(t *testing.T) {
+	parserSvc := parser.NewParserService(mocks.NewRepository(t))
+	assert.NoError(t, parserSvc.StopBulkJobs(context.TODO()))
+	reg := NewRegister(singleChain(parserSvc), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.BulkSubscriptionsHandler(w, httptest.NewRequest(http.MethodPost, "/subscriptions/bulk?async=true", strings.NewReader(`["`+addr1+`"]`)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, codeShuttingDown, decodeError(t, w).Code)
+}
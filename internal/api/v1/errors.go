@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/parser"
+)
+
+// errorCode is a machine-readable code of an API error
+type errorCode string
+
+const (
+	codeMethodNotAllowed   errorCode = "method_not_allowed"
+	codeInvalidRequest     errorCode = "invalid_request"
+	codePayloadTooLarge    errorCode = "payload_too_large"
+	codeInvalidAddress     errorCode = "invalid_address"
+	codeAlreadySubscribed  errorCode = "already_subscribed"
+	codeAddressNotFound    errorCode = "address_not_found"
+	codeJobNotFound        errorCode = "job_not_found"
+	codeTooManyJobs        errorCode = "too_many_jobs"
+	codeShuttingDown       errorCode = "shutting_down"
+	codeInvalidHash        errorCode = "invalid_hash"
+	codeTxNotFound         errorCode = "transaction_not_found"
+	codeBlockNotFound      errorCode = "block_not_found"
+	codeNodeUnavailable    errorCode = "node_unavailable"
+	codeStatsUnavailable   errorCode = "stats_unavailable"
+	codeInvalidLabel       errorCode = "invalid_label"
+	codeAlertsDisabled     errorCode = "alerts_disabled"
+	codeUnknownChain       errorCode = "unknown_chain"
+	codeStorageUnavailable errorCode = "storage_unavailable"
+	codeTimeout            errorCode = "timeout"
+	codeInternal           errorCode = "internal_error"
+)
+
+type errorBody struct {
+	Code    errorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// errorResponse is the uniform error envelope of every endpoint
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, code errorCode, message string) {
+	writeJSON(w, status, errorResponse{Error: errorBody{Code: code, Message: message}})
+}
+
+// writeParserError maps errors returned by the parser service to status codes
+func writeParserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, codeTimeout, "request deadline exceeded")
+	case errors.Is(err, parser.ErrInvalidAddress):
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidAddress, err.Error())
+	case errors.Is(err, parser.ErrAlreadySubscribed):
+		writeError(w, http.StatusConflict, codeAlreadySubscribed, err.Error())
+	case errors.Is(err, parser.ErrAddressNotFound):
+		writeError(w, http.StatusNotFound, codeAddressNotFound, err.Error())
+	case errors.Is(err, parser.ErrInvalidHash):
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidHash, err.Error())
+	case errors.Is(err, parser.ErrTransactionNotFound):
+		writeError(w, http.StatusNotFound, codeTxNotFound, err.Error())
+	case errors.Is(err, parser.ErrBlockNotFound):
+		writeError(w, http.StatusNotFound, codeBlockNotFound, err.Error())
+	case errors.Is(err, parser.ErrNodeUnavailable):
+		writeError(w, http.StatusBadGateway, codeNodeUnavailable, parser.ErrNodeUnavailable.Error())
+	case errors.Is(err, parser.ErrInvalidLabel):
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidLabel, err.Error())
+	case errors.Is(err, parser.ErrStatsUnavailable):
+		writeError(w, http.StatusNotImplemented, codeStatsUnavailable, err.Error())
+	case errors.Is(err, parser.ErrStorageUnavailable):
+		writeError(w, http.StatusServiceUnavailable, codeStorageUnavailable, parser.ErrStorageUnavailable.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, codeInternal, "internal error")
+	}
+}
+
+// allowMethod writes an error and returns false if the request method is not one of the expected ones
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	allowed := strings.Join(methods, ", ")
+	w.Header().Set("Allow", allowed)
+	if len(methods) == 1 {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Only "+allowed+" method is accepted")
+	} else {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Only "+allowed+" methods are accepted")
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/export"
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/types"
+)
+
+// ExportTransactionsHandler stream the transaction history of an address as a CSV or NDJSON statement,
+// GET /transactions/export. The rows are written as they are read from the storage.
+func (reg *register) ExportTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	chain, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteExport)
+	defer cancel()
+
+	query := r.URL.Query()
+	address := query.Get("address")
+	if address == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Address parameter is missing")
+		return
+	}
+	format := export.FormatCSV
+	if name := query.Get("format"); name != "" {
+		var err error
+		if format, err = export.ParseFormat(name); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+	}
+	location := time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		var err error
+		if location, err = time.LoadLocation(tz); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Unknown time zone %q", tz))
+			return
+		}
+	}
+	filter, err := parseTransactionFilter(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	// a long history outlives the write timeout of the server, the statement is bounded by the route deadline
+	deadline, _ := ctx.Deadline()
+	_ = http.NewResponseController(w).SetWriteDeadline(deadline)
+
+	// the status is sent with the first row, so that an invalid or unknown address is still reported as an error
+	var out *export.Writer
+	start := func() {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.%s", chain, address, format)))
+		w.WriteHeader(http.StatusOK)
+		out = export.NewWriter(w, export.Options{
+			Format:   format,
+			Address:  address,
+			Decimals: parserSvc.Decimals(),
+			Location: location,
+		})
+	}
+	rows := 0
+	err = parserSvc.ScanTransactions(ctx, address, filter, func(tx types.Transaction) error {
+		if out == nil {
+			start()
+		}
+		rows++
+		return out.Write(checksumTransactions([]types.Transaction{tx})[0])
+	})
+	if err != nil {
+		if out == nil {
+			writeParserError(w, err)
+			return
+		}
+		// the statement is cut, the connection is aborted so that the client does not take it as complete
+		slog.ErrorContext(ctx, "error exporting transactions", slog.String(logging.KeyAddress, address),
+			slog.Int("rows", rows), logging.Err(err))
+		panic(http.ErrAbortHandler)
+	}
+
+	if out == nil {
+		start()
+	}
+	if err := out.Close(); err != nil {
+		slog.ErrorContext(ctx, "error exporting transactions", slog.String(logging.KeyAddress, address), logging.Err(err))
+		panic(http.ErrAbortHandler)
+	}
+}
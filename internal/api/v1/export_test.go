@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// scanOf return a ScanTransactions mock calling fn with txns then returning err
+func scanOf(err error, txns ...types.Transaction) func(context.Context, string, repository.TransactionFilter, func(types.Transaction) error) error {
+	return func(ctx context.Context, address string, filter repository.TransactionFilter, fn func(types.Transaction) error) error {
+		for _, tx := range txns {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+func TestExportTransactionsHandler(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	tx := types.Transaction{BlockNumber: 16, Timestamp: 1705680731, Hash: "0x01", From: addr2, To: addr1,
+		Value: "0x14d1120d7b160000", Status: types.StatusSuccess, Type: "0x2"}
+	repo.On("ScanTransactions", mock.Anything, addr1, repository.TransactionFilter{}, mock.Anything).Return(scanOf(nil, tx)).Once()
+	repo.On("ScanTransactions", mock.Anything, addr1, repository.TransactionFilter{FromBlock: 16}, mock.Anything).Return(scanOf(nil, tx)).Once()
+	repo.On("ScanTransactions", mock.Anything, addr2, repository.TransactionFilter{}, mock.Anything).Return(scanOf(nil)).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.ExportTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions/export?address="+addr1, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="`+testChain+`-`+addr1+`.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "block,timestamp,hash,direction,from,to,value,status,type\n"+
+		"16,2024-01-19T16:12:11Z,0x01,inbound,"+addr2Checksum+","+addr1Checksum+",1.5,success,0x2\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	reg.ExportTransactionsHandler(w, httptest.NewRequest(http.MethodGet,
+		"/transactions/export?address="+addr1+"&format=ndjson&tz=Asia/Tokyo&fromBlock=16", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"block":16,"timestamp":"2024-01-20T01:12:11+09:00","hash":"0x01","direction":"inbound",
+		"from":"`+addr2Checksum+`","to":"`+addr1Checksum+`","value":"1.5","status":"success","type":"0x2"}`, w.Body.String())
+
+	// an empty history is an empty statement
+	w = httptest.NewRecorder()
+	reg.ExportTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions/export?address="+addr2, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "block,timestamp,hash,direction,from,to,value,status,type\n", w.Body.String())
+}
+
+func TestExportTransactionsHandler_errors(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("ScanTransactions", mock.Anything, addr2, repository.TransactionFilter{}, mock.Anything).Return(repository.ErrAddressNotFound).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		code   errorCode
+	}{
+		{"missing address", "", http.StatusBadRequest, codeInvalidRequest},
+		{"unknown format", "address=" + addr1 + "&format=xlsx", http.StatusBadRequest, codeInvalidRequest},
+		{"unknown time zone", "address=" + addr1 + "&tz=Mars/Olympus", http.StatusBadRequest, codeInvalidRequest},
+		{"invalid filter", "address=" + addr1 + "&status=pending", http.StatusBadRequest, codeInvalidRequest},
+		{"invalid address", "address=test", http.StatusUnprocessableEntity, codeInvalidAddress},
+		{"not subscribed", "address=" + addr2, http.StatusNotFound, codeAddressNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.ExportTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions/export?"+tt.query, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+}
+
+func TestExportTransactionsHandler_cut(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	tx := types.Transaction{BlockNumber: 16, Hash: "0x01", From: addr2, To: addr1, Value: "0x0"}
+	repo.On("ScanTransactions", mock.Anything, addr1, repository.TransactionFilter{}, mock.Anything).
+		Return(scanOf(fmt.Errorf("some error"), tx)).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	// the rows are already sent, the response is aborted
+	w := httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		reg.ExportTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions/export?address="+addr1, nil))
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "block,"))
+}
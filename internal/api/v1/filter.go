@@ -0,0 +1,152 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// addressTransaction is a rendered transaction with its direction for the queried address
+type addressTransaction struct {
+	renderedTransaction
+	Direction types.Direction `json:"direction"`
+}
+
+func withDirections(txns []renderedTransaction, address string) []addressTransaction {
+	annotated := make([]addressTransaction, len(txns))
+	for i, tx := range txns {
+		annotated[i] = addressTransaction{renderedTransaction: tx, Direction: tx.Direction(address)}
+	}
+
+	return annotated
+}
+
+// parseTransactionFilter read the filter of GET /transactions from the query parameters
+func parseTransactionFilter(query url.Values) (repository.TransactionFilter, error) {
+	var filter repository.TransactionFilter
+	var err error
+
+	switch direction := query.Get("direction"); direction {
+	case "":
+	case "in", string(types.DirectionInbound):
+		filter.Direction = types.DirectionInbound
+	case "out", string(types.DirectionOutbound):
+		filter.Direction = types.DirectionOutbound
+	case string(types.DirectionSelf):
+		filter.Direction = types.DirectionSelf
+	default:
+		return filter, fmt.Errorf("direction must be in, out or self, got %q", direction)
+	}
+
+	filter.Counterparty = query.Get("counterparty")
+
+	if filter.FromBlock, err = parseBlockNumber(query, "fromBlock"); err != nil {
+		return filter, err
+	}
+	if filter.ToBlock, err = parseBlockNumber(query, "toBlock"); err != nil {
+		return filter, err
+	}
+	if filter.ToBlock > 0 && filter.FromBlock > filter.ToBlock {
+		return filter, fmt.Errorf("fromBlock must not be after toBlock")
+	}
+
+	if filter.FromTime, err = parseTime(query, "fromTime"); err != nil {
+		return filter, err
+	}
+	if filter.ToTime, err = parseTime(query, "toTime"); err != nil {
+		return filter, err
+	}
+	if !filter.FromTime.IsZero() && !filter.ToTime.IsZero() && filter.FromTime.After(filter.ToTime) {
+		return filter, fmt.Errorf("fromTime must not be after toTime")
+	}
+
+	if filter.MinValue, err = parseValue(query, "minValue"); err != nil {
+		return filter, err
+	}
+	if filter.MaxValue, err = parseValue(query, "maxValue"); err != nil {
+		return filter, err
+	}
+	if filter.MinValue != nil && filter.MaxValue != nil && filter.MinValue.Cmp(filter.MaxValue) > 0 {
+		return filter, fmt.Errorf("minValue must not be above maxValue")
+	}
+
+	switch status := types.TxStatus(query.Get("status")); status {
+	case "", types.StatusSuccess, types.StatusFailed:
+		filter.Status = status
+	default:
+		return filter, fmt.Errorf("status must be success or failed, got %q", status)
+	}
+
+	if raw := query.Get("type"); raw != "" {
+		txType, err := parseUint(raw)
+		if err != nil {
+			return filter, fmt.Errorf("type must be a decimal or 0x-hex number")
+		}
+		filter.Type = utils.EncodeUint64(txType)
+	}
+
+	switch event := types.TxEvent(query.Get("event")); event {
+	case "", types.EventTransfer, types.EventCall, types.EventDeployment:
+		filter.Event = event
+	default:
+		return filter, fmt.Errorf("event must be transfer, call or deployment, got %q", event)
+	}
+
+	return filter, nil
+}
+
+func parseBlockNumber(query url.Values, name string) (uint64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	number, err := parseUint(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a decimal or 0x-hex block number", name)
+	}
+	return number, nil
+}
+
+// parseValue read an amount in the smallest unit of the chain, nil when it is missing
+func parseValue(query url.Values, name string) (*big.Int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := utils.ParseBigInt(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a decimal or 0x-hex amount in the smallest unit", name)
+	}
+	return value, nil
+}
+
+// parseTime read a RFC 3339 time or unix seconds
+func parseTime(query url.Values, name string) (time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a RFC 3339 time or unix seconds", name)
+	}
+	return t, nil
+}
+
+// parseUint read a decimal or 0x-hex number
+func parseUint(raw string) (uint64, error) {
+	if hexDigits, ok := strings.CutPrefix(raw, "0x"); ok {
+		return strconv.ParseUint(hexDigits, 16, 64)
+	}
+	return strconv.ParseUint(raw, 10, 64)
+}
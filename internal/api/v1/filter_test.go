@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseTransactionFilter(t *testing.T) {
+	tests := []struct {
+		query string
+		want  repository.TransactionFilter
+		err   string
+	}{
+		{"", repository.TransactionFilter{}, ""},
+		{"direction=in", repository.TransactionFilter{Direction: types.DirectionInbound}, ""},
+		{"direction=outbound", repository.TransactionFilter{Direction: types.DirectionOutbound}, ""},
+		{"direction=self&counterparty=" + addr2, repository.TransactionFilter{Direction: types.DirectionSelf, Counterparty: addr2}, ""},
+		{"fromBlock=16&toBlock=0x20", repository.TransactionFilter{FromBlock: 16, ToBlock: 32}, ""},
+		{"fromTime=1700000000&toTime=2024-01-19T16:12:11Z", repository.TransactionFilter{
+			FromTime: time.Unix(1700000000, 0),
+			ToTime:   time.Date(2024, time.January, 19, 16, 12, 11, 0, time.UTC),
+		}, ""},
+		{"minValue=1000&maxValue=0xde0b6b3a7640000", repository.TransactionFilter{
+			MinValue: big.NewInt(1000),
+			MaxValue: big.NewInt(1000000000000000000),
+		}, ""},
+		{"status=failed&type=2", repository.TransactionFilter{Status: types.StatusFailed, Type: "0x2"}, ""},
+		{"type=0x0", repository.TransactionFilter{Type: "0x0"}, ""},
+		{"event=deployment", repository.TransactionFilter{Event: types.EventDeployment}, ""},
+		{"direction=sideways", repository.TransactionFilter{}, "direction must be in, out or self"},
+		{"fromBlock=latest", repository.TransactionFilter{}, "fromBlock must be a decimal or 0x-hex block number"},
+		{"fromBlock=20&toBlock=10", repository.TransactionFilter{}, "fromBlock must not be after toBlock"},
+		{"toTime=yesterday", repository.TransactionFilter{}, "toTime must be a RFC 3339 time or unix seconds"},
+		{"fromTime=1700000001&toTime=1700000000", repository.TransactionFilter{}, "fromTime must not be after toTime"},
+		{"minValue=-1", repository.TransactionFilter{}, "minValue must be a decimal or 0x-hex amount"},
+		{"minValue=2&maxValue=1", repository.TransactionFilter{}, "minValue must not be above maxValue"},
+		{"status=pending", repository.TransactionFilter{}, "status must be success or failed"},
+		{"type=eip1559", repository.TransactionFilter{}, "type must be a decimal or 0x-hex number"},
+		{"event=swap", repository.TransactionFilter{}, "event must be transfer, call or deployment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+			filter, err := parseTransactionFilter(query)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, filter)
+		})
+	}
+}
+
+func TestGetTransactionsHandler_filter(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	expectLabels(repo, map[string]types.AddressLabel{})
+	filter := repository.TransactionFilter{Direction: types.DirectionInbound, FromBlock: 10, Status: types.StatusSuccess}
+	repo.On("QueryTransactions", mock.Anything, addr1, filter).Return([]types.Transaction{
+		{From: addr2, To: addr1, Hash: "hash1", BlockNumber: 12, Status: types.StatusSuccess},
+	}, nil).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet,
+		"/transactions?address="+addr1+"&direction=in&fromBlock=10&status=success", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var txns []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &txns))
+	if assert.Len(t, txns, 1) {
+		assert.Equal(t, "inbound", txns[0]["direction"])
+		assert.Equal(t, "success", txns[0]["status"])
+		assert.Equal(t, addr2Checksum, txns[0]["from"])
+	}
+
+	w = httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions?address="+addr1+"&direction=up", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, codeInvalidRequest, decodeError(t, w).Code)
+
+	w = httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions?address="+addr1+"&counterparty=hello", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, codeInvalidAddress, decodeError(t, w).Code)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// addressLabel is the label of an address
+type addressLabel struct {
+	Address string   `json:"address"`
+	Label   string   `json:"label"`
+	Tags    []string `json:"tags"`
+}
+
+// AddressHandler serves the resources of an address, /addresses/{address}/stats and /addresses/{address}/labels
+func (reg *register) AddressHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/labels") {
+		reg.AddressLabelsHandler(w, r)
+		return
+	}
+	reg.GetAddressStatsHandler(w, r)
+}
+
+// AddressLabelsHandler return, replace or remove the label of an address, GET, PUT and DELETE
+// /addresses/{address}/labels. Only a subscribed address can be labeled, GET also returns the
+// label of the label book.
+func (reg *register) AddressLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteAddresses)
+	defer cancel()
+
+	address, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, RouteAddresses), "/labels")
+	if !found || address == "" || strings.Contains(address, "/") {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Expected /addresses/{address}/labels")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var label types.AddressLabel
+		if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Error parsing request body")
+			return
+		}
+		if err := parserSvc.SetLabel(ctx, address, label); err != nil {
+			writeParserError(w, err)
+			return
+		}
+	case http.MethodDelete:
+		if err := parserSvc.SetLabel(ctx, address, types.AddressLabel{}); err != nil {
+			writeParserError(w, err)
+			return
+		}
+	}
+
+	labels, err := parserSvc.GetLabels(ctx, []string{address})
+	if err != nil {
+		writeParserError(w, err)
+		return
+	}
+	label := labels[utils.NormalizeAddress(address)]
+	rendered := addressLabel{Address: utils.ToChecksumAddress(address), Label: label.Label, Tags: label.Tags}
+	if rendered.Tags == nil {
+		rendered.Tags = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, rendered)
+}
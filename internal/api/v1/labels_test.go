@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/labels"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressLabelsHandler(t *testing.T) {
+	ctx := context.TODO()
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, addr1))
+	book, err := labels.ParseBook(strings.NewReader(`{"labels": [{"address": "` + addr2 + `", "label": "Binance 14", "tags": ["exchange"]}]}`))
+	assert.NoError(t, err)
+	reg := NewRegister(singleChain(parser.NewParserService(repo, parser.WithLabelBook(book))), testChain, RouteTimeouts{})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		reg.AddressHandler(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodPut, "/addresses/"+addr1+"/labels", `{"label": "hot wallet", "tags": ["treasury"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"address": "`+addr1Checksum+`", "label": "hot wallet", "tags": ["treasury"]}`, w.Body.String())
+
+	w = serve(http.MethodGet, "/addresses/"+addr1+"/labels", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"address": "`+addr1Checksum+`", "label": "hot wallet", "tags": ["treasury"]}`, w.Body.String())
+
+	// the label book labels the addresses which are not subscribed
+	w = serve(http.MethodGet, "/addresses/"+addr2+"/labels", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"address": "`+addr2Checksum+`", "label": "Binance 14", "tags": ["exchange"]}`, w.Body.String())
+
+	w = serve(http.MethodDelete, "/addresses/"+addr1+"/labels", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"address": "`+addr1Checksum+`", "label": "", "tags": []}`, w.Body.String())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   errorCode
+	}{
+		{"not subscribed", http.MethodPut, "/addresses/" + addr2 + "/labels", `{"label": "customer 123"}`, http.StatusNotFound, codeAddressNotFound},
+		{"invalid address", http.MethodPut, "/addresses/test/labels", `{"label": "customer 123"}`, http.StatusUnprocessableEntity, codeInvalidAddress},
+		{"invalid body", http.MethodPut, "/addresses/" + addr1 + "/labels", `{"label": 1}`, http.StatusBadRequest, codeInvalidRequest},
+		{"invalid label", http.MethodPut, "/addresses/" + addr1 + "/labels", `{"label": "` + strings.Repeat("a", 101) + `"}`, http.StatusUnprocessableEntity, codeInvalidLabel},
+		{"method", http.MethodPost, "/addresses/" + addr1 + "/labels", `{}`, http.StatusMethodNotAllowed, codeMethodNotAllowed},
+		{"stats", http.MethodGet, "/addresses/" + addr1 + "/stats", "", http.StatusNotImplemented, codeStatsUnavailable},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+	assert.Equal(t, "GET, PUT, DELETE", serve(http.MethodPost, "/addresses/"+addr1+"/labels", "").Header().Get("Allow"))
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// GetTransactionHandler return a transaction by hash, GET /transactions/{hash}
+func (reg *register) GetTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteTransaction)
+	defer cancel()
+
+	hash := strings.TrimPrefix(r.URL.Path, RouteTransaction)
+	if hash == "" || strings.Contains(hash, "/") {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Expected /transactions/{hash}")
+		return
+	}
+
+	lookup, err := parserSvc.GetTransaction(ctx, hash)
+	if err != nil {
+		writeParserError(w, err)
+		return
+	}
+
+	rendered, err := reg.renderLookups(ctx, parserSvc, []parser.TransactionLookup{lookup})
+	if err != nil {
+		writeParserError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rendered[0])
+}
+
+// GetBlockTransactionsHandler return the stored transactions of a block, GET /blocks/{number}/transactions
+func (reg *register) GetBlockTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteBlocks)
+	defer cancel()
+
+	number, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, RouteBlocks), "/transactions")
+	if !found || number == "" || strings.Contains(number, "/") {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Expected /blocks/{number}/transactions")
+		return
+	}
+	// the number is decimal, as returned by /current-block, or 0x-hex
+	blockNumber, err := parseUint(number)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid block number")
+		return
+	}
+
+	lookups, err := parserSvc.GetBlockTransactions(ctx, blockNumber)
+	if err != nil {
+		writeParserError(w, err)
+		return
+	}
+
+	rendered, err := reg.renderLookups(ctx, parserSvc, lookups)
+	if err != nil {
+		writeParserError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"block": blockNumber, "transactions": rendered})
+}
+
+// transactionLookup is the rendering of parser.TransactionLookup with the labels and the call of the transaction
+type transactionLookup struct {
+	Transaction renderedTransaction   `json:"transaction"`
+	Matches     []parser.AddressMatch `json:"matches"`
+	Source      parser.Source         `json:"source"`
+}
+
+// renderLookups render the looked up transactions as renderTransactions does and their matched addresses
+// in EIP-55 checksum form
+func (reg *register) renderLookups(ctx context.Context, parserSvc parser.Parser, lookups []parser.TransactionLookup) ([]transactionLookup, error) {
+	txns := make([]types.Transaction, len(lookups))
+	for i, lookup := range lookups {
+		txns[i] = lookup.Transaction
+	}
+	transactions, err := reg.renderTransactions(ctx, parserSvc, txns)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]transactionLookup, len(lookups))
+	for i, lookup := range lookups {
+		matches := make([]parser.AddressMatch, len(lookup.Matches))
+		for j, match := range lookup.Matches {
+			match.Address = utils.ToChecksumAddress(match.Address)
+			matches[j] = match
+		}
+		rendered[i] = transactionLookup{Transaction: transactions[i], Matches: matches, Source: lookup.Source}
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/abi"
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const txHash = "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+
+func TestGetTransactionHandler(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	expectLabels(repo, map[string]types.AddressLabel{})
+	repo.On("GetTransactionByHash", mock.Anything, txHash).Return(repository.IndexedTransaction{
+		Transaction: types.Transaction{Hash: txHash, From: addr1, To: addr2, BlockNumber: 10},
+		Addresses:   []string{addr1},
+	}, nil).Once()
+	repo.On("GetTransactionByHash", mock.Anything, txHash).Return(repository.IndexedTransaction{}, repository.ErrTransactionNotFound).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.GetTransactionHandler(w, httptest.NewRequest(http.MethodGet, "/transactions/"+txHash, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var lookup map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &lookup))
+	var tx map[string]interface{}
+	assert.NoError(t, json.Unmarshal(lookup["transaction"], &tx))
+	assert.Equal(t, txHash, tx["hash"])
+	assert.Equal(t, addr2Checksum, tx["to"])
+	assert.JSONEq(t, `[{"address":"`+addr1Checksum+`","direction":"outbound"}]`, string(lookup["matches"]))
+	assert.JSONEq(t, `"storage"`, string(lookup["source"]))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   errorCode
+	}{
+		{"not found", http.MethodGet, "/transactions/" + txHash, http.StatusNotFound, codeTxNotFound},
+		{"invalid hash", http.MethodGet, "/transactions/0x1234", http.StatusUnprocessableEntity, codeInvalidHash},
+		{"nested path", http.MethodGet, "/transactions/" + txHash + "/logs", http.StatusBadRequest, codeInvalidRequest},
+		{"wrong method", http.MethodPost, "/transactions/" + txHash, http.StatusMethodNotAllowed, codeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.GetTransactionHandler(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+}
+
+func TestGetTransactionHandler_node(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetTransactionByHash", mock.Anything, mock.Anything).Return(repository.IndexedTransaction{}, repository.ErrTransactionNotFound)
+	parserSvc := parser.NewParserService(repo, parser.WithTransactionFetcher(
+		func(ctx context.Context, hash string) (*types.Transaction, error) {
+			return nil, fmt.Errorf("connection refused")
+		}))
+	reg := NewRegister(singleChain(parserSvc), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.GetTransactionHandler(w, httptest.NewRequest(http.MethodGet, "/transactions/"+txHash, nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, codeNodeUnavailable, decodeError(t, w).Code)
+}
+
+func TestGetBlockTransactionsHandler_calls(t *testing.T) {
+	transfer := "0xa9059cbb" + strings.Repeat("0", 24) + strings.TrimPrefix(addr1, "0x") + strings.Repeat("0", 61) + "3e8"
+	repo := mocks.NewRepository(t)
+	expectLabels(repo, map[string]types.AddressLabel{})
+	repo.On("GetBlockTransactions", mock.Anything, uint64(16)).Return([]repository.IndexedTransaction{
+		{Transaction: types.Transaction{Hash: "0x01", From: addr1, To: addr2, Input: transfer}, Addresses: []string{addr1}},
+		{Transaction: types.Transaction{Hash: "0x02", From: addr1, To: addr2, Input: "0xDEADBEEF00"}, Addresses: []string{addr1}},
+		{Transaction: types.Transaction{Hash: "0x03", From: addr1, To: addr2, Input: "0x"}, Addresses: []string{addr1}},
+		{Transaction: types.Transaction{Hash: "0x04", From: addr1, Input: "0x6080",
+			Deployments: []types.Deployment{{Address: addr2, Deployer: addr1}}}, Addresses: []string{addr1}},
+	}, nil).Once()
+	abis, err := abi.Builtin()
+	assert.NoError(t, err)
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{}, WithABIRegistry(abis))
+
+	w := httptest.NewRecorder()
+	reg.GetBlockTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/blocks/16/transactions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Transactions []struct {
+			Transaction struct {
+				Event       types.TxEvent      `json:"event"`
+				Call        json.RawMessage    `json:"call"`
+				Deployments []types.Deployment `json:"deployments"`
+			} `json:"transaction"`
+		} `json:"transactions"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Transactions, 4) {
+		assert.Equal(t, types.EventCall, resp.Transactions[0].Transaction.Event)
+		assert.JSONEq(t, `{
+			"selector": "0xa9059cbb",
+			"function": "transfer",
+			"signature": "transfer(address,uint256)",
+			"arguments": [
+				{"name": "to", "type": "address", "value": "`+addr1Checksum+`"},
+				{"name": "value", "type": "uint256", "value": "1000"}
+			]
+		}`, string(resp.Transactions[0].Transaction.Call))
+		// an unknown selector is rendered alone, a plain transfer has no call
+		assert.JSONEq(t, `{"selector": "0xdeadbeef"}`, string(resp.Transactions[1].Transaction.Call))
+		assert.Nil(t, resp.Transactions[2].Transaction.Call)
+		assert.Equal(t, types.EventTransfer, resp.Transactions[2].Transaction.Event)
+		// a creation has no call, its contracts are rendered in checksum form
+		assert.Nil(t, resp.Transactions[3].Transaction.Call)
+		assert.Equal(t, types.EventDeployment, resp.Transactions[3].Transaction.Event)
+		assert.Equal(t, []types.Deployment{{Address: addr2Checksum, Deployer: addr1Checksum}},
+			resp.Transactions[3].Transaction.Deployments)
+	}
+}
+
+func TestGetBlockTransactionsHandler(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	expectLabels(repo, map[string]types.AddressLabel{})
+	repo.On("GetBlockTransactions", mock.Anything, uint64(16)).Return([]repository.IndexedTransaction{
+		{Transaction: types.Transaction{Hash: txHash, From: addr2, To: addr1, BlockNumber: 16}, Addresses: []string{addr1}},
+	}, nil).Twice()
+	repo.On("GetBlockTransactions", mock.Anything, uint64(17)).Return(nil, repository.ErrBlockNotFound).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	// the number is decimal or 0x-hex
+	for _, path := range []string{"/blocks/16/transactions", "/blocks/0x10/transactions"} {
+		w := httptest.NewRecorder()
+		reg.GetBlockTransactionsHandler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		var resp struct {
+			Block        uint64 `json:"block"`
+			Transactions []struct {
+				Matches []parser.AddressMatch `json:"matches"`
+			} `json:"transactions"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, uint64(16), resp.Block)
+		if assert.Len(t, resp.Transactions, 1) {
+			assert.Equal(t, []parser.AddressMatch{{Address: addr1Checksum, Direction: types.DirectionInbound}},
+				resp.Transactions[0].Matches)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   errorCode
+	}{
+		{"not parsed yet", "/blocks/17/transactions", http.StatusNotFound, codeBlockNotFound},
+		{"invalid number", "/blocks/latest/transactions", http.StatusBadRequest, codeInvalidRequest},
+		{"octal-looking number", "/blocks/0o20/transactions", http.StatusBadRequest, codeInvalidRequest},
+		{"missing suffix", "/blocks/16", http.StatusBadRequest, codeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.GetBlockTransactionsHandler(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+}
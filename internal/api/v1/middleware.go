@@ -0,0 +1,86 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/metrics"
+)
+
+// RequestIDHeader carries the correlation ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds the length of a request ID sent by a client
+const maxRequestIDLen = 128
+
+var httpDuration = metrics.Default.NewHistogramVec("txparser_http_request_duration_seconds",
+	"Latency of API requests by handler, method and status code.", metrics.DefaultBuckets, "handler", "method", "code")
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the features of the wrapped writer, e.g. the write deadline
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// codeAborted is the code label of the requests whose handler panicked, e.g. with http.ErrAbortHandler
+// to cut a response already started
+const codeAborted = "aborted"
+
+// Instrument records the latency of the requests served by the handler of a route and logs them. The
+// aborted requests are recorded too before the panic goes on to the server.
+func Instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			duration := time.Since(start)
+			attrs := []slog.Attr{
+				slog.String("handler", route),
+				slog.String(logging.KeyMethod, r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Duration(logging.KeyDuration, duration),
+			}
+
+			v := recover()
+			if v == nil {
+				httpDuration.With(route, r.Method, strconv.Itoa(rec.status)).Observe(duration.Seconds())
+				slog.LogAttrs(r.Context(), slog.LevelInfo, "http request", attrs...)
+				return
+			}
+			httpDuration.With(route, r.Method, codeAborted).Observe(duration.Seconds())
+			slog.LogAttrs(r.Context(), slog.LevelWarn, "http request aborted", attrs...)
+			panic(v)
+		}()
+
+		next(rec, r)
+	}
+}
+
+// RequestID carries the X-Request-ID header of the request, or a new ID when it is missing,
+// in the request context so every log record of the request is correlated. The ID is echoed
+// in the response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			id = logging.NewID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = logging.RequestID(r.Context())
+	}))
+
+	t.Run("from header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, RouteCurrentBlock, nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "abc-123", got)
+		assert.Equal(t, "abc-123", rec.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		for _, header := range []string{"", strings.Repeat("a", maxRequestIDLen+1)} {
+			req := httptest.NewRequest(http.MethodGet, RouteCurrentBlock, nil)
+			req.Header.Set(RequestIDHeader, header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, got)
+			assert.NotEqual(t, header, got)
+			assert.Equal(t, got, rec.Header().Get(RequestIDHeader))
+		}
+	})
+}
+
+func TestInstrument_aborted(t *testing.T) {
+	handler := Instrument("/test/aborted", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic(http.ErrAbortHandler)
+	})
+
+	// the panic goes on to the server, which closes the connection
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/aborted", nil))
+	})
+
+	var sb strings.Builder
+	_, err := metrics.Default.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Contains(t, sb.String(), `txparser_http_request_duration_seconds_count{handler="/test/aborted",method="GET",code="aborted"} 1`)
+	assert.NotContains(t, sb.String(), `handler="/test/aborted",method="GET",code="200"`)
+}
@@ -1,86 +1,194 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/TrustWallet/tx-parser/internal/abi"
+	"github.com/TrustWallet/tx-parser/internal/alerting"
 	"github.com/TrustWallet/tx-parser/internal/parser"
 )
 
+const (
+	RouteSubscribe         = "/subscribe"
+	RouteCurrentBlock      = "/current-block"
+	RouteTransactions      = "/transactions"
+	RouteTransaction       = "/transactions/"
+	RouteExport            = "/transactions/export"
+	RouteBlocks            = "/blocks/"
+	RouteAddresses         = "/addresses/"
+	RouteAlerts            = "/alerts"
+	RouteBulkSubscriptions = "/subscriptions/bulk"
+	RouteBulkJobs          = "/subscriptions/jobs"
+	RouteBulkJobIssues     = "/subscriptions/jobs/issues"
+)
+
+// RouteTimeouts is the deadline applied to the request context of each route,
+// routes without an entry use Default. Zero means no deadline.
+type RouteTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
 type register struct {
-	parserSvc parser.Parser
+	// parsers are keyed by chain name
+	parsers      map[string]parser.Parser
+	defaultChain string
+	timeouts     RouteTimeouts
+	// alerts is the feed of the alerts, nil when the alerts are disabled
+	alerts *alerting.Feed
+	// abis decode the contract calls, only their selector is rendered when it is nil
+	abis *abi.Registry
+}
+
+// RegisterOption configures the optional features of the API
+type RegisterOption func(*register)
+
+// WithAlertFeed serves the alerts of the feed on GET /alerts
+func WithAlertFeed(feed *alerting.Feed) RegisterOption {
+	return func(reg *register) {
+		reg.alerts = feed
+	}
 }
 
-func NewRegister(parserSvc parser.Parser) *register {
+// WithABIRegistry decodes the contract calls of the rendered transactions with the ABIs of the registry
+func WithABIRegistry(abis *abi.Registry) RegisterOption {
+	return func(reg *register) {
+		reg.abis = abis
+	}
+}
+
+// NewRegister creates the API handlers of the chains, a request selects a chain with
+// `chain` query parameter and defaults to defaultChain
+func NewRegister(parsers map[string]parser.Parser, defaultChain string, timeouts RouteTimeouts, opts ...RegisterOption) *register {
 	reg := &register{
-		parserSvc: parserSvc,
+		parsers:      parsers,
+		defaultChain: defaultChain,
+		timeouts:     timeouts,
+	}
+	for _, opt := range opts {
+		opt(reg)
 	}
 
 	return reg
 }
 
+// chainParser return the parser of the chain selected by the request, it writes
+// an error and returns false if the chain is unknown
+func (reg *register) chainParser(w http.ResponseWriter, r *http.Request) (string, parser.Parser, bool) {
+	chain := r.URL.Query().Get("chain")
+	if chain == "" {
+		chain = reg.defaultChain
+	}
+
+	parserSvc, ok := reg.parsers[chain]
+	if !ok {
+		writeError(w, http.StatusBadRequest, codeUnknownChain, fmt.Sprintf("Unknown chain %q", chain))
+		return "", nil, false
+	}
+
+	return chain, parserSvc, true
+}
+
+// requestContext return the request context bounded by the deadline of the route
+func (reg *register) requestContext(r *http.Request, route string) (context.Context, context.CancelFunc) {
+	timeout, ok := reg.timeouts.Routes[route]
+	if !ok {
+		timeout = reg.timeouts.Default
+	}
+	if timeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+
+	return context.WithTimeout(r.Context(), timeout)
+}
+
 func (reg *register) SubscribeHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is accepted", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, http.MethodPost) {
 		return
 	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteSubscribe)
+	defer cancel()
 
 	var data struct {
 		Address string `json:"address"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
-		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Error parsing request body")
 		return
 	}
 
-	if !reg.parserSvc.Subscribe(data.Address) {
-		http.Error(w, "Address already subscribed", http.StatusBadRequest)
+	if err := parserSvc.Subscribe(ctx, data.Address); err != nil {
+		writeParserError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Subscribed successfully"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Subscribed successfully"})
 }
 
 func (reg *register) GetCurrentBlockHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Only GET method is accepted", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
 		return
 	}
+	ctx, cancel := reg.requestContext(r, RouteCurrentBlock)
+	defer cancel()
 
-	blockNum := reg.parserSvc.GetCurrentBlock()
-	response, err := json.Marshal(map[string]interface{}{"block": blockNum})
+	blockNum, err := parserSvc.GetCurrentBlock(ctx)
 	if err != nil {
-		http.Error(w, "Error marshaling response", http.StatusInternalServerError)
+		writeParserError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"block": blockNum})
 }
 
 func (reg *register) GetTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Only GET method is accepted", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
 		return
 	}
+	ctx, cancel := reg.requestContext(r, RouteTransactions)
+	defer cancel()
 
 	address := r.URL.Query().Get("address")
 	if address == "" {
-		http.Error(w, "Address parameter is missing", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Address parameter is missing")
+		return
+	}
+
+	filter, err := parseTransactionFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	// the transactions of every parsed block are returned, the filters only narrow them down
+	txns, err := parserSvc.QueryTransactions(ctx, address, filter)
+	if err != nil {
+		writeParserError(w, err)
 		return
 	}
 
-	txns := reg.parserSvc.GetTransactions(address)
-	response, err := json.Marshal(txns)
+	rendered, err := reg.renderTransactions(ctx, parserSvc, txns)
 	if err != nil {
-		http.Error(w, "Error marshaling response", http.StatusInternalServerError)
+		writeParserError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	writeJSON(w, http.StatusOK, withDirections(rendered, address))
 }
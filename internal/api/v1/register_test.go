@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	addr1         = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	addr1Checksum = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	addr2         = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+	addr2Checksum = "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+)
+
+const testChain = "ethereum"
+
+func singleChain(parserSvc parser.Parser) map[string]parser.Parser {
+	return map[string]parser.Parser{testChain: parserSvc}
+}
+
+// expectLabels lets the mocked repository label the rendered transactions
+func expectLabels(repo *mocks.Repository, labels map[string]types.AddressLabel) {
+	repo.On("GetLabels", mock.Anything, mock.Anything).Return(labels, nil).Maybe()
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorBody {
+	var resp errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	return resp.Error
+}
+
+func TestSubscribeHandler(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddress", mock.Anything, addr1).Return(nil).Once()
+	repo.On("AddAddress", mock.Anything, addr1).Return(repository.ErrAddressExists).Once()
+	repo.On("AddAddress", mock.Anything, addr2).Return(fmt.Errorf("connection refused")).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		code   errorCode
+	}{
+		{"subscribed", http.MethodPost, `{"address":"` + addr1 + `"}`, http.StatusOK, ""},
+		{"duplicate", http.MethodPost, `{"address":"` + addr1 + `"}`, http.StatusConflict, codeAlreadySubscribed},
+		{"storage error", http.MethodPost, `{"address":"` + addr2 + `"}`, http.StatusServiceUnavailable, codeStorageUnavailable},
+		{"invalid address", http.MethodPost, `{"address":"hello"}`, http.StatusUnprocessableEntity, codeInvalidAddress},
+		{"invalid body", http.MethodPost, `{`, http.StatusBadRequest, codeInvalidRequest},
+		{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed, codeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.SubscribeHandler(w, httptest.NewRequest(tt.method, "/subscribe", strings.NewReader(tt.body)))
+			assert.Equal(t, tt.status, w.Code)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, decodeError(t, w).Code)
+			}
+		})
+	}
+}
+
+func TestGetCurrentBlockHandler(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(13), nil).Once()
+	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(0), fmt.Errorf("some error")).Once()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.GetCurrentBlockHandler(w, httptest.NewRequest(http.MethodGet, "/current-block", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"block":13}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	reg.GetCurrentBlockHandler(w, httptest.NewRequest(http.MethodGet, "/current-block", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, codeStorageUnavailable, decodeError(t, w).Code)
+}
+
+func TestGetCurrentBlockHandler_deadline(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetCurrentBlock", mock.Anything).Return(func(ctx context.Context) (uint64, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{
+		Default: time.Minute,
+		Routes:  map[string]time.Duration{RouteCurrentBlock: 10 * time.Millisecond},
+	})
+
+	w := httptest.NewRecorder()
+	reg.GetCurrentBlockHandler(w, httptest.NewRequest(http.MethodGet, "/current-block", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, codeTimeout, decodeError(t, w).Code)
+}
+
+func TestGetTransactionsHandler(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	expectLabels(repo, map[string]types.AddressLabel{addr2: {Label: "exchange deposit", Tags: []string{"exchange"}}})
+	repo.On("QueryTransactions", mock.Anything, addr1, repository.TransactionFilter{}).Return([]types.Transaction{
+		{From: addr1, To: addr2, Hash: "hash1"},
+	}, nil)
+	repo.On("QueryTransactions", mock.Anything, addr2, repository.TransactionFilter{}).Return(nil, repository.ErrAddressNotFound)
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions?address="+addr1, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var txns []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &txns))
+	assert.Len(t, txns, 1)
+	assert.Equal(t, addr1Checksum, txns[0]["from"])
+	assert.Equal(t, addr2Checksum, txns[0]["to"])
+	assert.Equal(t, "outbound", txns[0]["direction"])
+	assert.Nil(t, txns[0]["fromLabel"])
+	assert.Equal(t, map[string]interface{}{"label": "exchange deposit", "tags": []interface{}{"exchange"}}, txns[0]["toLabel"])
+
+	w = httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions?address="+addr2, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, codeAddressNotFound, decodeError(t, w).Code)
+
+	w = httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, codeInvalidRequest, decodeError(t, w).Code)
+}
+
+func TestGetTransactionsHandler_empty(t *testing.T) {
+	repo := repository.NewInMemRepo()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+	assert.NoError(t, reg.parsers[testChain].Subscribe(context.TODO(), addr1))
+
+	w := httptest.NewRecorder()
+	reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, "/transactions?address="+addr1Checksum, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[]`, w.Body.String())
+}
+
+func TestGetTransactionsHandler_history(t *testing.T) {
+	ctx := context.TODO()
+	repo := repository.NewInMemRepo()
+	reg := NewRegister(singleChain(parser.NewParserService(repo)), testChain, RouteTimeouts{})
+	assert.NoError(t, reg.parsers[testChain].Subscribe(ctx, addr1))
+	assert.NoError(t, repo.SaveTransactions(ctx, 1, []types.Transaction{{Hash: "0x01", From: addr2, To: addr1, BlockNumber: 1}}))
+	assert.NoError(t, repo.SaveTransactions(ctx, 2, []types.Transaction{{Hash: "0x02", From: addr1, To: addr2, BlockNumber: 2}}))
+
+	hashes := func(target string) []string {
+		w := httptest.NewRecorder()
+		reg.GetTransactionsHandler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, w.Code, target)
+		var txns []struct {
+			Hash string `json:"hash"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &txns))
+		var hashes []string
+		for _, tx := range txns {
+			hashes = append(hashes, tx.Hash)
+		}
+		return hashes
+	}
+
+	// with or without filter, the transactions of every parsed block are searched, oldest first
+	assert.Equal(t, []string{"0x01", "0x02"}, hashes("/transactions?address="+addr1))
+	assert.Equal(t, []string{"0x01"}, hashes("/transactions?address="+addr1+"&direction=in"))
+	assert.Equal(t, []string{"0x02"}, hashes("/transactions?address="+addr1+"&fromBlock=2"))
+}
+
+func TestChainParameter(t *testing.T) {
+	ethRepo := mocks.NewRepository(t)
+	ethRepo.On("GetCurrentBlock", mock.Anything).Return(uint64(100), nil)
+	bscRepo := mocks.NewRepository(t)
+	bscRepo.On("GetCurrentBlock", mock.Anything).Return(uint64(200), nil)
+	reg := NewRegister(map[string]parser.Parser{
+		"ethereum": parser.NewParserService(ethRepo),
+		"bsc":      parser.NewParserService(bscRepo),
+	}, "ethereum", RouteTimeouts{})
+
+	for target, want := range map[string]float64{
+		"/current-block":                100,
+		"/current-block?chain=ethereum": 100,
+		"/current-block?chain=bsc":      200,
+	} {
+		w := httptest.NewRecorder()
+		reg.GetCurrentBlockHandler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, w.Code, target)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, want, resp["block"], target)
+	}
+
+	w := httptest.NewRecorder()
+	reg.SubscribeHandler(w, httptest.NewRequest(http.MethodPost, "/subscribe?chain=solana", strings.NewReader(`{"address":"`+addr1+`"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errorBody{Code: codeUnknownChain, Message: `Unknown chain "solana"`}, decodeError(t, w))
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/TrustWallet/tx-parser/internal/abi"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// checksumTransactions render addresses of the transactions in EIP-55 checksum form
+func checksumTransactions(txns []types.Transaction) []types.Transaction {
+	rendered := make([]types.Transaction, len(txns))
+	for i, tx := range txns {
+		tx.From = utils.ToChecksumAddress(tx.From)
+		tx.To = utils.ToChecksumAddress(tx.To)
+		if tx.Deployments != nil {
+			deployments := make([]types.Deployment, len(tx.Deployments))
+			for j, deployment := range tx.Deployments {
+				deployment.Address = utils.ToChecksumAddress(deployment.Address)
+				deployment.Deployer = utils.ToChecksumAddress(deployment.Deployer)
+				deployments[j] = deployment
+			}
+			tx.Deployments = deployments
+		}
+		rendered[i] = tx
+	}
+
+	return rendered
+}
+
+// renderedTransaction is a transaction with its kind, the labels of its sender and recipient and its
+// decoded call, if any
+type renderedTransaction struct {
+	types.Transaction
+	FromLabel *types.AddressLabel `json:"fromLabel,omitempty"`
+	ToLabel   *types.AddressLabel `json:"toLabel,omitempty"`
+	Event     types.TxEvent       `json:"event"`
+	Call      *abi.Call           `json:"call,omitempty"`
+}
+
+// renderTransactions render addresses of the transactions in EIP-55 checksum form, label the senders
+// and the recipients and decode the contract calls
+func (reg *register) renderTransactions(ctx context.Context, parserSvc parser.Parser, txns []types.Transaction) ([]renderedTransaction, error) {
+	rendered := make([]renderedTransaction, len(txns))
+	if len(txns) == 0 {
+		return rendered, nil
+	}
+
+	addresses := make([]string, 0, 2*len(txns))
+	for _, tx := range txns {
+		addresses = append(addresses, tx.From, tx.To)
+	}
+	labels, err := parserSvc.GetLabels(ctx, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := func(address string) *types.AddressLabel {
+		if label, ok := labels[utils.NormalizeAddress(address)]; ok && address != "" {
+			return &label
+		}
+		return nil
+	}
+	for i, tx := range checksumTransactions(txns) {
+		rendered[i] = renderedTransaction{
+			Transaction: tx,
+			FromLabel:   lookup(tx.From),
+			ToLabel:     lookup(tx.To),
+			Event:       tx.Event(),
+			Call:        reg.decodeCall(tx),
+		}
+	}
+
+	return rendered, nil
+}
+
+// decodeCall decodes the call data of a transaction with the ABI registry, nil for a plain transfer
+// or a contract creation. The selector alone is returned when the call can't be decoded.
+func (reg *register) decodeCall(tx types.Transaction) *abi.Call {
+	selector := tx.MethodSelector()
+	if selector == "" || tx.To == "" {
+		return nil
+	}
+	if reg.abis == nil {
+		return &abi.Call{Selector: selector}
+	}
+
+	call, err := reg.abis.DecodeCall(tx.Input)
+	if err != nil {
+		call = abi.Call{Selector: selector}
+	}
+	return &call
+}
+
+// checksumResults render addresses of the bulk results in EIP-55 checksum form,
+// invalid addresses are kept as they were sent
+func checksumResults(results []parser.SubscriptionResult) []parser.SubscriptionResult {
+	rendered := make([]parser.SubscriptionResult, len(results))
+	for i, res := range results {
+		if res.Status != parser.StatusInvalid {
+			res.Address = utils.ToChecksumAddress(res.Address)
+		}
+		rendered[i] = res
+	}
+
+	return rendered
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		response = []byte(`{"error":{"code":"internal_error","message":"Error marshaling response"}}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/analytics"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// addressStats is the rendering of analytics.Stats, the amounts are decimals of the native unit
+type addressStats struct {
+	Address        string        `json:"address"`
+	Received       string        `json:"received"`
+	Sent           string        `json:"sent"`
+	FeesPaid       string        `json:"feesPaid"`
+	Transactions   int           `json:"transactions"`
+	Counterparties int           `json:"counterparties"`
+	FirstActivity  *activity     `json:"firstActivity"`
+	LastActivity   *activity     `json:"lastActivity"`
+	Daily          []volumeStats `json:"daily"`
+	Weekly         []volumeStats `json:"weekly"`
+}
+
+type activity struct {
+	Block     uint64    `json:"block"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type volumeStats struct {
+	Start        string `json:"start"`
+	Received     string `json:"received"`
+	Sent         string `json:"sent"`
+	Transactions int    `json:"transactions"`
+}
+
+// GetAddressStatsHandler return the activity aggregates of an address, GET /addresses/{address}/stats
+func (reg *register) GetAddressStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, parserSvc, ok := reg.chainParser(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := reg.requestContext(r, RouteAddresses)
+	defer cancel()
+
+	address, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, RouteAddresses), "/stats")
+	if !found || address == "" || strings.Contains(address, "/") {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Expected /addresses/{address}/stats")
+		return
+	}
+
+	stats, err := parserSvc.GetAddressStats(ctx, address)
+	if err != nil {
+		writeParserError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, renderStats(address, stats, parserSvc.Decimals()))
+}
+
+func renderStats(address string, stats analytics.Stats, decimals int) addressStats {
+	amount := func(v *big.Int) string {
+		return utils.FormatUnits(v, decimals)
+	}
+	volumes := func(buckets []analytics.Bucket) []volumeStats {
+		rendered := make([]volumeStats, len(buckets))
+		for i, b := range buckets {
+			rendered[i] = volumeStats{
+				Start:        b.Start.Format(time.DateOnly),
+				Received:     amount(b.Received),
+				Sent:         amount(b.Sent),
+				Transactions: b.TxCount,
+			}
+		}
+		return rendered
+	}
+
+	rendered := addressStats{
+		Address:        utils.ToChecksumAddress(address),
+		Received:       amount(stats.Received),
+		Sent:           amount(stats.Sent),
+		FeesPaid:       amount(stats.FeesPaid),
+		Transactions:   stats.TxCount,
+		Counterparties: stats.Counterparties,
+		Daily:          volumes(stats.Daily),
+		Weekly:         volumes(stats.Weekly),
+	}
+	if stats.FirstActivity != nil {
+		rendered.FirstActivity = &activity{Block: stats.FirstActivity.Block, Timestamp: stats.FirstActivity.Time}
+		rendered.LastActivity = &activity{Block: stats.LastActivity.Block, Timestamp: stats.LastActivity.Time}
+	}
+
+	return rendered
+}
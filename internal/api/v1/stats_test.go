@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/analytics"
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/parser"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAddressStatsHandler(t *testing.T) {
+	ctx := context.TODO()
+	tracker := analytics.NewTracker(repository.NewInMemRepo())
+	_, err := tracker.AddAddresses(ctx, []string{addr1})
+	assert.NoError(t, err)
+	assert.NoError(t, tracker.SaveTransactions(ctx, 16, []types.Transaction{
+		{Hash: "0x01", From: addr1, To: addr2, Value: "0x14d1120d7b160000", Fee: "0x2386f26fc10000", BlockNumber: 16, Timestamp: 1705680731},
+	}))
+	reg := NewRegister(singleChain(parser.NewParserService(tracker)), testChain, RouteTimeouts{})
+
+	w := httptest.NewRecorder()
+	reg.GetAddressStatsHandler(w, httptest.NewRequest(http.MethodGet, "/addresses/"+addr1+"/stats", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"address": "`+addr1Checksum+`",
+		"received": "0", "sent": "1.5", "feesPaid": "0.01",
+		"transactions": 1, "counterparties": 1,
+		"firstActivity": {"block": 16, "timestamp": "2024-01-19T16:12:11Z"},
+		"lastActivity": {"block": 16, "timestamp": "2024-01-19T16:12:11Z"},
+		"daily": [{"start": "2024-01-19", "received": "0", "sent": "1.5", "transactions": 1}],
+		"weekly": [{"start": "2024-01-15", "received": "0", "sent": "1.5", "transactions": 1}]
+	}`, w.Body.String())
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   errorCode
+	}{
+		{"not subscribed", "/addresses/" + addr2 + "/stats", http.StatusNotFound, codeAddressNotFound},
+		{"invalid address", "/addresses/test/stats", http.StatusUnprocessableEntity, codeInvalidAddress},
+		{"missing suffix", "/addresses/" + addr1, http.StatusBadRequest, codeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			reg.GetAddressStatsHandler(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+
+	// the storage does not track the stats
+	reg = NewRegister(singleChain(parser.NewParserService(mocks.NewRepository(t))), testChain, RouteTimeouts{})
+	w = httptest.NewRecorder()
+	reg.GetAddressStatsHandler(w, httptest.NewRequest(http.MethodGet, "/addresses/"+addr1+"/stats", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Equal(t, codeStatsUnavailable, decodeError(t, w).Code)
+}
@@ -0,0 +1,88 @@
+// Package cache stores immutable values by key in memory and on disk, within byte-size bounds.
+package cache
+
+import (
+	"container/list"
+
+	"github.com/TrustWallet/tx-parser/internal/metrics"
+)
+
+// Tiers of the metrics
+const (
+	tierMemory = "memory"
+	tierDisk   = "disk"
+)
+
+var (
+	cacheBytes = metrics.Default.NewGaugeVec("txparser_cache_bytes",
+		"Size of the cached values by tier.", "tier")
+	cacheEvictions = metrics.Default.NewCounterVec("txparser_cache_evictions_total",
+		"Number of values evicted to stay within the size bound by tier.", "tier")
+)
+
+// Cache stores values by key. The values must not be modified once stored or returned.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// lruList orders the entries from the most to the least recently used and bounds their total size
+type lruList[V any] struct {
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+	size  int64
+}
+
+func newLRUList[V any](maxBytes int64) *lruList[V] {
+	return &lruList[V]{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get return the value of the key and marks it as the most recently used
+func (l *lruList[V]) get(key string) (V, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.ll.MoveToFront(elem)
+	return elem.Value.(*lruEntry[V]).value, true
+}
+
+// add inserts or replaces the value of the key and return the entries evicted to stay
+// within the size bound, the entry itself when it is larger than the bound
+func (l *lruList[V]) add(key string, value V, size int64) []lruEntry[V] {
+	l.remove(key)
+	if size > l.maxBytes {
+		return []lruEntry[V]{{key: key, value: value, size: size}}
+	}
+
+	l.items[key] = l.ll.PushFront(&lruEntry[V]{key: key, value: value, size: size})
+	l.size += size
+
+	var evicted []lruEntry[V]
+	for l.size > l.maxBytes {
+		oldest := l.ll.Back()
+		entry := oldest.Value.(*lruEntry[V])
+		l.ll.Remove(oldest)
+		delete(l.items, entry.key)
+		l.size -= entry.size
+		evicted = append(evicted, *entry)
+	}
+	return evicted
+}
+
+func (l *lruList[V]) remove(key string) {
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		l.ll.Remove(elem)
+		delete(l.items, key)
+		l.size -= entry.size
+	}
+}
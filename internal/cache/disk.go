@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+// tempPrefix marks the files being written, they are removed when the cache is opened
+const tempPrefix = ".tmp-"
+
+// Disk is an LRU cache storing one file per value in a directory, bounded by the size
+// of the files. It survives restarts: the files are reloaded from the least to the most
+// recently used when the cache is opened.
+type Disk struct {
+	dir string
+
+	mu sync.Mutex
+	// lru holds the names of the files
+	lru *lruList[struct{}]
+}
+
+// NewDisk opens the cache stored in dir, the directory is created if needed
+func NewDisk(dir string, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read cache directory: %w", err)
+	}
+
+	type file struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), tempPrefix) {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{name: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	d := &Disk{dir: dir, lru: newLRUList[struct{}](maxBytes)}
+	for _, f := range files {
+		d.evict(d.lru.add(f.name, struct{}{}, f.size))
+	}
+	cacheBytes.With(tierDisk).Set(float64(d.lru.size))
+	return d, nil
+}
+
+func (d *Disk) Get(key string) ([]byte, bool) {
+	name := fileName(key)
+	d.mu.Lock()
+	_, ok := d.lru.get(name)
+	d.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := filepath.Join(d.dir, name)
+	value, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("error reading cache file", slog.String("path", path), logging.Err(err))
+		d.mu.Lock()
+		d.lru.remove(name)
+		d.mu.Unlock()
+		return nil, false
+	}
+	// the modification time orders the files when the cache is reopened
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return value, true
+}
+
+func (d *Disk) Set(key string, value []byte) {
+	name := fileName(key)
+	if err := d.write(name, value); err != nil {
+		slog.Warn("error writing cache file", slog.String("path", filepath.Join(d.dir, name)), logging.Err(err))
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.evict(d.lru.add(name, struct{}{}, int64(len(value))))
+	cacheBytes.With(tierDisk).Set(float64(d.lru.size))
+}
+
+// write replaces the file atomically, so that a reader never sees a partial value
+func (d *Disk) write(name string, value []byte) error {
+	tmp, err := os.CreateTemp(d.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(d.dir, name))
+}
+
+// evict removes the files of the evicted entries, the caller holds the lock
+func (d *Disk) evict(evicted []lruEntry[struct{}]) {
+	for _, entry := range evicted {
+		if err := os.Remove(filepath.Join(d.dir, entry.key)); err != nil && !os.IsNotExist(err) {
+			slog.Warn("error removing cache file", slog.String("path", filepath.Join(d.dir, entry.key)), logging.Err(err))
+		}
+	}
+	cacheEvictions.With(tierDisk).Add(float64(len(evicted)))
+}
+
+// fileName maps a key to a file name, the keys may contain any character
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
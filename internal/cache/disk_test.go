@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 16)
+	assert.NoError(t, err)
+
+	d.Set("block/1", []byte("12345678"))
+	d.Set("block/2", []byte("abcdefgh"))
+	value, ok := d.Get("block/1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("12345678"), value)
+
+	// block/2 is the least recently used
+	d.Set("block/3", []byte("ABCDEFGH"))
+	_, ok = d.Get("block/2")
+	assert.False(t, ok)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestDisk_reopen(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 1024)
+	assert.NoError(t, err)
+	d.Set("block/1", []byte("12345678"))
+	d.Set("block/2", []byte("abcdefgh"))
+	// a write interrupted by a crash
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, tempPrefix+"123"), []byte("abc"), 0o600))
+
+	d, err = NewDisk(dir, 1024)
+	assert.NoError(t, err)
+	value, ok := d.Get("block/2")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("abcdefgh"), value)
+	assert.Equal(t, int64(16), d.lru.size)
+	_, err = os.Stat(filepath.Join(dir, tempPrefix+"123"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDisk_removedFile(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 1024)
+	assert.NoError(t, err)
+	d.Set("block/1", []byte("12345678"))
+	assert.NoError(t, os.Remove(filepath.Join(dir, fileName("block/1"))))
+
+	_, ok := d.Get("block/1")
+	assert.False(t, ok)
+	assert.Zero(t, d.lru.size)
+}
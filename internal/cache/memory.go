@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"sync"
+)
+
+// Memory is an in-memory LRU cache bounded by the size of its keys and values
+type Memory struct {
+	mu  sync.Mutex
+	lru *lruList[[]byte]
+}
+
+func NewMemory(maxBytes int64) *Memory {
+	return &Memory{lru: newLRUList[[]byte](maxBytes)}
+}
+
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lru.get(key)
+}
+
+func (m *Memory) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	evicted := m.lru.add(key, value, int64(len(key)+len(value)))
+	cacheEvictions.With(tierMemory).Add(float64(len(evicted)))
+	cacheBytes.With(tierMemory).Set(float64(m.lru.size))
+}
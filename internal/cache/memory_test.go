@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	// every entry below weighs 2+8 bytes
+	m := NewMemory(30)
+	m.Set("k1", []byte("value001"))
+	m.Set("k2", []byte("value002"))
+	m.Set("k3", []byte("value003"))
+
+	// k1 becomes the most recently used, k2 is evicted instead
+	value, ok := m.Get("k1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value001"), value)
+	m.Set("k4", []byte("value004"))
+
+	_, ok = m.Get("k2")
+	assert.False(t, ok)
+	for _, key := range []string{"k1", "k3", "k4"} {
+		_, ok = m.Get(key)
+		assert.True(t, ok, key)
+	}
+	assert.Equal(t, int64(30), m.lru.size)
+}
+
+func TestMemory_replace(t *testing.T) {
+	m := NewMemory(30)
+	m.Set("k1", []byte("value001"))
+	m.Set("k1", []byte("v"))
+
+	value, ok := m.Get("k1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v"), value)
+	assert.Equal(t, int64(3), m.lru.size)
+}
+
+func TestMemory_tooLarge(t *testing.T) {
+	m := NewMemory(10)
+	m.Set("k1", []byte("a value larger than the cache"))
+
+	_, ok := m.Get("k1")
+	assert.False(t, ok)
+	assert.Zero(t, m.lru.size)
+}
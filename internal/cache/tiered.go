@@ -0,0 +1,31 @@
+package cache
+
+// Tiered looks up the values from the fastest to the slowest tier, a value found in
+// a slower tier is copied to the faster ones
+type Tiered struct {
+	tiers []Cache
+}
+
+func NewTiered(tiers ...Cache) *Tiered {
+	return &Tiered{tiers: tiers}
+}
+
+func (t *Tiered) Get(key string) ([]byte, bool) {
+	for i, tier := range t.tiers {
+		value, ok := tier.Get(key)
+		if !ok {
+			continue
+		}
+		for _, faster := range t.tiers[:i] {
+			faster.Set(key, value)
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+func (t *Tiered) Set(key string, value []byte) {
+	for _, tier := range t.tiers {
+		tier.Set(key, value)
+	}
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiered(t *testing.T) {
+	memory := NewMemory(1024)
+	slow := NewMemory(1024)
+	tiered := NewTiered(memory, slow)
+
+	tiered.Set("k1", []byte("v1"))
+	_, ok := memory.Get("k1")
+	assert.True(t, ok)
+	_, ok = slow.Get("k1")
+	assert.True(t, ok)
+
+	// a value found in the slow tier is copied to the fast one
+	slow.Set("k2", []byte("v2"))
+	value, ok := tiered.Get("k2")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v2"), value)
+	_, ok = memory.Get("k2")
+	assert.True(t, ok)
+
+	_, ok = tiered.Get("k3")
+	assert.False(t, ok)
+}
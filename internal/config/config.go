@@ -0,0 +1,629 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+// EnvPrefix is the prefix of every environment variable read by the config
+const EnvPrefix = "TXP_"
+
+// StorageMemory is the in-memory storage backend
+const StorageMemory = "memory"
+
+// DefaultChain is the name of the chain configured by the rpc and crawler sections
+const DefaultChain = "ethereum"
+
+// Chain types, i.e. the node API and the transaction model of a chain
+const (
+	ChainTypeEVM     = "evm"
+	ChainTypeBitcoin = "bitcoin"
+)
+
+var chainNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Config is the effective configuration of the service. It is built by merging,
+// from lowest to highest precedence: defaults, JSON config file, environment variables
+// and command-line flags.
+type Config struct {
+	Server  ServerConfig  `json:"server"`
+	RPC     RPCConfig     `json:"rpc"`
+	Crawler CrawlerConfig `json:"crawler"`
+	Storage StorageConfig `json:"storage"`
+	Health  HealthConfig  `json:"health"`
+	Log     LogConfig     `json:"log"`
+	Cache   CacheConfig   `json:"cache"`
+	Alerts  AlertsConfig  `json:"alerts"`
+	Labels  LabelsConfig  `json:"labels"`
+	ABI     ABIConfig     `json:"abi"`
+	// Chains are the crawled EVM chains, the rpc and crawler sections configure
+	// a single DefaultChain when it is empty
+	Chains []ChainConfig `json:"chains,omitempty"`
+	// RateLimits are the request budgets of the RPC endpoints by host, e.g. mainnet.infura.io,
+	// the endpoints without budget are not throttled
+	RateLimits map[string]RateLimitConfig `json:"rateLimits,omitempty"`
+	// QuotaFile keeps the quota usage of the endpoints across restarts, empty keeps it in memory
+	QuotaFile string `json:"quotaFile,omitempty"`
+}
+
+type ServerConfig struct {
+	Addr               string   `json:"addr"`
+	ReadTimeout        Duration `json:"readTimeout"`
+	WriteTimeout       Duration `json:"writeTimeout"`
+	IdleTimeout        Duration `json:"idleTimeout"`
+	RequestTimeout     Duration `json:"requestTimeout"`
+	BulkRequestTimeout Duration `json:"bulkRequestTimeout"`
+	// ExportRequestTimeout bounds the statements of /transactions/export, which outlive WriteTimeout
+	ExportRequestTimeout Duration `json:"exportRequestTimeout"`
+	ShutdownTimeout      Duration `json:"shutdownTimeout"`
+}
+
+type RPCConfig struct {
+	// Endpoints are tried in order, the next one is used when a request fails
+	Endpoints []string `json:"endpoints"`
+	// ChainID is the EIP-155 chain ID of the endpoints, verified at startup
+	ChainID uint64 `json:"chainId"`
+}
+
+type CrawlerConfig struct {
+	// PollInterval is the interval between two crawl ticks until the block time is learned
+	PollInterval Duration `json:"pollInterval"`
+	// MinPollInterval and MaxPollInterval bound the interval adapted to the observed block time
+	MinPollInterval Duration `json:"minPollInterval"`
+	MaxPollInterval Duration `json:"maxPollInterval"`
+	TickTimeout     Duration `json:"tickTimeout"`
+	StartBlock      uint64   `json:"startBlock"`
+	Confirmations   uint64   `json:"confirmations"`
+	// Traces and AutoSubscribeDeployments configure the default chain, see ChainConfig
+	Traces                   bool `json:"traces,omitempty"`
+	AutoSubscribeDeployments bool `json:"autoSubscribeDeployments,omitempty"`
+}
+
+type ChainConfig struct {
+	// Name identifies the chain in the API, the logs and the metrics, e.g. ethereum, bsc
+	Name string `json:"name"`
+	// Type is evm (default) or bitcoin for the bitcoind-style UTXO chains
+	Type string `json:"type,omitempty"`
+	// ChainID is the EIP-155 chain ID of the endpoints, verified at startup, EVM chains only
+	ChainID uint64 `json:"chainId,omitempty"`
+	// Endpoints are tried in order, the next one is used when a request fails
+	Endpoints []string `json:"endpoints"`
+	// BlockTime is the expected average block time of the chain until it is learned from
+	// the parsed blocks. Zero polls at crawler.pollInterval until then.
+	BlockTime     Duration `json:"blockTime"`
+	StartBlock    uint64   `json:"startBlock"`
+	Confirmations uint64   `json:"confirmations"`
+	// Finality is the number of blocks on top of a block from which its RPC results are
+	// cached, zero uses cache.finality
+	Finality uint64 `json:"finality,omitempty"`
+	// Traces traces the blocks with debug_traceBlockByNumber to find the contracts created by
+	// internal calls, EVM chains only
+	Traces bool `json:"traces,omitempty"`
+	// AutoSubscribeDeployments subscribes the contracts deployed by the subscribed addresses, EVM chains only
+	AutoSubscribeDeployments bool `json:"autoSubscribeDeployments,omitempty"`
+}
+
+// PollInterval return the interval between two crawl ticks of the chain until its block time is known
+func (c ChainConfig) PollInterval(fallback Duration) time.Duration {
+	if c.BlockTime <= 0 {
+		return fallback.Std()
+	}
+	return c.BlockTime.Std() / 3
+}
+
+// RateLimitConfig is the request budget of an RPC endpoint, in units weighted by method
+type RateLimitConfig struct {
+	// Rate is the number of units per second and Burst the number of units spent at once
+	Rate  float64 `json:"rate"`
+	Burst float64 `json:"burst"`
+	// Quota is the number of units per QuotaPeriod, e.g. the monthly compute units of
+	// a provider plan, zero is unlimited
+	Quota       float64  `json:"quota,omitempty"`
+	QuotaPeriod Duration `json:"quotaPeriod,omitempty"`
+	// QuotaStart anchors the quota windows on the billing cycle, the Unix epoch by default, and
+	// QuotaMonthly makes them calendar months starting on its day instead of QuotaPeriod long
+	QuotaStart   time.Time `json:"quotaStart,omitempty"`
+	QuotaMonthly bool      `json:"quotaMonthly,omitempty"`
+	// Weights are the units of the methods, e.g. {"eth_getBlockByNumber": 16}
+	Weights map[string]float64 `json:"weights,omitempty"`
+}
+
+// CacheConfig bounds the cache of the immutable RPC results, e.g. the final blocks
+type CacheConfig struct {
+	// MemoryBytes bounds the in-memory tier, zero disables the cache
+	MemoryBytes uint64 `json:"memoryBytes"`
+	// Dir is the directory of the on-disk tier bounded by DiskBytes, empty disables it
+	Dir       string `json:"dir,omitempty"`
+	DiskBytes uint64 `json:"diskBytes"`
+	// Finality is the number of blocks on top of a block from which it is cached
+	Finality uint64 `json:"finality"`
+}
+
+// AlertsConfig enables the alerts on the transactions of the subscribed addresses
+type AlertsConfig struct {
+	// RulesFile is the path of the JSON rules, empty disables the alerts
+	RulesFile string `json:"rulesFile,omitempty"`
+	// Webhook is the URL the alerts are posted to, empty disables the webhook
+	Webhook string `json:"webhook,omitempty"`
+	// FeedSize is the number of latest alerts served by GET /alerts
+	FeedSize uint64 `json:"feedSize"`
+}
+
+// LabelsConfig is the label book of the well-known addresses
+type LabelsConfig struct {
+	// BookFile is the path of the JSON label book, empty labels the subscribed addresses only
+	BookFile string `json:"bookFile,omitempty"`
+}
+
+// ABIConfig is the ABIs decoding the contract calls, on top of the built-in ones
+type ABIConfig struct {
+	// Dir is the directory of the JSON ABI files, empty decodes with the built-in ABIs only
+	Dir string `json:"dir,omitempty"`
+}
+
+type StorageConfig struct {
+	Backend string `json:"backend"`
+	// RetainBlocks is the number of the latest blocks whose transactions are kept, the older ones are pruned
+	RetainBlocks uint64 `json:"retainBlocks"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn, error
+	Level string `json:"level"`
+	// Format is one of text, json
+	Format string `json:"format"`
+}
+
+type HealthConfig struct {
+	// MaxCrawlAge is the age of the last successful crawl from which the instance is not ready, it must
+	// exceed crawler.maxPollInterval + crawler.tickTimeout, the longest time between two successful ticks
+	MaxCrawlAge Duration `json:"maxCrawlAge"`
+	// MaxBlockLag is the number of blocks, on top of the confirmations, the parsed block
+	// may lag the node head before the instance is not ready
+	MaxBlockLag uint64 `json:"maxBlockLag"`
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:                 ":8080",
+			ReadTimeout:          Duration(10 * time.Second),
+			WriteTimeout:         Duration(75 * time.Second),
+			IdleTimeout:          Duration(120 * time.Second),
+			RequestTimeout:       Duration(5 * time.Second),
+			BulkRequestTimeout:   Duration(60 * time.Second),
+			ExportRequestTimeout: Duration(30 * time.Minute),
+			ShutdownTimeout:      Duration(15 * time.Second),
+		},
+		RPC: RPCConfig{
+			Endpoints: []string{"https://cloudflare-eth.com"},
+			ChainID:   1,
+		},
+		Crawler: CrawlerConfig{
+			PollInterval:    Duration(4 * time.Second),
+			MinPollInterval: Duration(time.Second),
+			MaxPollInterval: Duration(time.Minute),
+			TickTimeout:     Duration(10 * time.Second),
+		},
+		Storage: StorageConfig{
+			Backend:      StorageMemory,
+			RetainBlocks: 100_000,
+		},
+		Health: HealthConfig{
+			MaxCrawlAge: Duration(3 * time.Minute),
+			MaxBlockLag: 10,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Cache: CacheConfig{
+			MemoryBytes: 64 << 20,
+			DiskBytes:   1 << 30,
+			Finality:    64,
+		},
+		Alerts: AlertsConfig{
+			FeedSize: 1000,
+		},
+	}
+}
+
+// setting is a config field which can be set from an environment variable and a flag
+type setting struct {
+	name  string
+	usage string
+	apply func(cfg *Config, value string) error
+}
+
+var settings = []setting{
+	{"server-addr", "HTTP listen address", func(c *Config, v string) error {
+		c.Server.Addr = v
+		return nil
+	}},
+	{"server-read-timeout", "HTTP server read timeout", durationSetter(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{"server-write-timeout", "HTTP server write timeout", durationSetter(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{"server-idle-timeout", "HTTP server idle timeout", durationSetter(func(c *Config) *Duration { return &c.Server.IdleTimeout })},
+	{"server-request-timeout", "deadline of API requests", durationSetter(func(c *Config) *Duration { return &c.Server.RequestTimeout })},
+	{"server-bulk-request-timeout", "deadline of bulk subscription requests", durationSetter(func(c *Config) *Duration { return &c.Server.BulkRequestTimeout })},
+	{"server-export-request-timeout", "deadline of transaction exports", durationSetter(func(c *Config) *Duration { return &c.Server.ExportRequestTimeout })},
+	{"shutdown-timeout", "time allowed to drain requests, finish the crawl tick and flush storage on shutdown", durationSetter(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"rpc-endpoints", "comma separated list of JSON-RPC endpoints", func(c *Config, v string) error {
+		c.RPC.Endpoints = splitList(v)
+		return nil
+	}},
+	{"chain-id", "EIP-155 chain ID of the JSON-RPC endpoints, verified at startup", uintSetter(func(c *Config) *uint64 { return &c.RPC.ChainID })},
+	{"poll-interval", "interval between two crawl ticks", durationSetter(func(c *Config) *Duration { return &c.Crawler.PollInterval })},
+	{"min-poll-interval", "lower bound of the adaptive poll interval", durationSetter(func(c *Config) *Duration { return &c.Crawler.MinPollInterval })},
+	{"max-poll-interval", "upper bound of the adaptive poll interval", durationSetter(func(c *Config) *Duration { return &c.Crawler.MaxPollInterval })},
+	{"tick-timeout", "deadline of one crawl tick", durationSetter(func(c *Config) *Duration { return &c.Crawler.TickTimeout })},
+	{"start-block", "first block to parse when nothing is parsed yet, 0 starts at the chain head", uintSetter(func(c *Config) *uint64 { return &c.Crawler.StartBlock })},
+	{"confirmations", "number of blocks to wait before parsing a block", uintSetter(func(c *Config) *uint64 { return &c.Crawler.Confirmations })},
+	{"traces", "trace the blocks to find the contracts created by internal calls, the node must serve debug_traceBlockByNumber", boolSetter(func(c *Config) *bool { return &c.Crawler.Traces })},
+	{"auto-subscribe-deployments", "subscribe the contracts deployed by the subscribed addresses", boolSetter(func(c *Config) *bool { return &c.Crawler.AutoSubscribeDeployments })},
+	{"storage-backend", "storage backend, one of: " + StorageMemory, func(c *Config, v string) error {
+		c.Storage.Backend = v
+		return nil
+	}},
+	{"storage-retain-blocks", "number of the latest blocks whose transactions are kept", uintSetter(func(c *Config) *uint64 { return &c.Storage.RetainBlocks })},
+	{"log-level", "log level, one of: debug, info, warn, error", func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+	{"log-format", "log format, one of: text, json", func(c *Config, v string) error {
+		c.Log.Format = v
+		return nil
+	}},
+	{"cache-memory-bytes", "size of the in-memory cache of the final blocks, 0 disables the cache", uintSetter(func(c *Config) *uint64 { return &c.Cache.MemoryBytes })},
+	{"cache-dir", "directory of the on-disk cache of the final blocks, empty disables it", func(c *Config, v string) error {
+		c.Cache.Dir = v
+		return nil
+	}},
+	{"cache-disk-bytes", "size of the on-disk cache", uintSetter(func(c *Config) *uint64 { return &c.Cache.DiskBytes })},
+	{"cache-finality", "number of blocks on top of a block from which it is cached", uintSetter(func(c *Config) *uint64 { return &c.Cache.Finality })},
+	{"alerts-rules-file", "path of the JSON alert rules, empty disables the alerts", func(c *Config, v string) error {
+		c.Alerts.RulesFile = v
+		return nil
+	}},
+	{"alerts-webhook", "URL the alerts are posted to, empty disables the webhook", func(c *Config, v string) error {
+		c.Alerts.Webhook = v
+		return nil
+	}},
+	{"alerts-feed-size", "number of latest alerts served by the API", uintSetter(func(c *Config) *uint64 { return &c.Alerts.FeedSize })},
+	{"labels-book-file", "path of the JSON label book of the well-known addresses", func(c *Config, v string) error {
+		c.Labels.BookFile = v
+		return nil
+	}},
+	{"quota-file", "path of the file keeping the quota usage of the RPC endpoints across restarts", func(c *Config, v string) error {
+		c.QuotaFile = v
+		return nil
+	}},
+	{"abi-dir", "directory of the JSON ABI files decoding the contract calls", func(c *Config, v string) error {
+		c.ABI.Dir = v
+		return nil
+	}},
+	{"health-max-crawl-age", "age of the last successful crawl from which the instance is not ready", durationSetter(func(c *Config) *Duration { return &c.Health.MaxCrawlAge })},
+	{"health-max-block-lag", "blocks the parsed block may lag the node head, on top of the confirmations, before the instance is not ready", uintSetter(func(c *Config) *uint64 { return &c.Health.MaxBlockLag })},
+}
+
+// envName returns the environment variable of a setting, e.g. poll-interval -> TXP_POLL_INTERVAL
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load builds the configuration from the command-line arguments and the environment.
+// The config file is given by -config flag or TXP_CONFIG environment variable.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("tx-parser", flag.ContinueOnError)
+	configFile := fs.String("config", getenv(EnvPrefix+"CONFIG"), "path of the JSON config file")
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		s := s
+		usage := fmt.Sprintf("%s (env %s)", s.usage, envName(s.name))
+		fs.Func(s.name, usage, func(v string) error {
+			flagValues = append(flagValues, flagValue{setting: s, value: v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		value := getenv(envName(s.name))
+		if value == "" {
+			continue
+		}
+		if err := s.apply(&cfg, value); err != nil {
+			return Config{}, fmt.Errorf("env %s: %w", envName(s.name), err)
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := fv.setting.apply(&cfg, fv.value); err != nil {
+			return Config{}, fmt.Errorf("flag -%s: %w", fv.setting.name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate checks the configuration is usable
+func (c Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	for name, d := range map[string]Duration{
+		"server.readTimeout":          c.Server.ReadTimeout,
+		"server.writeTimeout":         c.Server.WriteTimeout,
+		"server.idleTimeout":          c.Server.IdleTimeout,
+		"server.requestTimeout":       c.Server.RequestTimeout,
+		"server.bulkRequestTimeout":   c.Server.BulkRequestTimeout,
+		"server.exportRequestTimeout": c.Server.ExportRequestTimeout,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdownTimeout must be positive"))
+	}
+
+	errs = append(errs, validateEndpoints("rpc.endpoints", c.RPC.Endpoints)...)
+	if c.RPC.ChainID == 0 {
+		errs = append(errs, errors.New("rpc.chainId is required"))
+	}
+
+	names := make(map[string]bool, len(c.Chains))
+	for i, chain := range c.Chains {
+		field := fmt.Sprintf("chains[%d]", i)
+		switch {
+		case !chainNamePattern.MatchString(chain.Name):
+			errs = append(errs, fmt.Errorf("%s.name: invalid name %q, lowercase letters, digits and dashes are allowed", field, chain.Name))
+		case names[chain.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: duplicate chain %q", field, chain.Name))
+		}
+		names[chain.Name] = true
+		switch chain.Type {
+		case "", ChainTypeEVM:
+			if chain.ChainID == 0 {
+				errs = append(errs, fmt.Errorf("%s.chainId is required", field))
+			}
+		case ChainTypeBitcoin:
+			if chain.Traces {
+				errs = append(errs, fmt.Errorf("%s.traces is not supported by %s chains", field, ChainTypeBitcoin))
+			}
+			if chain.AutoSubscribeDeployments {
+				errs = append(errs, fmt.Errorf("%s.autoSubscribeDeployments is not supported by %s chains", field, ChainTypeBitcoin))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s.type: unsupported type %q, one of: %s, %s", field, chain.Type, ChainTypeEVM, ChainTypeBitcoin))
+		}
+		if chain.BlockTime < 0 {
+			errs = append(errs, fmt.Errorf("%s.blockTime must not be negative", field))
+		}
+		errs = append(errs, validateEndpoints(field+".endpoints", chain.Endpoints)...)
+	}
+
+	for host, limit := range c.RateLimits {
+		errs = append(errs, validateRateLimit("rateLimits."+host, limit)...)
+	}
+
+	if c.Crawler.PollInterval <= 0 {
+		errs = append(errs, errors.New("crawler.pollInterval must be positive"))
+	}
+	if c.Crawler.MinPollInterval <= 0 {
+		errs = append(errs, errors.New("crawler.minPollInterval must be positive"))
+	}
+	if c.Crawler.MaxPollInterval < c.Crawler.MinPollInterval {
+		errs = append(errs, errors.New("crawler.maxPollInterval must not be less than crawler.minPollInterval"))
+	}
+	if c.Crawler.TickTimeout <= 0 {
+		errs = append(errs, errors.New("crawler.tickTimeout must be positive"))
+	}
+
+	if c.Cache.Dir != "" && c.Cache.DiskBytes == 0 {
+		errs = append(errs, errors.New("cache.diskBytes must be positive when cache.dir is set"))
+	}
+	if c.Cache.Finality == 0 {
+		errs = append(errs, errors.New("cache.finality must be positive"))
+	}
+
+	if c.Alerts.Webhook != "" {
+		errs = append(errs, validateEndpoints("alerts.webhook", []string{c.Alerts.Webhook})...)
+	}
+
+	if c.Health.MaxCrawlAge <= 0 {
+		errs = append(errs, errors.New("health.maxCrawlAge must be positive"))
+	} else if c.Health.MaxCrawlAge <= c.Crawler.MaxPollInterval+c.Crawler.TickTimeout {
+		// a chain backed off to the max interval succeeds once per interval and tick
+		errs = append(errs, errors.New("health.maxCrawlAge must be greater than crawler.maxPollInterval + crawler.tickTimeout"))
+	}
+
+	if _, err := logging.New(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		errs = append(errs, fmt.Errorf("log: %w", err))
+	}
+
+	if c.Storage.Backend != StorageMemory {
+		errs = append(errs, fmt.Errorf("storage.backend: unsupported backend %q", c.Storage.Backend))
+	}
+	if c.Storage.RetainBlocks == 0 {
+		errs = append(errs, errors.New("storage.retainBlocks must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateEndpoints(field string, endpoints []string) []error {
+	if len(endpoints) == 0 {
+		return []error{fmt.Errorf("%s requires at least one endpoint", field)}
+	}
+
+	var errs []error
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s: invalid URL %q", field, redactURL(endpoint)))
+		}
+	}
+
+	return errs
+}
+
+func validateRateLimit(field string, limit RateLimitConfig) []error {
+	var errs []error
+	if limit.Rate <= 0 {
+		errs = append(errs, fmt.Errorf("%s.rate must be positive", field))
+	}
+	if limit.Burst < 1 {
+		errs = append(errs, fmt.Errorf("%s.burst must be at least 1", field))
+	}
+	if limit.Quota < 0 {
+		errs = append(errs, fmt.Errorf("%s.quota must not be negative", field))
+	}
+	if limit.Quota > 0 && limit.QuotaPeriod <= 0 && !limit.QuotaMonthly {
+		errs = append(errs, fmt.Errorf("%s.quotaPeriod must be positive", field))
+	}
+	if limit.QuotaPeriod > 0 && limit.QuotaMonthly {
+		errs = append(errs, fmt.Errorf("%s.quotaPeriod and %s.quotaMonthly are exclusive", field, field))
+	}
+	for method, weight := range limit.Weights {
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("%s.weights.%s must not be negative", field, method))
+		}
+	}
+
+	return errs
+}
+
+// ChainList returns the crawled chains, a single DefaultChain configured by
+// the rpc and crawler sections when no chain is listed
+func (c Config) ChainList() []ChainConfig {
+	if len(c.Chains) > 0 {
+		return c.Chains
+	}
+
+	return []ChainConfig{{
+		Name:          DefaultChain,
+		Type:          ChainTypeEVM,
+		ChainID:       c.RPC.ChainID,
+		Endpoints:     c.RPC.Endpoints,
+		StartBlock:    c.Crawler.StartBlock,
+		Confirmations: c.Crawler.Confirmations,
+
+		Traces:                   c.Crawler.Traces,
+		AutoSubscribeDeployments: c.Crawler.AutoSubscribeDeployments,
+	}}
+}
+
+// Redacted returns a copy of the config which is safe to print, credentials
+// and API keys embedded in RPC and webhook URLs are masked
+func (c Config) Redacted() Config {
+	redacted := c
+	redacted.RPC.Endpoints = redactURLs(c.RPC.Endpoints)
+	if c.Alerts.Webhook != "" {
+		redacted.Alerts.Webhook = redactURL(c.Alerts.Webhook)
+	}
+	if c.Chains != nil {
+		redacted.Chains = make([]ChainConfig, len(c.Chains))
+		for i, chain := range c.Chains {
+			chain.Endpoints = redactURLs(chain.Endpoints)
+			redacted.Chains[i] = chain
+		}
+	}
+
+	return redacted
+}
+
+// String returns the redacted config as JSON
+func (c Config) String() string {
+	b, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(b)
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func durationSetter(field func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		return field(c).UnmarshalText([]byte(v))
+	}
+}
+
+func boolSetter(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func uintSetter(field func(c *Config) *uint64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"time"
+)
+
+// Duration is a time.Duration which is written as a string like "4s" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(input []byte) error {
+	v, err := time.ParseDuration(string(input))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Std returns the value as time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redactedValue = "xxxxx"
+
+// minSecretSegmentLen is the length from which an URL path segment is considered as an API key,
+// e.g. https://mainnet.infura.io/v3/<key>
+const minSecretSegmentLen = 16
+
+// redactURL masks the user info, the query values and the path segments which look like API keys
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redactedValue
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		} else {
+			u.User = url.User(redactedValue)
+		}
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, redactedValue)
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if looksLikeSecret(segment) {
+			segments[i] = redactedValue
+		}
+	}
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+
+	return u.String()
+}
+
+func redactURLs(raw []string) []string {
+	redacted := make([]string, len(raw))
+	for i, u := range raw {
+		redacted[i] = redactURL(u)
+	}
+
+	return redacted
+}
+
+func looksLikeSecret(segment string) bool {
+	if len(segment) < minSecretSegmentLen {
+		return false
+	}
+	for _, c := range segment {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && c != '-' && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
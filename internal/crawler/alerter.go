@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Alerter evaluates the transactions matched in a block with the subscribed addresses they matched,
+// before the block is saved. A block parsed again after an error is evaluated again.
+type Alerter interface {
+	Evaluate(ctx context.Context, txns []repository.IndexedTransaction)
+}
+
+// evaluateAlerts passes the matched transactions to the alerter of the options, if any
+func evaluateAlerts(ctx context.Context, repo repository.Repository, opts Options, txns []types.Transaction) error {
+	if opts.Alerter == nil || len(txns) == 0 {
+		return nil
+	}
+
+	addresses, err := repo.GetAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	subscribed := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		subscribed[utils.NormalizeAddress(address)] = struct{}{}
+	}
+
+	indexed := make([]repository.IndexedTransaction, 0, len(txns))
+	for _, tx := range txns {
+		var matched []string
+		for _, address := range tx.Addresses() {
+			if _, ok := subscribed[utils.NormalizeAddress(address)]; ok {
+				matched = append(matched, address)
+			}
+		}
+		if len(matched) > 0 {
+			indexed = append(indexed, repository.IndexedTransaction{Transaction: tx, Addresses: matched})
+		}
+	}
+	opts.Alerter.Evaluate(ctx, indexed)
+
+	return nil
+}
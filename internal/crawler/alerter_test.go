@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/ethtest"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// alerterFunc is an Alerter calling the function
+type alerterFunc func(ctx context.Context, txns []repository.IndexedTransaction)
+
+func (f alerterFunc) Evaluate(ctx context.Context, txns []repository.IndexedTransaction) {
+	f(ctx, txns)
+}
+
+func TestEthereumCrawler_Run_alerter(t *testing.T) {
+	ctx := context.TODO()
+	const alice, bob = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	node := ethtest.NewNode(t)
+	node.AddTransactions(ethtest.Transaction{From: alice, To: bob, Value: 1})
+	node.Mine(1)
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, "0x4838B106FCe9647Bdf1E7877BF73cE8B0BAD5f97"))
+
+	var evaluated []repository.IndexedTransaction
+	alerter := alerterFunc(func(_ context.Context, txns []repository.IndexedTransaction) {
+		// the block is not saved yet
+		parsed, err := repo.GetCurrentBlock(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, parsed)
+		evaluated = append(evaluated, txns...)
+	})
+	crawler := NewEthereumCrawler(repo, NewEthereumClient(node.URL), Options{Chain: "ethereum", StartBlock: 1, Alerter: alerter})
+	assert.NoError(t, crawler.Run(ctx))
+
+	if assert.Len(t, evaluated, 1) {
+		assert.Equal(t, []string{bob}, evaluated[0].Addresses)
+		assert.Equal(t, "ethereum", evaluated[0].Transaction.Chain)
+		// the fee is known to the rules
+		assert.Equal(t, "0x17dfcdece4000", evaluated[0].Transaction.Fee)
+	}
+}
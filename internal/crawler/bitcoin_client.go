@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+)
+
+// BitcoinClient reads the blocks of a Bitcoin-family chain from a bitcoind-style JSON-RPC node
+type BitcoinClient interface {
+	// BlockNumber return the height of the best chain tip
+	BlockNumber(ctx context.Context) (uint64, error)
+	GetBlockHash(ctx context.Context, height uint64) (string, error)
+	// GetBlock return the block with its decoded transactions
+	GetBlock(ctx context.Context, hash string) (*types.BitcoinBlock, error)
+	// GetRawTransaction return a decoded transaction, the node must run with -txindex
+	// to look up confirmed transactions
+	GetRawTransaction(ctx context.Context, txid string) (*types.BitcoinTransaction, error)
+}
+
+type bitcoinClient struct {
+	rpcTransport
+}
+
+func NewBitcoinClient(rpcNode string, fallbacks ...string) *bitcoinClient {
+	return &bitcoinClient{rpcTransport: rpcTransport{
+		rpcNodes:     append([]string{rpcNode}, fallbacks...),
+		legacyErrors: true,
+	}}
+}
+
+func (c *bitcoinClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var height uint64
+	err := c.callMethod(ctx, &height, getBlockCountMethod, []interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+func (c *bitcoinClient) GetBlockHash(ctx context.Context, height uint64) (string, error) {
+	var hash string
+	err := c.callMethod(ctx, &hash, getBlockHashMethod, []interface{}{height})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+func (c *bitcoinClient) GetBlock(ctx context.Context, hash string) (*types.BitcoinBlock, error) {
+	var block types.BitcoinBlock
+	// verbosity 2 returns the decoded transactions
+	err := c.callMethod(ctx, &block, getBlockMethod, []interface{}{hash, 2})
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+func (c *bitcoinClient) GetRawTransaction(ctx context.Context, txid string) (*types.BitcoinTransaction, error) {
+	var tx types.BitcoinTransaction
+	err := c.callMethod(ctx, &tx, getRawTransactionMethod, []interface{}{txid, true})
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	btcAlice = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	btcBob   = "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"
+	btcCarol = "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"
+)
+
+// fakeBitcoind serves the JSON-RPC methods used by the bitcoin client from canned blocks,
+// errors are answered the legacy way with an HTTP 500 status
+type fakeBitcoind struct {
+	mu     sync.Mutex
+	blocks []string // block hashes by height
+	raw    map[string]json.RawMessage
+	calls  map[string]int
+}
+
+func newFakeBitcoind(t *testing.T) (*fakeBitcoind, *httptest.Server) {
+	node := &fakeBitcoind{raw: make(map[string]json.RawMessage), calls: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(node.serveHTTP))
+	t.Cleanup(server.Close)
+	return node, server
+}
+
+// addBlock appends a block whose transactions are JSON objects in getblock verbosity 2 format
+func (n *fakeBitcoind) addBlock(txns ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	height := len(n.blocks)
+	hash := fmt.Sprintf("%064x", height+1)
+	n.blocks = append(n.blocks, hash)
+	n.raw[hash] = json.RawMessage(fmt.Sprintf(`{"hash":%q,"height":%d,"time":%d,"previousblockhash":"","tx":[%s]}`,
+		hash, height, 1700000000+height*600, strings.Join(txns, ",")))
+	for _, tx := range txns {
+		var decoded struct {
+			TxID string `json:"txid"`
+		}
+		_ = json.Unmarshal([]byte(tx), &decoded)
+		n.raw[decoded.TxID] = json.RawMessage(tx)
+	}
+}
+
+func (n *fakeBitcoind) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[req.Method]++
+
+	var result interface{}
+	var key string
+	switch req.Method {
+	case "getblockcount":
+		result = len(n.blocks) - 1
+	case "getblockhash":
+		var height int
+		_ = json.Unmarshal(req.Params[0], &height)
+		if height < len(n.blocks) {
+			result = n.blocks[height]
+		}
+	case "getblock", "getrawtransaction":
+		_ = json.Unmarshal(req.Params[0], &key)
+		if raw, ok := n.raw[key]; ok {
+			result = raw
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":%s}`, req.ID)
+		return
+	}
+
+	if result == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, `{"result":null,"error":{"code":-5,"message":"No such mempool or blockchain transaction"},"id":%s}`, req.ID)
+		return
+	}
+	resp, _ := json.Marshal(map[string]interface{}{"result": result, "error": nil, "id": req.ID})
+	w.Write(resp)
+}
+
+func coinbaseTx(txid, address, value string) string {
+	return fmt.Sprintf(`{"txid":%q,"vin":[{"coinbase":"03a0bb0d","sequence":4294967295}],`+
+		`"vout":[{"value":%s,"n":0,"scriptPubKey":{"type":"witness_v0_keyhash","address":%q}}]}`, txid, value, address)
+}
+
+func TestBitcoinClient(t *testing.T) {
+	node, server := newFakeBitcoind(t)
+	node.addBlock(coinbaseTx("aa", btcAlice, "50.00000000"))
+	node.addBlock(coinbaseTx("bb", btcBob, "6.25000000"))
+
+	cli := NewBitcoinClient(server.URL)
+	ctx := context.TODO()
+	height, err := cli.BlockNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), height)
+
+	hash, err := cli.GetBlockHash(ctx, 1)
+	assert.NoError(t, err)
+	block, err := cli.GetBlock(ctx, hash)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), block.Height)
+	assert.Len(t, block.Tx, 1)
+	assert.Equal(t, "6.25000000", block.Tx[0].Vout[0].Value.String())
+	assert.Equal(t, btcBob, block.Tx[0].Vout[0].ScriptPubKey.PayeeAddress())
+
+	// bitcoind answers errors with an HTTP 500 status, they are returned as JSON-RPC errors
+	_, err = cli.GetRawTransaction(ctx, "unknown")
+	var rpcErr *jsonError
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -5, rpcErr.Code)
+}
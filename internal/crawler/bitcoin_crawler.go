@@ -0,0 +1,317 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// resolveConcurrency bounds the parallel getrawtransaction calls resolving the inputs of a block
+const resolveConcurrency = 8
+
+// outpoint identifies a transaction output
+type outpoint struct {
+	txid string
+	vout uint32
+}
+
+type bitcoinCrawler struct {
+	crawlStatus
+	repo repository.Repository
+	cli  BitcoinClient
+	opts Options
+}
+
+func NewBitcoinCrawler(repo repository.Repository, cli BitcoinClient, opts Options) *bitcoinCrawler {
+	return &bitcoinCrawler{repo: repo, cli: cli, opts: opts}
+}
+
+func (c *bitcoinCrawler) Run(ctx context.Context) error {
+	start := time.Now()
+	block, err := c.getNextBlock(ctx)
+	if err != nil {
+		if errors.Is(err, ErrDuplicateParsed) {
+			c.markSuccess()
+			return nil
+		}
+
+		slog.ErrorContext(ctx, "error getting next block", logging.Err(err))
+		return err
+	}
+	blockAttrs := []any{
+		slog.Uint64(logging.KeyBlockNumber, block.Height),
+		slog.String(logging.KeyBlockHash, block.Hash),
+	}
+
+	txns, err := c.extractTransactions(ctx, block)
+	if err != nil {
+		slog.ErrorContext(ctx, "error extracting transactions from block", append(blockAttrs, logging.Err(err))...)
+		return err
+	}
+	err = evaluateAlerts(ctx, c.repo, c.opts, txns)
+	if err != nil {
+		slog.ErrorContext(ctx, "error evaluating alerts", append(blockAttrs, logging.Err(err))...)
+		return err
+	}
+
+	err = c.repo.SaveTransactions(ctx, block.Height, txns)
+	if err != nil {
+		slog.ErrorContext(ctx, "error saving transactions",
+			append(blockAttrs, slog.Int("count", len(txns)), logging.Err(err))...)
+		return err
+	}
+
+	observeParsed(c.opts.Chain, block.Height, len(txns))
+	c.setParsed(block.Height, block.Time, c.opts.Confirmations)
+	c.markSuccess()
+	slog.InfoContext(ctx, "parsed block", append(blockAttrs,
+		slog.Int("transactions", len(block.Tx)),
+		slog.Int("matched", len(txns)),
+		slog.Duration(logging.KeyDuration, time.Since(start)))...)
+	return nil
+}
+
+// getNextBlock return the block following the last parsed one, once it has enough confirmations
+func (c *bitcoinCrawler) getNextBlock(ctx context.Context) (*types.BitcoinBlock, error) {
+	head, err := c.cli.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.setHead(head)
+
+	parsed, err := c.repo.GetCurrentBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	observeHead(c.opts.Chain, head, parsed)
+
+	height, ok := nextBlockNumber(head, parsed, c.opts)
+	if !ok {
+		slog.DebugContext(ctx, "no new confirmed block", slog.Uint64("head", head), slog.Uint64("parsed", parsed))
+		return nil, ErrDuplicateParsed
+	}
+
+	hash, err := c.cli.GetBlockHash(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.cli.GetBlock(ctx, hash)
+}
+
+// extractTransactions return the transactions of the block sending from or paying to a subscribed address
+func (c *bitcoinCrawler) extractTransactions(ctx context.Context, block *types.BitcoinBlock) ([]types.Transaction, error) {
+	addresses, err := c.repo.GetAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	addressDict := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		addressDict[utils.NormalizeAddress(address)] = struct{}{}
+	}
+
+	spent, err := c.resolveInputs(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+
+	var txns []types.Transaction
+	for i, tx := range block.Tx {
+		txn, err := c.toTransaction(block, i, tx, spent)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", tx.TxID, err)
+		}
+
+		for _, address := range txn.Addresses() {
+			if _, ok := addressDict[utils.NormalizeAddress(address)]; ok {
+				txns = append(txns, txn)
+				break
+			}
+		}
+	}
+
+	return txns, nil
+}
+
+// resolveInputs return the outputs spent by the inputs of the block. The outputs are taken from
+// the prevout of the inputs when the node returns it, from the block itself or looked up otherwise.
+func (c *bitcoinCrawler) resolveInputs(ctx context.Context, block *types.BitcoinBlock) (map[outpoint]types.BitcoinOutput, error) {
+	inBlock := make(map[string]*types.BitcoinTransaction, len(block.Tx))
+	for i := range block.Tx {
+		inBlock[block.Tx[i].TxID] = &block.Tx[i]
+	}
+
+	spent := make(map[outpoint]types.BitcoinOutput)
+	var lookups []string
+	missing := make(map[string]struct{})
+	for _, tx := range block.Tx {
+		for _, in := range tx.Vin {
+			if in.Coinbase != "" || in.Prevout != nil {
+				continue
+			}
+			if _, ok := inBlock[in.TxID]; ok {
+				continue
+			}
+			if _, ok := missing[in.TxID]; !ok {
+				missing[in.TxID] = struct{}{}
+				lookups = append(lookups, in.TxID)
+			}
+		}
+	}
+
+	prevTxns, err := c.getTransactions(ctx, lookups)
+	if err != nil {
+		return nil, err
+	}
+	for txid, tx := range inBlock {
+		prevTxns[txid] = tx
+	}
+
+	for _, tx := range block.Tx {
+		for _, in := range tx.Vin {
+			if in.Coinbase != "" {
+				continue
+			}
+			point := outpoint{txid: in.TxID, vout: in.Vout}
+			if in.Prevout != nil {
+				spent[point] = *in.Prevout
+				continue
+			}
+
+			prev, ok := prevTxns[in.TxID]
+			if !ok || int(in.Vout) >= len(prev.Vout) {
+				return nil, fmt.Errorf("input %s:%d of transaction %s: spent output not found", in.TxID, in.Vout, tx.TxID)
+			}
+			spent[point] = prev.Vout[in.Vout]
+		}
+	}
+
+	return spent, nil
+}
+
+// getTransactions looks up the transactions by txid, resolveConcurrency at a time
+func (c *bitcoinCrawler) getTransactions(ctx context.Context, txids []string) (map[string]*types.BitcoinTransaction, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		txns     = make(map[string]*types.BitcoinTransaction, len(txids))
+		sem      = make(chan struct{}, resolveConcurrency)
+	)
+	for _, txid := range txids {
+		if ctx.Err() != nil {
+			break
+		}
+		txid := txid
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			tx, err := c.cli.GetRawTransaction(ctx, txid)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("get transaction %s: %w", txid, err)
+					cancel()
+				}
+				return
+			}
+			txns[txid] = tx
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return txns, firstErr
+}
+
+// toTransaction converts the i-th transaction of the block, spent holds the outputs spent by its inputs
+func (c *bitcoinCrawler) toTransaction(block *types.BitcoinBlock, i int, tx types.BitcoinTransaction,
+	spent map[outpoint]types.BitcoinOutput) (types.Transaction, error) {
+	txn := types.Transaction{
+		BlockNumber:      utils.HexUint64(block.Height),
+		BlockHash:        block.Hash,
+		Hash:             tx.TxID,
+		TransactionIndex: utils.EncodeUint64(uint64(i)),
+		Timestamp:        utils.HexUint64(block.Time),
+		Chain:            c.opts.Chain,
+		// a mined UTXO transaction can not fail
+		Status: types.StatusSuccess,
+	}
+
+	var spentTotal uint64
+	for _, in := range tx.Vin {
+		if in.Coinbase != "" {
+			continue
+		}
+		utxo, sats, err := toUTXO(spent[outpoint{txid: in.TxID, vout: in.Vout}])
+		if err != nil {
+			return types.Transaction{}, err
+		}
+		spentTotal += sats
+		txn.Inputs = append(txn.Inputs, utxo)
+	}
+
+	var total uint64
+	for _, out := range tx.Vout {
+		utxo, sats, err := toUTXO(out)
+		if err != nil {
+			return types.Transaction{}, err
+		}
+		total += sats
+		txn.Outputs = append(txn.Outputs, utxo)
+	}
+	txn.Value = strconv.FormatUint(total, 10)
+	// the fee is what the inputs spend above the outputs, a coinbase pays none
+	if len(txn.Inputs) > 0 && spentTotal >= total {
+		txn.Fee = strconv.FormatUint(spentTotal-total, 10)
+	}
+
+	for _, in := range txn.Inputs {
+		if in.Address != "" {
+			txn.From = in.Address
+			break
+		}
+	}
+	for _, out := range txn.Outputs {
+		if out.Address != "" {
+			txn.To = out.Address
+			break
+		}
+	}
+
+	return txn, nil
+}
+
+// toUTXO converts an output, its value is returned in satoshis
+func toUTXO(out types.BitcoinOutput) (types.UTXO, uint64, error) {
+	sats, err := utils.ParseBitcoinAmount(out.Value.String())
+	if err != nil {
+		return types.UTXO{}, 0, fmt.Errorf("output value %q: %w", out.Value, err)
+	}
+
+	return types.UTXO{
+		Address: out.ScriptPubKey.PayeeAddress(),
+		Value:   strconv.FormatUint(sats, 10),
+	}, sats, nil
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitcoinCrawler_Run(t *testing.T) {
+	node, server := newFakeBitcoind(t)
+	node.addBlock(
+		coinbaseTx("c0", btcCarol, "50.00000000"),
+		`{"txid":"f0","vin":[{"txid":"e0","vout":0}],"vout":[`+
+			`{"value":0.70000000,"n":0,"scriptPubKey":{"type":"pubkeyhash","address":"`+btcBob+`"}},`+
+			`{"value":0.29990000,"n":1,"scriptPubKey":{"type":"scripthash","address":"`+btcCarol+`"}}]}`,
+	)
+	node.addBlock(
+		coinbaseTx("c1", btcCarol, "6.25000000"),
+		// Bob spends an output of the previous block, resolved with getrawtransaction
+		`{"txid":"f1","vin":[{"txid":"f0","vout":0}],"vout":[`+
+			`{"value":0.50000000,"n":0,"scriptPubKey":{"type":"witness_v0_keyhash","address":"`+btcAlice+`"}},`+
+			`{"value":0.19000000,"n":1,"scriptPubKey":{"type":"pubkeyhash","address":"`+btcBob+`"}},`+
+			`{"value":0,"n":2,"scriptPubKey":{"type":"nulldata"}}]}`,
+		// the spent output is carried by the input, as with getblock verbosity 3
+		`{"txid":"f2","vin":[{"txid":"e1","vout":3,"prevout":{"value":1.00000000,"scriptPubKey":{"type":"scripthash","address":"`+btcCarol+`"}}}],"vout":[`+
+			`{"value":0.99000000,"n":0,"scriptPubKey":{"type":"witness_v0_keyhash","address":"`+btcAlice+`"}}]}`,
+		// spends an output of the same block
+		`{"txid":"f3","vin":[{"txid":"f2","vout":0}],"vout":[`+
+			`{"value":0.98000000,"n":0,"scriptPubKey":{"type":"scripthash","address":"`+btcCarol+`"}}]}`,
+	)
+
+	ctx := context.TODO()
+	repo := repository.NewInMemRepo()
+	_, err := repo.AddAddresses(ctx, []string{btcAlice, btcBob})
+	assert.NoError(t, err)
+
+	crawler := NewBitcoinCrawler(repo, NewBitcoinClient(server.URL), Options{Chain: "bitcoin", StartBlock: 1})
+	assert.NoError(t, crawler.Run(ctx))
+	assert.False(t, crawler.LastSuccess().IsZero())
+
+	parsed, err := repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), parsed)
+	assert.Equal(t, 1, node.calls["getrawtransaction"])
+
+	bobTxns, err := repo.QueryTransactions(ctx, btcBob, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Transaction{{
+		BlockNumber:      1,
+		BlockHash:        fmt.Sprintf("%064x", 2),
+		From:             btcBob,
+		To:               btcAlice,
+		Value:            "69000000",
+		Hash:             "f1",
+		TransactionIndex: "0x1",
+		Timestamp:        1700000600,
+		Status:           types.StatusSuccess,
+		Fee:              "1000000",
+		Chain:            "bitcoin",
+		Inputs:           []types.UTXO{{Address: btcBob, Value: "70000000"}},
+		Outputs: []types.UTXO{
+			{Address: btcAlice, Value: "50000000"},
+			{Address: btcBob, Value: "19000000"},
+			{Address: "", Value: "0"},
+		},
+	}}, bobTxns)
+
+	aliceTxns, err := repo.QueryTransactions(ctx, btcAlice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, aliceTxns, 3)
+	assert.Equal(t, "f2", aliceTxns[1].Hash)
+	assert.Equal(t, []types.UTXO{{Address: btcCarol, Value: "100000000"}}, aliceTxns[1].Inputs)
+	assert.Equal(t, "f3", aliceTxns[2].Hash)
+	assert.Equal(t, btcAlice, aliceTxns[2].From)
+
+	// no new block
+	assert.NoError(t, crawler.Run(ctx))
+	assert.Equal(t, 1, node.calls["getblock"])
+}
+
+func TestBitcoinCrawler_unresolvedInput(t *testing.T) {
+	node, server := newFakeBitcoind(t)
+	node.addBlock(`{"txid":"f0","vin":[{"txid":"e0","vout":0}],"vout":[` +
+		`{"value":0.1,"n":0,"scriptPubKey":{"type":"pubkeyhash","address":"` + btcBob + `"}}]}`)
+
+	ctx := context.TODO()
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, btcBob))
+
+	crawler := NewBitcoinCrawler(repo, NewBitcoinClient(server.URL), Options{})
+	err := crawler.Run(ctx)
+	assert.ErrorContains(t, err, "get transaction e0: No such mempool or blockchain transaction")
+	assert.True(t, crawler.LastSuccess().IsZero())
+}
@@ -0,0 +1,236 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/TrustWallet/tx-parser/internal/cache"
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/types"
+)
+
+// CacheOptions tunes which results the caching clients keep
+type CacheOptions struct {
+	// Chain prefixes the keys, so that the clients of several chains can share a cache
+	Chain string
+	// Network identifies the network the endpoints serve, the verified chain ID of an EVM chain or
+	// the genesis block hash of a bitcoin chain. It is part of the keys, so that the results
+	// persisted on disk are not served once the endpoints of the chain serve another network.
+	Network string
+	// Finality is the number of blocks mined on top of a block from which it can not be
+	// reorganized anymore, only the final blocks are cached by number
+	Finality uint64
+}
+
+// resultCache stores the immutable results of the RPC calls of a chain. The head is the
+// highest block number returned by the node, the blocks are final relative to it.
+type resultCache struct {
+	cache cache.Cache
+	opts  CacheOptions
+	head  atomic.Uint64
+}
+
+func (c *resultCache) observeHead(head uint64) {
+	for {
+		current := c.head.Load()
+		if head <= current || c.head.CompareAndSwap(current, head) {
+			return
+		}
+	}
+}
+
+// final reports whether the block can not be reorganized anymore, blocks are not
+// final until the head is known
+func (c *resultCache) final(number uint64) bool {
+	head := c.head.Load()
+	return head > 0 && number+c.opts.Finality <= head
+}
+
+func (c *resultCache) key(m method, id interface{}) string {
+	return fmt.Sprintf("%s/%s/%s/%v", c.opts.Chain, c.opts.Network, m, id)
+}
+
+// load decodes the cached result of the call into v
+func (c *resultCache) load(m method, key string, v interface{}) bool {
+	raw, ok := c.cache.Get(key)
+	if ok {
+		if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(v); err != nil {
+			slog.Warn("error decoding cached result", slog.String(logging.KeyMethod, string(m)), logging.Err(err))
+			ok = false
+		}
+	}
+	observeCache(m, ok)
+	return ok
+}
+
+func (c *resultCache) store(m method, key string, v interface{}) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		slog.Warn("error encoding result to cache", slog.String(logging.KeyMethod, string(m)), logging.Err(err))
+		return
+	}
+	c.cache.Set(key, buf.Bytes())
+}
+
+// cachingClient serves the final blocks from the cache, the head number is never cached
+type cachingClient struct {
+	Client
+	resultCache
+}
+
+func NewCachingClient(cli Client, c cache.Cache, opts CacheOptions) *cachingClient {
+	return &cachingClient{Client: cli, resultCache: resultCache{cache: c, opts: opts}}
+}
+
+func (c *cachingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	head, err := c.Client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.observeHead(head)
+	return head, nil
+}
+
+func (c *cachingClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	if !c.final(blockNumber) {
+		return c.Client.GetBlockByNumber(ctx, blockNumber)
+	}
+
+	key := c.key(getBlockByNumberMethod, blockNumber)
+	var block types.Block
+	if c.load(getBlockByNumberMethod, key, &block) {
+		return &block, nil
+	}
+
+	b, err := c.Client.GetBlockByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.store(getBlockByNumberMethod, key, b)
+	return b, nil
+}
+
+// GetTransactionReceipt serves the receipts of the final blocks from the cache, nil when the
+// wrapped client does not fetch receipts
+func (c *cachingClient) GetTransactionReceipt(ctx context.Context, hash string) (*types.Receipt, error) {
+	cli, ok := c.Client.(ReceiptClient)
+	if !ok {
+		return nil, nil
+	}
+
+	key := c.key(getTransactionReceiptMethod, hash)
+	var receipt types.Receipt
+	if c.load(getTransactionReceiptMethod, key, &receipt) {
+		return &receipt, nil
+	}
+
+	r, err := cli.GetTransactionReceipt(ctx, hash)
+	if err != nil || r == nil {
+		return r, err
+	}
+	if c.final(uint64(r.BlockNumber)) {
+		c.store(getTransactionReceiptMethod, key, r)
+	}
+	return r, nil
+}
+
+// TraceBlockByNumber serves the traces of the final blocks from the cache, nil when the
+// wrapped client does not trace the blocks
+func (c *cachingClient) TraceBlockByNumber(ctx context.Context, blockNumber uint64) ([]types.TransactionTrace, error) {
+	cli, ok := c.Client.(TraceClient)
+	if !ok {
+		return nil, nil
+	}
+	if !c.final(blockNumber) {
+		return cli.TraceBlockByNumber(ctx, blockNumber)
+	}
+
+	key := c.key(traceBlockByNumberMethod, blockNumber)
+	var traces []types.TransactionTrace
+	if c.load(traceBlockByNumberMethod, key, &traces) {
+		return traces, nil
+	}
+
+	traces, err := cli.TraceBlockByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	c.store(traceBlockByNumberMethod, key, traces)
+	return traces, nil
+}
+
+// cachingBitcoinClient serves the final blocks and the transactions from the cache
+type cachingBitcoinClient struct {
+	BitcoinClient
+	resultCache
+}
+
+func NewCachingBitcoinClient(cli BitcoinClient, c cache.Cache, opts CacheOptions) *cachingBitcoinClient {
+	return &cachingBitcoinClient{BitcoinClient: cli, resultCache: resultCache{cache: c, opts: opts}}
+}
+
+func (c *cachingBitcoinClient) BlockNumber(ctx context.Context) (uint64, error) {
+	head, err := c.BitcoinClient.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.observeHead(head)
+	return head, nil
+}
+
+func (c *cachingBitcoinClient) GetBlockHash(ctx context.Context, height uint64) (string, error) {
+	if !c.final(height) {
+		return c.BitcoinClient.GetBlockHash(ctx, height)
+	}
+
+	key := c.key(getBlockHashMethod, height)
+	var hash string
+	if c.load(getBlockHashMethod, key, &hash) {
+		return hash, nil
+	}
+
+	hash, err := c.BitcoinClient.GetBlockHash(ctx, height)
+	if err != nil {
+		return "", err
+	}
+	c.store(getBlockHashMethod, key, hash)
+	return hash, nil
+}
+
+func (c *cachingBitcoinClient) GetBlock(ctx context.Context, hash string) (*types.BitcoinBlock, error) {
+	key := c.key(getBlockMethod, hash)
+	var block types.BitcoinBlock
+	if c.load(getBlockMethod, key, &block) {
+		return &block, nil
+	}
+
+	b, err := c.BitcoinClient.GetBlock(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	// the block of a hash never changes, but a block which is not final may be orphaned
+	if c.final(b.Height) {
+		c.store(getBlockMethod, key, b)
+	}
+	return b, nil
+}
+
+// GetRawTransaction caches every transaction, the inputs and the outputs of a txid never change
+func (c *cachingBitcoinClient) GetRawTransaction(ctx context.Context, txid string) (*types.BitcoinTransaction, error) {
+	key := c.key(getRawTransactionMethod, txid)
+	var tx types.BitcoinTransaction
+	if c.load(getRawTransactionMethod, key, &tx) {
+		return &tx, nil
+	}
+
+	t, err := c.BitcoinClient.GetRawTransaction(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+	c.store(getRawTransactionMethod, key, t)
+	return t, nil
+}
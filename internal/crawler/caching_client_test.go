@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/cache"
+	"github.com/TrustWallet/tx-parser/internal/ethtest"
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingClient(t *testing.T) {
+	ctx := context.TODO()
+	final := &types.Block{
+		Number:       utils.HexUint64(90),
+		Hash:         "0x90",
+		Timestamp:    utils.HexUint64(1700000000),
+		Transactions: []types.Transaction{{Hash: "0xaa", From: "0x01", To: "0x02", Value: "0x10", BlockNumber: 90}},
+	}
+	recent := &types.Block{Number: utils.HexUint64(99), Hash: "0x99"}
+
+	mockCli := mocks.NewClient(t)
+	mockCli.On("BlockNumber", ctx).Return(uint64(100), nil).Twice()
+	mockCli.On("GetBlockByNumber", ctx, uint64(90)).Return(final, nil).Once()
+	mockCli.On("GetBlockByNumber", ctx, uint64(99)).Return(recent, nil).Twice()
+	cli := NewCachingClient(mockCli, cache.NewMemory(1<<20), CacheOptions{Chain: "ethereum", Finality: 10})
+
+	// the head number is never cached
+	for i := 0; i < 2; i++ {
+		head, err := cli.BlockNumber(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(100), head)
+	}
+
+	// the final block is fetched once, the recent one every time
+	for i := 0; i < 2; i++ {
+		block, err := cli.GetBlockByNumber(ctx, 90)
+		assert.NoError(t, err)
+		assert.Equal(t, final, block)
+		block, err = cli.GetBlockByNumber(ctx, 99)
+		assert.NoError(t, err)
+		assert.Equal(t, recent, block)
+	}
+	assert.Equal(t, float64(1), rpcCacheRequests.With(string(getBlockByNumberMethod), "hit").Value())
+}
+
+func TestCachingClient_unknownHead(t *testing.T) {
+	ctx := context.TODO()
+	block := &types.Block{Number: utils.HexUint64(1)}
+	mockCli := mocks.NewClient(t)
+	mockCli.On("GetBlockByNumber", ctx, uint64(1)).Return(block, nil).Twice()
+	cli := NewCachingClient(mockCli, cache.NewMemory(1<<20), CacheOptions{Chain: "ethereum"})
+
+	// no block is final until the head is known
+	for i := 0; i < 2; i++ {
+		_, err := cli.GetBlockByNumber(ctx, 1)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCachingClient_GetTransactionReceipt(t *testing.T) {
+	ctx := context.TODO()
+	const alice, bob, carol = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5",
+		"0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97", "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+	node := ethtest.NewNode(t)
+	hashes := node.AddTransactions(
+		ethtest.Transaction{From: alice, To: bob},
+		ethtest.Transaction{From: bob, To: alice, Failed: true},
+	)
+	node.Mine(1)
+	recent := node.AddTransactions(ethtest.Transaction{From: alice, To: carol})
+	node.Mine(3)
+	cli := NewCachingClient(NewEthereumClient(node.URL), cache.NewMemory(1<<20), CacheOptions{Chain: "ethereum", Finality: 3})
+	_, err := cli.BlockNumber(ctx)
+	assert.NoError(t, err)
+
+	// the receipts of the final block are fetched once, the recent one every time
+	for i := 0; i < 2; i++ {
+		receipt, err := cli.GetTransactionReceipt(ctx, hashes[0])
+		assert.NoError(t, err)
+		assert.Equal(t, types.StatusSuccess, receipt.TxStatus())
+		assert.Equal(t, "0x5208", receipt.GasUsed)
+		receipt, err = cli.GetTransactionReceipt(ctx, hashes[1])
+		assert.NoError(t, err)
+		assert.Equal(t, types.StatusFailed, receipt.TxStatus())
+		receipt, err = cli.GetTransactionReceipt(ctx, recent[0])
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HexUint64(2), receipt.BlockNumber)
+	}
+	assert.Equal(t, 4, node.Calls("eth_getTransactionReceipt"))
+
+	// the wrapped client without receipts returns none
+	receipt, err := NewCachingClient(mocks.NewClient(t), cache.NewMemory(1<<20), CacheOptions{}).GetTransactionReceipt(ctx, hashes[0])
+	assert.NoError(t, err)
+	assert.Nil(t, receipt)
+}
+
+func TestCachingBitcoinClient(t *testing.T) {
+	node, server := newFakeBitcoind(t)
+	node.addBlock(coinbaseTx("aa", btcAlice, "50.00000000"))
+	node.addBlock(coinbaseTx("bb", btcBob, "6.25000000"))
+	node.addBlock(coinbaseTx("cc", btcCarol, "6.25000000"))
+
+	ctx := context.TODO()
+	shared := cache.NewMemory(1 << 20)
+	cli := NewCachingBitcoinClient(NewBitcoinClient(server.URL), shared, CacheOptions{Chain: "bitcoin", Finality: 1})
+	height, err := cli.BlockNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), height)
+
+	for i := 0; i < 2; i++ {
+		for h := uint64(1); h <= 2; h++ {
+			hash, err := cli.GetBlockHash(ctx, h)
+			assert.NoError(t, err)
+			block, err := cli.GetBlock(ctx, hash)
+			assert.NoError(t, err)
+			assert.Equal(t, h, block.Height)
+		}
+		tx, err := cli.GetRawTransaction(ctx, "aa")
+		assert.NoError(t, err)
+		assert.Equal(t, btcAlice, tx.Vout[0].ScriptPubKey.PayeeAddress())
+	}
+
+	// block 2 is not final, block 1 and the transaction are served from the cache
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	assert.Equal(t, 3, node.calls["getblockhash"])
+	assert.Equal(t, 3, node.calls["getblock"])
+	assert.Equal(t, 1, node.calls["getrawtransaction"])
+
+	// the chains sharing the cache do not see the results of each other
+	_, ok := shared.Get("litecoin//getrawtransaction/aa")
+	assert.False(t, ok)
+}
+
+func TestCachingClient_network(t *testing.T) {
+	ctx := context.TODO()
+	shared := cache.NewMemory(1 << 20)
+	mainnet := &types.Block{Number: utils.HexUint64(1), Hash: "0x01"}
+	testnet := &types.Block{Number: utils.HexUint64(1), Hash: "0x02"}
+
+	mainnetCli := mocks.NewClient(t)
+	mainnetCli.On("BlockNumber", ctx).Return(uint64(100), nil).Once()
+	mainnetCli.On("GetBlockByNumber", ctx, uint64(1)).Return(mainnet, nil).Once()
+	cli := NewCachingClient(mainnetCli, shared, CacheOptions{Chain: "ethereum", Network: "1", Finality: 10})
+	_, err := cli.BlockNumber(ctx)
+	assert.NoError(t, err)
+	block, err := cli.GetBlockByNumber(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, mainnet, block)
+
+	// the endpoints of the chain now serve another network, the cached blocks of the previous one are not served
+	testnetCli := mocks.NewClient(t)
+	testnetCli.On("BlockNumber", ctx).Return(uint64(100), nil).Once()
+	testnetCli.On("GetBlockByNumber", ctx, uint64(1)).Return(testnet, nil).Once()
+	cli = NewCachingClient(testnetCli, shared, CacheOptions{Chain: "ethereum", Network: "11155111", Finality: 10})
+	_, err = cli.BlockNumber(ctx)
+	assert.NoError(t, err)
+	block, err = cli.GetBlockByNumber(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, testnet, block)
+}
+
+func TestCachingClient_TraceBlockByNumber(t *testing.T) {
+	ctx := context.TODO()
+	node, hashes := deploymentNode(t)
+	node.Mine(3)
+	cli := NewCachingClient(NewEthereumClient(node.URL), cache.NewMemory(1<<20), CacheOptions{Chain: "ethereum", Finality: 3})
+	_, err := cli.BlockNumber(ctx)
+	assert.NoError(t, err)
+
+	// the trace of the final block is fetched once, the recent one every time
+	for i := 0; i < 2; i++ {
+		traces, err := cli.TraceBlockByNumber(ctx, 1)
+		assert.NoError(t, err)
+		if assert.Len(t, traces, 3) {
+			assert.Equal(t, hashes[1], traces[1].TxHash)
+			assert.Equal(t, []types.Deployment{{Address: pair, Deployer: factory, Opcode: "CREATE2"}},
+				traces[1].Result.InternalDeployments())
+		}
+		traces, err = cli.TraceBlockByNumber(ctx, 3)
+		assert.NoError(t, err)
+		assert.Empty(t, traces)
+	}
+	assert.Equal(t, 3, node.Calls("debug_traceBlockByNumber"))
+
+	// the wrapped client without traces returns none
+	traces, err := NewCachingClient(mocks.NewClient(t), cache.NewMemory(1<<20), CacheOptions{}).TraceBlockByNumber(ctx, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, traces)
+}
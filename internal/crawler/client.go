@@ -1,13 +1,9 @@
 package crawler
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"reflect"
 
 	"github.com/TrustWallet/tx-parser/internal/types"
 	"github.com/TrustWallet/tx-parser/internal/utils"
@@ -16,40 +12,67 @@ import (
 type Client interface {
 	BlockNumber(ctx context.Context) (uint64, error)
 	GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error)
+	ChainID(ctx context.Context) (uint64, error)
+}
+
+// ReceiptClient is implemented by the clients fetching the transaction receipts, the
+// crawler then saves the execution status of the transactions
+type ReceiptClient interface {
+	// GetTransactionReceipt return the receipt of a mined transaction, nil when the node does not know it
+	GetTransactionReceipt(ctx context.Context, hash string) (*types.Receipt, error)
+}
+
+// TraceClient is implemented by the clients tracing the internal calls of the blocks, the crawler
+// then records the contracts created by the internal calls. The node must serve debug_traceBlockByNumber.
+type TraceClient interface {
+	// TraceBlockByNumber return the call frames of the transactions of a block, in block order
+	TraceBlockByNumber(ctx context.Context, blockNumber uint64) ([]types.TransactionTrace, error)
 }
 
 type ethereumClient struct {
-	rpcNode string
+	rpcTransport
 }
 
-func NewEthereumClient(rpcNode string) *ethereumClient {
-	return &ethereumClient{rpcNode: rpcNode}
+func NewEthereumClient(rpcNode string, fallbacks ...string) *ethereumClient {
+	return &ethereumClient{rpcTransport: rpcTransport{rpcNodes: append([]string{rpcNode}, fallbacks...)}}
 }
 
 func (c *ethereumClient) BlockNumber(ctx context.Context) (uint64, error) {
 	var result utils.HexUint64
-	err := c.callMethod(ctx, &result, map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  blockNumberMethod,
-		"params":  []string{},
-		"id":      83, // can be any ID, not matter now
-	})
+	err := c.callMethod(ctx, &result, blockNumberMethod, []string{})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
 
+// ChainID return the EIP-155 chain ID of the network the node is connected to
+func (c *ethereumClient) ChainID(ctx context.Context) (uint64, error) {
+	var result utils.HexUint64
+	err := c.callMethod(ctx, &result, chainIDMethod, []string{})
 	if err != nil {
 		return 0, err
 	}
 	return uint64(result), nil
 }
 
+// VerifyChainID checks the node serves the expected chain, so that a misconfigured
+// endpoint does not mix the blocks of another network into the repository
+func VerifyChainID(ctx context.Context, cli Client, want uint64) error {
+	got, err := cli.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("get chain ID: %w", err)
+	}
+	if got != want {
+		return fmt.Errorf("%w: node serves chain %d, expected %d", ErrChainIDMismatch, got, want)
+	}
+	return nil
+}
+
 func (c *ethereumClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
 	number := utils.EncodeUint64(blockNumber)
 	var raw json.RawMessage
-	err := c.callMethod(ctx, &raw, map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  getBlockByNumberMethod,
-		"params":  []interface{}{number, true},
-		"id":      1, // can be any ID, not matter now
-	})
+	err := c.callMethod(ctx, &raw, getBlockByNumberMethod, []interface{}{number, true})
 	if err != nil {
 		return nil, err
 	}
@@ -66,62 +89,61 @@ func (c *ethereumClient) GetBlockByNumber(ctx context.Context, blockNumber uint6
 	return &block, nil
 }
 
-func (c *ethereumClient) callMethod(ctx context.Context, result interface{}, callBody interface{}) error {
-	if result != nil && reflect.TypeOf(result).Kind() != reflect.Ptr {
-		return fmt.Errorf("call result parameter must be pointer or nil interface: %v", result)
-	}
-	respBody, err := c.doRequest(ctx, callBody)
+// GetTransactionByHash return a transaction, nil when the node does not know it. The block
+// number of a pending transaction is zero.
+func (c *ethereumClient) GetTransactionByHash(ctx context.Context, hash string) (*types.Transaction, error) {
+	var raw json.RawMessage
+	err := c.callMethod(ctx, &raw, getTransactionByHashMethod, []string{hash})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if string(raw) == "null" {
+		return nil, nil
 	}
-	defer respBody.Close()
 
-	var respmsg jsonrpcMessage
-	if err = json.NewDecoder(respBody).Decode(&respmsg); err != nil {
-		return err
+	// blockNumber is null while the transaction is pending
+	var tx struct {
+		types.Transaction
+		BlockNumber *utils.HexUint64 `json:"blockNumber"`
 	}
-	if respmsg.Error != nil {
-		return respmsg.Error
+	err = json.Unmarshal(raw, &tx)
+	if err != nil {
+		return nil, err
 	}
-	if len(respmsg.Result) == 0 {
-		return ErrNoResult
+	if tx.BlockNumber != nil {
+		tx.Transaction.BlockNumber = *tx.BlockNumber
 	}
 
-	if result == nil {
-		return nil
-	}
-	return json.Unmarshal(respmsg.Result, result)
+	return &tx.Transaction, nil
 }
 
-func (c *ethereumClient) doRequest(ctx context.Context, msg interface{}) (io.ReadCloser, error) {
-	body, err := json.Marshal(msg)
+func (c *ethereumClient) GetTransactionReceipt(ctx context.Context, hash string) (*types.Receipt, error) {
+	var raw json.RawMessage
+	err := c.callMethod(ctx, &raw, getTransactionReceiptMethod, []string{hash})
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpcNode, io.NopCloser(bytes.NewReader(body)))
+	if string(raw) == "null" {
+		return nil, nil
+	}
+
+	var receipt types.Receipt
+	err = json.Unmarshal(raw, &receipt)
 	if err != nil {
 		return nil, err
 	}
-	req.ContentLength = int64(len(body))
-	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
 
-	// do request
-	resp, err := http.DefaultClient.Do(req)
+	return &receipt, nil
+}
+
+// TraceBlockByNumber return the call frames of the transactions of a block by the callTracer
+func (c *ethereumClient) TraceBlockByNumber(ctx context.Context, blockNumber uint64) ([]types.TransactionTrace, error) {
+	var traces []types.TransactionTrace
+	err := c.callMethod(ctx, &traces, traceBlockByNumberMethod,
+		[]interface{}{utils.EncodeUint64(blockNumber), map[string]string{"tracer": "callTracer"}})
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var buf bytes.Buffer
-		var body []byte
-		if _, err := buf.ReadFrom(resp.Body); err == nil {
-			body = buf.Bytes()
-		}
 
-		return nil, HTTPError{
-			Status:     resp.Status,
-			StatusCode: resp.StatusCode,
-			Body:       body,
-		}
-	}
-	return resp.Body, nil
+	return traces, nil
 }
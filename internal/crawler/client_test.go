@@ -2,25 +2,35 @@ package crawler
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/TrustWallet/tx-parser/internal/ethtest"
+	"github.com/TrustWallet/tx-parser/internal/rpctest"
 	"github.com/stretchr/testify/assert"
 )
 
+// ethereumFixture holds the head and the head block of the mainnet, recorded with
+// go test ./internal/crawler -run TestEthereumClient -rpctest.record
+const ethereumFixture = "testdata/ethereum_mainnet.json"
+
 // Test get latest block number no error
 func TestEthereumClient_BlockNumber(t *testing.T) {
-	cli := NewEthereumClient(EthNodeUrl)
+	node := rpctest.NewServer(t, ethereumFixture, EthNodeUrl)
+	cli := NewEthereumClient(node.URL)
 
 	blockNumber, err := cli.BlockNumber(context.TODO())
 	assert.NoError(t, err)
-	assert.Greater(t, blockNumber, uint64(0))
-	t.Logf("block number %d", blockNumber)
+	assert.NotZero(t, blockNumber)
 }
 
 func TestEthereumClient_GetBlockByNumber(t *testing.T) {
 	ctx := context.TODO()
-
-	cli := NewEthereumClient(EthNodeUrl)
+	node := rpctest.NewServer(t, ethereumFixture, EthNodeUrl)
+	cli := NewEthereumClient(node.URL)
 
 	blockNumber, err := cli.BlockNumber(ctx)
 	assert.NoError(t, err)
@@ -28,4 +38,118 @@ func TestEthereumClient_GetBlockByNumber(t *testing.T) {
 	block, err := cli.GetBlockByNumber(ctx, blockNumber)
 	assert.NoError(t, err)
 	assert.Equal(t, blockNumber, uint64(block.Number))
+	assert.NotEmpty(t, block.Hash)
+	for _, txn := range block.Transactions {
+		assert.Equal(t, block.Hash, txn.BlockHash)
+		assert.Equal(t, block.Number, txn.BlockNumber)
+		assert.Equal(t, block.Timestamp, txn.Timestamp)
+	}
+}
+
+func TestEthereumClient_GetTransactionByHash(t *testing.T) {
+	ctx := context.TODO()
+	const alice, bob = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	node := ethtest.NewNode(t)
+	hashes := node.AddTransactions(ethtest.Transaction{From: alice, To: bob, Value: 7})
+	node.Mine(1)
+	cli := NewEthereumClient(node.URL)
+
+	tx, err := cli.GetTransactionByHash(ctx, hashes[0])
+	assert.NoError(t, err)
+	if assert.NotNil(t, tx) {
+		assert.Equal(t, hashes[0], tx.Hash)
+		assert.Equal(t, alice, tx.From)
+		assert.Equal(t, bob, tx.To)
+		assert.Equal(t, "0x7", tx.Value)
+		assert.Equal(t, uint64(1), uint64(tx.BlockNumber))
+	}
+
+	// unknown transactions are null
+	tx, err = cli.GetTransactionByHash(ctx, "0x"+strings.Repeat("00", 32))
+	assert.NoError(t, err)
+	assert.Nil(t, tx)
+}
+
+func TestEthereumClient_GetTransactionByHash_pending(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"hash":"0x01","blockHash":null,"blockNumber":null,` +
+			`"transactionIndex":null,"from":"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5","to":null,"value":"0x0"}}`))
+	}))
+	defer node.Close()
+	cli := NewEthereumClient(node.URL)
+
+	tx, err := cli.GetTransactionByHash(context.TODO(), "0x01")
+	assert.NoError(t, err)
+	if assert.NotNil(t, tx) {
+		assert.Equal(t, "0x01", tx.Hash)
+		assert.Zero(t, tx.BlockNumber)
+		assert.Empty(t, tx.BlockHash)
+	}
+}
+
+func TestEthereumClient_failover(t *testing.T) {
+	var failedCalls atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failedCalls.Add(1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1228c0d"}`))
+	}))
+	defer healthy.Close()
+
+	cli := NewEthereumClient(failing.URL, healthy.URL)
+	blockNumber, err := cli.BlockNumber(context.TODO())
+	assert.NoError(t, err)
+	assert.NotZero(t, blockNumber)
+	assert.Equal(t, int32(1), failedCalls.Load())
+
+	endpoint := endpointLabel(failing.URL)
+	assert.Equal(t, float64(1), rpcErrors.With(string(blockNumberMethod), endpoint, rpcErrorHTTP, "503").Value())
+}
+
+func TestEthereumClient_jsonError(t *testing.T) {
+	var calls atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid argument"}}`))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	cli := NewEthereumClient(first.URL, second.URL)
+	_, err := cli.GetBlockByNumber(context.TODO(), 1)
+	assert.EqualError(t, err, "invalid argument")
+	// an RPC error is an answer of the node, it is not retried on the next one
+	assert.Equal(t, int32(1), calls.Load())
+
+	endpoint := endpointLabel(first.URL)
+	assert.Equal(t, float64(1), rpcErrors.With(string(getBlockByNumberMethod), endpoint, rpcErrorJSONRPC, "-32602").Value())
+}
+
+func TestEthereumClient_ChainID(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x38"}`))
+	}))
+	defer node.Close()
+
+	cli := NewEthereumClient(node.URL)
+	chainID, err := cli.ChainID(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(56), chainID)
+
+	assert.NoError(t, VerifyChainID(context.TODO(), cli, 56))
+	err = VerifyChainID(context.TODO(), cli, 1)
+	assert.ErrorIs(t, err, ErrChainIDMismatch)
+	assert.EqualError(t, err, "chain ID mismatch: node serves chain 56, expected 1")
+}
+
+func TestEndpointLabel(t *testing.T) {
+	assert.Equal(t, "mainnet.infura.io", endpointLabel("https://mainnet.infura.io/v3/0123456789abcdef"))
+	assert.Equal(t, "127.0.0.1:8545", endpointLabel("http://127.0.0.1:8545"))
+	assert.Equal(t, "unknown", endpointLabel("::"))
 }
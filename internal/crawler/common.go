@@ -9,6 +9,16 @@ type method string
 const (
 	blockNumberMethod      method = "eth_blockNumber"
 	getBlockByNumberMethod method = "eth_getBlockByNumber"
+	chainIDMethod          method = "eth_chainId"
+
+	getTransactionByHashMethod  method = "eth_getTransactionByHash"
+	getTransactionReceiptMethod method = "eth_getTransactionReceipt"
+	traceBlockByNumberMethod    method = "debug_traceBlockByNumber"
+
+	getBlockCountMethod     method = "getblockcount"
+	getBlockHashMethod      method = "getblockhash"
+	getBlockMethod          method = "getblock"
+	getRawTransactionMethod method = "getrawtransaction"
 )
 
 const EthNodeUrl = "https://cloudflare-eth.com"
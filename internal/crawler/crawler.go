@@ -3,59 +3,219 @@ package crawler
 import (
 	"context"
 	"errors"
-	"log"
-	"strings"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/TrustWallet/tx-parser/internal/logging"
 	"github.com/TrustWallet/tx-parser/internal/repository"
 	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
 )
 
 type Crawler interface {
 	Run(ctx context.Context) error
 }
 
+// Options tunes which blocks the crawler parses
+type Options struct {
+	// Chain is the name of the crawled chain, it labels the metrics
+	Chain string
+	// StartBlock is the first block to parse when nothing is parsed yet, 0 starts at the chain head
+	StartBlock uint64
+	// Confirmations is the number of blocks mined on top of a block before it is parsed
+	Confirmations uint64
+	// Alerter evaluates the matched transactions, nil disables the alerts
+	Alerter Alerter
+	// Traces traces the blocks to find the contracts created by internal calls, the client must
+	// implement TraceClient
+	Traces bool
+	// AutoSubscribeDeployments subscribes the contracts deployed by the subscribed addresses
+	AutoSubscribeDeployments bool
+}
+
+// Progress is the position of the crawler on the chain after the last crawl
+type Progress struct {
+	// Head is the latest block reported by the node
+	Head uint64
+	// Parsed is the last block parsed by the crawler and ParsedTime its timestamp,
+	// ParsedTime is zero until the crawler parsed a block
+	Parsed     uint64
+	ParsedTime time.Time
+	// Behind reports whether confirmed blocks are waiting to be parsed
+	Behind bool
+}
+
+// crawlStatus tracks the last crawl which completed without error and the progress of the crawler
+type crawlStatus struct {
+	// lastSuccess is the unix nano time of the last successful crawl
+	lastSuccess atomic.Int64
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+func (s *crawlStatus) setHead(head uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.progress.Head = head
+	s.progress.Behind = false
+}
+
+func (s *crawlStatus) setParsed(number, timestamp, confirmations uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.progress.Parsed = number
+	s.progress.ParsedTime = time.Unix(int64(timestamp), 0)
+	s.progress.Behind = number+confirmations < s.progress.Head
+}
+
+// Progress return the position of the crawler on the chain after the last crawl
+func (s *crawlStatus) Progress() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.progress
+}
+
+// Head return the latest block reported by the node at the last crawl, zero if none
+func (s *crawlStatus) Head() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.progress.Head
+}
+
+func (s *crawlStatus) markSuccess() {
+	s.lastSuccess.Store(time.Now().UnixNano())
+}
+
+// LastSuccess return the time of the last crawl which completed without error,
+// zero if none did yet
+func (s *crawlStatus) LastSuccess() time.Time {
+	nano := s.lastSuccess.Load()
+	if nano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
+
+// nextBlockNumber return the block following the last parsed one, once it has enough confirmations.
+// The crawler starts from the start block, or the confirmed head when no start block is set.
+// It returns false when there is no new confirmed block.
+func nextBlockNumber(head, parsed uint64, opts Options) (uint64, bool) {
+	if head < opts.Confirmations {
+		return 0, false
+	}
+	confirmed := head - opts.Confirmations
+
+	blockNumber := parsed + 1
+	if parsed == 0 {
+		blockNumber = opts.StartBlock
+		if blockNumber == 0 {
+			blockNumber = confirmed
+		}
+	}
+
+	return blockNumber, blockNumber <= confirmed
+}
+
 type ethereumCrawler struct {
+	crawlStatus
 	repo repository.Repository
 	cli  Client
+	opts Options
 }
 
-func NewEthereumCrawler(repo repository.Repository, cli Client) *ethereumCrawler {
-	return &ethereumCrawler{repo: repo, cli: cli}
+func NewEthereumCrawler(repo repository.Repository, cli Client, opts Options) *ethereumCrawler {
+	return &ethereumCrawler{repo: repo, cli: cli, opts: opts}
 }
 
 func (c *ethereumCrawler) Run(ctx context.Context) error {
-	block, err := c.getLatestBlock(ctx)
+	start := time.Now()
+	block, err := c.getNextBlock(ctx)
 	if err != nil {
 		if errors.Is(err, ErrDuplicateParsed) {
+			c.markSuccess()
 			return nil
 		}
 
-		log.Printf("error getting latest block %v", err)
+		slog.ErrorContext(ctx, "error getting next block", logging.Err(err))
 		return err
 	}
+	blockAttrs := []any{
+		slog.Uint64(logging.KeyBlockNumber, uint64(block.Number)),
+		slog.String(logging.KeyBlockHash, block.Hash),
+	}
 
+	err = c.traceDeployments(ctx, block)
+	if err != nil {
+		slog.ErrorContext(ctx, "error tracing block", append(blockAttrs, logging.Err(err))...)
+		return err
+	}
 	txns, err := c.extractTransactions(ctx, block)
 	if err != nil {
-		log.Printf("extract transactions from block with err: %v", err)
+		slog.ErrorContext(ctx, "error extracting transactions from block", append(blockAttrs, logging.Err(err))...)
+		return err
+	}
+	err = c.fetchStatuses(ctx, txns)
+	if err != nil {
+		slog.ErrorContext(ctx, "error fetching transaction receipts", append(blockAttrs, logging.Err(err))...)
+		return err
+	}
+	err = c.subscribeDeployments(ctx, txns)
+	if err != nil {
+		slog.ErrorContext(ctx, "error subscribing deployed contracts", append(blockAttrs, logging.Err(err))...)
+		return err
+	}
+	err = evaluateAlerts(ctx, c.repo, c.opts, txns)
+	if err != nil {
+		slog.ErrorContext(ctx, "error evaluating alerts", append(blockAttrs, logging.Err(err))...)
 		return err
 	}
 
-	return c.saveData(ctx, uint64(block.Number), txns)
+	err = c.saveData(ctx, uint64(block.Number), txns)
+	if err != nil {
+		slog.ErrorContext(ctx, "error saving transactions",
+			append(blockAttrs, slog.Int("count", len(txns)), logging.Err(err))...)
+		return err
+	}
 
+	observeParsed(c.opts.Chain, uint64(block.Number), len(txns))
+	c.setParsed(uint64(block.Number), uint64(block.Timestamp), c.opts.Confirmations)
+	c.markSuccess()
+	slog.InfoContext(ctx, "parsed block", append(blockAttrs,
+		slog.Int("transactions", len(block.Transactions)),
+		slog.Int("matched", len(txns)),
+		slog.Duration(logging.KeyDuration, time.Since(start)))...)
+	return nil
 }
 
-func (c *ethereumCrawler) getLatestBlock(ctx context.Context) (*types.Block, error) {
-	blockNumber, err := c.cli.BlockNumber(ctx)
+// getNextBlock return the block following the last parsed one, once it has enough confirmations
+func (c *ethereumCrawler) getNextBlock(ctx context.Context) (*types.Block, error) {
+	head, err := c.cli.BlockNumber(ctx)
 	if err != nil {
 		return nil, err
 	}
+	c.setHead(head)
+	if head < c.opts.Confirmations {
+		return nil, ErrDuplicateParsed
+	}
 
 	parsedBlockNum, err := c.repo.GetCurrentBlock(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if blockNumber == parsedBlockNum {
-		log.Printf("duplicate: block %d already parsed", blockNumber)
+	observeHead(c.opts.Chain, head, parsedBlockNum)
+
+	blockNumber, ok := nextBlockNumber(head, parsedBlockNum, c.opts)
+	if !ok {
+		slog.DebugContext(ctx, "no new confirmed block",
+			slog.Uint64("head", head), slog.Uint64("parsed", parsedBlockNum))
 		return nil, ErrDuplicateParsed
 	}
 
@@ -74,14 +234,17 @@ func (c *ethereumCrawler) extractTransactions(ctx context.Context, block *types.
 	}
 	addressDict := make(map[string]struct{}, len(addresses))
 	for _, address := range addresses {
-		addressDict[strings.ToLower(address)] = struct{}{}
+		addressDict[utils.NormalizeAddress(address)] = struct{}{}
 	}
 
 	var txns []types.Transaction
 	for _, txn := range block.Transactions {
-		if _, ok := addressDict[strings.ToLower(txn.From)]; ok {
+		txn.Chain = c.opts.Chain
+		if _, ok := addressDict[utils.NormalizeAddress(txn.From)]; ok {
+			txns = append(txns, txn)
+		} else if _, ok = addressDict[utils.NormalizeAddress(txn.To)]; ok {
 			txns = append(txns, txn)
-		} else if _, ok = addressDict[strings.ToLower(txn.To)]; ok {
+		} else if deployedBy(txn, addressDict) {
 			txns = append(txns, txn)
 		}
 	}
@@ -89,12 +252,41 @@ func (c *ethereumCrawler) extractTransactions(ctx context.Context, block *types.
 	return txns, nil
 }
 
-func (c *ethereumCrawler) saveData(ctx context.Context, blockNumber uint64, txns []types.Transaction) error {
-	err := c.repo.SaveTransactions(ctx, blockNumber, txns)
-	if err != nil {
-		log.Printf("error saving %d transactions", len(txns))
-		return err
+// fetchStatuses sets the execution status, the fee and the deployed contract of the matched transactions
+// from their receipts, when the client fetches them
+func (c *ethereumCrawler) fetchStatuses(ctx context.Context, txns []types.Transaction) error {
+	cli, ok := c.cli.(ReceiptClient)
+	if !ok {
+		return nil
+	}
+
+	for i := range txns {
+		receipt, err := cli.GetTransactionReceipt(ctx, txns[i].Hash)
+		if err != nil {
+			return fmt.Errorf("receipt of %s: %w", txns[i].Hash, err)
+		}
+		if receipt == nil {
+			continue
+		}
+		txns[i].Status = receipt.TxStatus()
+		// the contract of a reverted deployment is not created
+		if txns[i].To == "" && receipt.ContractAddress != "" && txns[i].Status != types.StatusFailed {
+			deployment := types.Deployment{Address: receipt.ContractAddress, Deployer: txns[i].From}
+			txns[i].Deployments = append([]types.Deployment{deployment}, txns[i].Deployments...)
+		}
+		// a receipt without gas used, e.g. cached before it was recorded, leaves the fee unknown
+		if receipt.GasUsed != "" {
+			fee, err := receipt.Fee(txns[i].GasPrice)
+			if err != nil {
+				return fmt.Errorf("receipt of %s: %w", txns[i].Hash, err)
+			}
+			txns[i].Fee = "0x" + fee.Text(16)
+		}
 	}
 
 	return nil
 }
+
+func (c *ethereumCrawler) saveData(ctx context.Context, blockNumber uint64, txns []types.Transaction) error {
+	return c.repo.SaveTransactions(ctx, blockNumber, txns)
+}
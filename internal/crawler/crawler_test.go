@@ -2,17 +2,20 @@ package crawler
 
 import (
 	"context"
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/TrustWallet/tx-parser/internal/ethtest"
 	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/repository"
 	"github.com/TrustWallet/tx-parser/internal/types"
 	"github.com/TrustWallet/tx-parser/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-func Test_getLatestBlock_success(t *testing.T) {
+func Test_getNextBlock_success(t *testing.T) {
 	ctx := context.TODO()
 	repo := mocks.NewRepository(t)
 	repo.On("GetCurrentBlock", ctx).Return(uint64(12), nil)
@@ -29,12 +32,12 @@ func Test_getLatestBlock_success(t *testing.T) {
 		cli:  cli,
 	}
 
-	block, err := crawler.getLatestBlock(ctx)
+	block, err := crawler.getNextBlock(ctx)
 	assert.NoError(t, err)
 	assert.NotEqual(t, fakeBlock, block)
 }
 
-func Test_getLatestBlock_errDuplicate(t *testing.T) {
+func Test_getNextBlock_errDuplicate(t *testing.T) {
 	ctx := context.TODO()
 	repo := mocks.NewRepository(t)
 	repo.On("GetCurrentBlock", ctx).Return(uint64(13), nil)
@@ -47,12 +50,52 @@ func Test_getLatestBlock_errDuplicate(t *testing.T) {
 		cli:  cli,
 	}
 
-	block, err := crawler.getLatestBlock(ctx)
+	block, err := crawler.getNextBlock(ctx)
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, ErrDuplicateParsed)
 	assert.Nil(t, block)
 }
 
+func Test_getNextBlock_options(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		parsed uint64
+		head   uint64
+		expect uint64
+	}{
+		{"start at head", Options{}, 0, 100, 100},
+		{"start at confirmed head", Options{Confirmations: 5}, 0, 100, 95},
+		{"start at start block", Options{StartBlock: 50, Confirmations: 5}, 0, 100, 50},
+		{"next block when behind", Options{StartBlock: 50}, 60, 100, 61},
+		{"next confirmed block", Options{Confirmations: 5}, 94, 100, 95},
+		{"not confirmed yet", Options{Confirmations: 5}, 95, 100, 0},
+		{"head below confirmations", Options{Confirmations: 5}, 0, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			repo := mocks.NewRepository(t)
+			repo.On("GetCurrentBlock", ctx).Return(tt.parsed, nil).Maybe()
+
+			cli := mocks.NewClient(t)
+			cli.On("BlockNumber", ctx).Return(tt.head, nil)
+			if tt.expect != 0 {
+				cli.On("GetBlockByNumber", ctx, tt.expect).Return(&types.Block{Number: utils.HexUint64(tt.expect)}, nil)
+			}
+
+			crawler := NewEthereumCrawler(repo, cli, tt.opts)
+			block, err := crawler.getNextBlock(ctx)
+			if tt.expect == 0 {
+				assert.ErrorIs(t, err, ErrDuplicateParsed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, uint64(block.Number))
+		})
+	}
+}
+
 func Test_extractTransactions(t *testing.T) {
 	ctx := context.TODO()
 	repo := mocks.NewRepository(t)
@@ -148,9 +191,13 @@ func TestEthereumCrawler_Run(t *testing.T) {
 	}
 	cli.On("GetBlockByNumber", ctx, uint64(14)).Return(&fakeBlock, nil)
 
-	crawler := NewEthereumCrawler(repo, cli)
+	crawler := NewEthereumCrawler(repo, cli, Options{})
+	assert.True(t, crawler.LastSuccess().IsZero())
+	assert.Zero(t, crawler.Head())
 	err := crawler.Run(ctx)
 	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), crawler.LastSuccess(), time.Second)
+	assert.Equal(t, uint64(14), crawler.Head())
 
 	repo.AssertNumberOfCalls(t, "GetCurrentBlock", 1)
 	repo.AssertNumberOfCalls(t, "GetAddresses", 1)
@@ -158,3 +205,94 @@ func TestEthereumCrawler_Run(t *testing.T) {
 	cli.AssertNumberOfCalls(t, "BlockNumber", 1)
 	cli.AssertNumberOfCalls(t, "GetBlockByNumber", 1)
 }
+
+func TestEthereumCrawler_Run_receipts(t *testing.T) {
+	ctx := context.TODO()
+	const (
+		alice = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+		bob   = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+		dave  = "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+		weth  = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+		// withdraw(1 ether) of WETH
+		withdraw = "0x2e1a7d4d0000000000000000000000000000000000000000000000000de0b6b3a7640000"
+	)
+	node := ethtest.NewNode(t)
+	node.AddTransactions(
+		ethtest.Transaction{From: alice, To: bob, Value: 1},
+		ethtest.Transaction{From: bob, To: weth, Value: 10_000_000_000_000_000, Input: "0xd0e30db0", Gas: 45_038},
+		ethtest.Transaction{From: dave, To: weth, Input: withdraw, Gas: 30_000, Failed: true},
+	)
+	node.Mine(1)
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, "0x4838B106FCe9647Bdf1E7877BF73cE8B0BAD5f97"))
+	assert.NoError(t, repo.AddAddress(ctx, "0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045"))
+
+	crawler := NewEthereumCrawler(repo, NewEthereumClient(node.URL), Options{Chain: "ethereum", StartBlock: 1})
+	assert.NoError(t, crawler.Run(ctx))
+
+	parsed, err := repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), parsed)
+	txns, err := repo.QueryTransactions(ctx, bob, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 2) {
+		assert.Equal(t, "0x1", txns[0].Value)
+		assert.Equal(t, "0x17dfcdece4000", txns[0].Fee)
+		assert.Equal(t, weth, txns[1].To)
+		assert.Equal(t, "0x2386f26fc10000", txns[1].Value)
+		assert.Equal(t, "ethereum", txns[1].Chain)
+		assert.Equal(t, types.StatusSuccess, txns[1].Status)
+		assert.Equal(t, "0x3333c87d3f000", txns[1].Fee)
+	}
+	// the status and the fee come from the receipt, a reverted call pays for its gas
+	txns, err = repo.QueryTransactions(ctx, dave, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 1) {
+		assert.Equal(t, types.StatusFailed, txns[0].Status)
+		assert.Equal(t, "0x221b262dd8000", txns[0].Fee)
+	}
+	assert.Equal(t, time.Unix(1700000012, 0), crawler.Progress().ParsedTime)
+}
+
+func TestEthereumCrawler_Run_fakeNode(t *testing.T) {
+	ctx := context.TODO()
+	const alice, bob = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	node := ethtest.NewNode(t)
+	node.Mine(1)
+	node.AddTransactions(ethtest.Transaction{From: alice, To: bob, Value: 1})
+	node.Mine(2)
+	node.AddTransactions(ethtest.Transaction{From: bob, To: alice, Value: 2})
+	node.Mine(1)
+
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+	crawler := NewEthereumCrawler(repo, NewEthereumClient(node.URL), Options{Chain: "ethereum", StartBlock: 1})
+
+	// a rate limited tick fails, the next one parses the block
+	node.FailNext("eth_getBlockByNumber", 1, ethtest.RateLimited)
+	err := crawler.Run(ctx)
+	var httpErr HTTPError
+	assert.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+
+	// the head moves while the blocks are fetched
+	node.OnCall("eth_getBlockByNumber", func() { node.Mine(1) })
+	for parsed := uint64(1); parsed <= 4; parsed++ {
+		assert.NoError(t, crawler.Run(ctx))
+		current, err := repo.GetCurrentBlock(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, parsed, current)
+	}
+	assert.Equal(t, uint64(8), node.Head())
+	assert.True(t, crawler.Progress().Behind)
+
+	// the transactions of every parsed block are kept
+	txns, err := repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 2) {
+		assert.Equal(t, bob, txns[0].To)
+		assert.Equal(t, "0x1", txns[0].Value)
+		assert.Equal(t, bob, txns[1].From)
+		assert.Equal(t, "0x2", txns[1].Value)
+	}
+}
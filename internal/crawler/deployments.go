@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Kinds of deployment of txparser_deployments_total
+const (
+	deploymentTransaction = "transaction"
+	deploymentInternal    = "internal"
+)
+
+// traceDeployments sets the contracts created by the internal calls of the transactions of the block,
+// when the traces are enabled
+func (c *ethereumCrawler) traceDeployments(ctx context.Context, block *types.Block) error {
+	cli, ok := c.cli.(TraceClient)
+	if !c.opts.Traces || !ok || len(block.Transactions) == 0 {
+		return nil
+	}
+
+	traces, err := cli.TraceBlockByNumber(ctx, uint64(block.Number))
+	if err != nil {
+		return fmt.Errorf("trace of block %d: %w", block.Number, err)
+	}
+	if traces == nil {
+		return nil
+	}
+	if len(traces) != len(block.Transactions) {
+		return fmt.Errorf("trace of block %d has %d transactions, the block has %d",
+			block.Number, len(traces), len(block.Transactions))
+	}
+	for i, trace := range traces {
+		tx := &block.Transactions[i]
+		// the nodes predating the txHash field return the traces in block order
+		if trace.TxHash != "" && !strings.EqualFold(trace.TxHash, tx.Hash) {
+			return fmt.Errorf("trace %d of block %d is of transaction %s, expected %s", i, block.Number, trace.TxHash, tx.Hash)
+		}
+		tx.Deployments = trace.Result.InternalDeployments()
+	}
+
+	return nil
+}
+
+// deployedBy reports whether a contract created by the transaction is deployed by one of the addresses
+func deployedBy(tx types.Transaction, addresses map[string]struct{}) bool {
+	for _, deployment := range tx.Deployments {
+		if _, ok := addresses[utils.NormalizeAddress(deployment.Deployer)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribeDeployments counts the deployments of the matched transactions and, when enabled, subscribes
+// the contracts deployed by the subscribed addresses, before the transactions are saved so that their
+// deployment is saved for them too
+func (c *ethereumCrawler) subscribeDeployments(ctx context.Context, txns []types.Transaction) error {
+	for _, tx := range txns {
+		for _, deployment := range tx.Deployments {
+			kind := deploymentTransaction
+			if deployment.Opcode != "" {
+				kind = deploymentInternal
+			}
+			deployments.With(c.opts.Chain, kind).Inc()
+		}
+	}
+	if !c.opts.AutoSubscribeDeployments {
+		return nil
+	}
+
+	addresses, err := c.repo.GetAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	subscribed := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		subscribed[utils.NormalizeAddress(address)] = struct{}{}
+	}
+
+	var contracts, deployers []string
+	for _, tx := range txns {
+		for _, deployment := range tx.Deployments {
+			contract := utils.NormalizeAddress(deployment.Address)
+			if _, ok := subscribed[utils.NormalizeAddress(deployment.Deployer)]; !ok {
+				continue
+			}
+			if _, ok := subscribed[contract]; ok {
+				continue
+			}
+			// the contracts it deploys in turn later in the block are subscribed too
+			subscribed[contract] = struct{}{}
+			contracts = append(contracts, contract)
+			deployers = append(deployers, deployment.Deployer)
+		}
+	}
+	if len(contracts) == 0 {
+		return nil
+	}
+
+	added, err := c.repo.AddAddresses(ctx, contracts)
+	if err != nil {
+		return err
+	}
+	for i, contract := range contracts {
+		if added[i] {
+			slog.InfoContext(ctx, "subscribed deployed contract",
+				slog.String("address", contract), slog.String("deployer", deployers[i]))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/ethtest"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	deployer = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	trader   = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	router   = "0x7a250d5630b4cf539739df2c5dacb4c659f2488d"
+	factory  = "0x5c69bee701ef814a2b6a3edd4b1652cb9cc5aa6f"
+	pair     = "0xb4e16d0168e52d35cacd2c6185b44281ec28c9dc"
+	reverted = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+)
+
+// deploymentNode mines a block with a deploying transaction of the deployer and the internal
+// creations of the factory, one of them reverted
+func deploymentNode(t *testing.T) (*ethtest.Node, []string) {
+	node := ethtest.NewNode(t)
+	hashes := node.AddTransactions(
+		ethtest.Transaction{From: deployer, Input: "0x60806040"},
+		ethtest.Transaction{From: trader, To: router, Input: "0xe8e33700", Creations: []ethtest.Creation{
+			{Creator: factory, Address: pair, Opcode: "CREATE2"},
+		}},
+		ethtest.Transaction{From: trader, To: router, Input: "0xe8e33700", Creations: []ethtest.Creation{
+			{Creator: factory, Address: reverted, Reverted: true},
+		}},
+	)
+	node.Mine(1)
+	return node, hashes
+}
+
+func TestEthereumCrawler_Run_deployments(t *testing.T) {
+	ctx := context.TODO()
+	node, hashes := deploymentNode(t)
+	cli := NewEthereumClient(node.URL)
+	receipt, err := cli.GetTransactionReceipt(ctx, hashes[0])
+	assert.NoError(t, err)
+	contract := receipt.ContractAddress
+
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, deployer))
+	assert.NoError(t, repo.AddAddress(ctx, factory))
+	crawler := NewEthereumCrawler(repo, cli, Options{Chain: "ethereum", StartBlock: 1, Traces: true, AutoSubscribeDeployments: true})
+	assert.NoError(t, crawler.Run(ctx))
+
+	// the contract of the deploying transaction comes from its receipt
+	txns, err := repo.QueryTransactions(ctx, deployer, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 1) {
+		assert.Equal(t, []types.Deployment{{Address: contract, Deployer: deployer}}, txns[0].Deployments)
+		assert.Equal(t, types.EventDeployment, txns[0].Event())
+		assert.Equal(t, []string{contract}, txns[0].Counterparties(deployer))
+	}
+	// the internal creation of the factory matches it, the reverted one does not
+	txns, err = repo.QueryTransactions(ctx, factory, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 1) {
+		assert.Equal(t, hashes[1], txns[0].Hash)
+		assert.Equal(t, []types.Deployment{{Address: pair, Deployer: factory, Opcode: "CREATE2"}}, txns[0].Deployments)
+	}
+
+	// the deployed contracts are subscribed with their deployment
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{deployer, factory, contract, pair}, addresses)
+	txns, err = repo.QueryTransactions(ctx, pair, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 1) {
+		assert.Equal(t, hashes[1], txns[0].Hash)
+	}
+}
+
+func TestEthereumCrawler_Run_deploymentsWithoutTraces(t *testing.T) {
+	ctx := context.TODO()
+	node, _ := deploymentNode(t)
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, factory))
+	crawler := NewEthereumCrawler(repo, NewEthereumClient(node.URL), Options{Chain: "ethereum", StartBlock: 1})
+	assert.NoError(t, crawler.Run(ctx))
+
+	// the internal creations are unknown and nothing is subscribed
+	txns, err := repo.QueryTransactions(ctx, factory, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+	assert.Equal(t, 0, node.Calls("debug_traceBlockByNumber"))
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{factory}, addresses)
+}
+
+func TestEthereumCrawler_Run_traceUnavailable(t *testing.T) {
+	ctx := context.TODO()
+	node, _ := deploymentNode(t)
+	node.FailNext("debug_traceBlockByNumber", 1, ethtest.InternalError)
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, factory))
+	crawler := NewEthereumCrawler(repo, NewEthereumClient(node.URL), Options{Chain: "ethereum", StartBlock: 1, Traces: true})
+
+	// the block is not parsed without its trace, the next tick retries it
+	assert.ErrorContains(t, crawler.Run(ctx), "trace of block 1")
+	parsed, err := repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), parsed)
+	assert.NoError(t, crawler.Run(ctx))
+	txns, err := repo.QueryTransactions(ctx, factory, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+}
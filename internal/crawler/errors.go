@@ -44,4 +44,6 @@ func (err *jsonError) ErrorData() interface{} {
 var (
 	ErrNoResult        = errors.New("no result in JSON-RPC response")
 	ErrDuplicateParsed = errors.New("duplicate parsed block")
+	ErrChainIDMismatch = errors.New("chain ID mismatch")
+	ErrQuotaExhausted  = errors.New("RPC quota exhausted")
 )
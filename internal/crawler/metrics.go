@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/metrics"
+)
+
+var (
+	chainHeadBlock = metrics.Default.NewGaugeVec("txparser_chain_head_block",
+		"Latest block number reported by the RPC node by chain.", "chain")
+	lastParsedBlock = metrics.Default.NewGaugeVec("txparser_last_parsed_block",
+		"Number of the last parsed block by chain.", "chain")
+	blockLag = metrics.Default.NewGaugeVec("txparser_block_lag",
+		"Number of blocks between the chain head and the last parsed block by chain.", "chain")
+	blocksProcessed = metrics.Default.NewCounterVec("txparser_blocks_processed_total",
+		"Number of parsed blocks by chain.", "chain")
+	matchedTransactions = metrics.Default.NewCounterVec("txparser_matched_transactions_total",
+		"Number of transactions matching a subscribed address by chain.", "chain")
+	deployments = metrics.Default.NewCounterVec("txparser_deployments_total",
+		"Number of contracts deployed by the matched transactions by chain and kind, transaction or internal.", "chain", "kind")
+	pollInterval = metrics.Default.NewGaugeVec("txparser_poll_interval_seconds",
+		"Delay before the next crawl tick by chain.", "chain")
+	estimatedBlockTime = metrics.Default.NewGaugeVec("txparser_estimated_block_time_seconds",
+		"Block time learned from the timestamps of the parsed blocks by chain.", "chain")
+	rpcDuration = metrics.Default.NewHistogramVec("txparser_rpc_request_duration_seconds",
+		"Latency of JSON-RPC calls by method and endpoint.", metrics.DefaultBuckets, "method", "endpoint")
+	rpcUnits = metrics.Default.NewCounterVec("txparser_rpc_units_total",
+		"Request budget units spent on JSON-RPC calls by endpoint and lane.", "endpoint", "lane")
+	rateLimitTokens = metrics.Default.NewGaugeVec("txparser_rpc_rate_limit_tokens",
+		"Units left in the token bucket of the rate limited endpoints after the last call.", "endpoint")
+	quotaRemaining = metrics.Default.NewGaugeVec("txparser_rpc_quota_remaining_units",
+		"Units left in the current quota period of the endpoints with a quota.", "endpoint")
+	rateLimitWait = metrics.Default.NewHistogramVec("txparser_rpc_rate_limit_wait_seconds",
+		"Time JSON-RPC calls waited for the rate limit by endpoint and lane.", metrics.DefaultBuckets, "endpoint", "lane")
+	rpcCacheRequests = metrics.Default.NewCounterVec("txparser_rpc_cache_requests_total",
+		"Lookups of cached JSON-RPC results by method and result, hit or miss.", "method", "result")
+	rpcErrors = metrics.Default.NewCounterVec("txparser_rpc_errors_total",
+		"JSON-RPC call errors by method, endpoint, type and code. The code is the HTTP status for http errors "+
+			"and the JSON-RPC error code for jsonrpc errors.", "method", "endpoint", "type", "code")
+)
+
+// RPC error types of txparser_rpc_errors_total
+const (
+	rpcErrorHTTP      = "http"
+	rpcErrorJSONRPC   = "jsonrpc"
+	rpcErrorNoResult  = "no_result"
+	rpcErrorTimeout   = "timeout"
+	rpcErrorTransport = "transport"
+)
+
+// observeRPC records the latency and the error of one call to an RPC node
+func observeRPC(ctx context.Context, rpcNode string, method method, duration time.Duration, err error) {
+	endpoint := endpointLabel(rpcNode)
+	rpcDuration.With(string(method), endpoint).Observe(duration.Seconds())
+	attrs := []any{
+		slog.String(logging.KeyMethod, string(method)),
+		slog.String(logging.KeyEndpoint, endpoint),
+		slog.Duration(logging.KeyDuration, duration),
+	}
+	if err == nil {
+		slog.DebugContext(ctx, "rpc call", attrs...)
+		return
+	}
+
+	errType, code := classifyRPCError(err)
+	rpcErrors.With(string(method), endpoint, errType, code).Inc()
+	slog.WarnContext(ctx, "rpc call failed", append(attrs,
+		slog.String("error_type", errType), slog.String("error_code", code), logging.Err(err))...)
+}
+
+func classifyRPCError(err error) (errType string, code string) {
+	var httpErr HTTPError
+	var rpcErr *jsonError
+	switch {
+	case errors.As(err, &httpErr):
+		return rpcErrorHTTP, strconv.Itoa(httpErr.StatusCode)
+	case errors.As(err, &rpcErr):
+		return rpcErrorJSONRPC, strconv.Itoa(rpcErr.Code)
+	case errors.Is(err, ErrNoResult):
+		return rpcErrorNoResult, ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return rpcErrorTimeout, ""
+	default:
+		return rpcErrorTransport, ""
+	}
+}
+
+// endpointLabel keeps only the host of the RPC node, the path and query often carry API keys
+func endpointLabel(rpcNode string) string {
+	u, err := url.Parse(rpcNode)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// observeCache records a lookup of a cached RPC result
+func observeCache(method method, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	rpcCacheRequests.With(string(method), result).Inc()
+}
+
+// observeHead records the chain head and the lag of the parsed block behind it
+func observeHead(chain string, head, parsed uint64) {
+	chainHeadBlock.With(chain).Set(float64(head))
+	lag := float64(0)
+	if head > parsed {
+		lag = float64(head - parsed)
+	}
+	blockLag.With(chain).Set(lag)
+}
+
+// observeParsed records a parsed block and its matched transactions
+func observeParsed(chain string, blockNumber uint64, matched int) {
+	blocksProcessed.With(chain).Inc()
+	matchedTransactions.With(chain).Add(float64(matched))
+	lastParsedBlock.With(chain).Set(float64(blockNumber))
+	observeHead(chain, uint64(chainHeadBlock.With(chain).Value()), blockNumber)
+}
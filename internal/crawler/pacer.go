@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"time"
+)
+
+const (
+	// blockTimeWeight is the weight of a new sample in the moving average of the block time
+	blockTimeWeight = 0.2
+	// pollMarginRatio is the part of the block time waited after the expected block,
+	// so that the node has received the block when it is polled
+	pollMarginRatio = 0.1
+)
+
+// PacerOptions bounds the interval between two crawl ticks
+type PacerOptions struct {
+	// Chain is the name of the crawled chain, it labels the metrics
+	Chain string
+	Min   time.Duration
+	Max   time.Duration
+	// BlockTime is the expected block time until it is learned from the parsed blocks,
+	// zero polls at Initial until then
+	BlockTime time.Duration
+	Initial   time.Duration
+}
+
+// Pacer schedules the crawl ticks of a chain. It learns the block time from the timestamps
+// of the parsed blocks and polls just after the next block is expected, polls as fast as
+// allowed while the crawler is behind and backs off while the head does not move.
+type Pacer struct {
+	opts PacerOptions
+	// blockTime is the moving average of the block time, zero until learned
+	blockTime time.Duration
+	// last is the last parsed block observed
+	last     uint64
+	lastTime time.Time
+	// backoff is the delay used while there is no new block
+	backoff time.Duration
+}
+
+func NewPacer(opts PacerOptions) *Pacer {
+	return &Pacer{opts: opts, blockTime: opts.BlockTime}
+}
+
+// Next return the delay before the next tick given the progress of the crawler after
+// the last tick and its error
+func (p *Pacer) Next(progress Progress, err error, now time.Time) time.Duration {
+	delay := p.next(progress, err, now)
+	pollInterval.With(p.opts.Chain).Set(delay.Seconds())
+	return delay
+}
+
+func (p *Pacer) next(progress Progress, err error, now time.Time) time.Duration {
+	newBlock := !progress.ParsedTime.IsZero() && progress.Parsed > p.last
+	if newBlock {
+		p.observe(progress.Parsed, progress.ParsedTime)
+	}
+
+	switch {
+	case err != nil || !newBlock:
+		// the head did not move or the node failed, wait longer every time
+		p.backoff = p.clamp(2 * p.backoff)
+		return p.backoff
+	case progress.Behind:
+		p.backoff = 0
+		return p.opts.Min
+	}
+
+	p.backoff = 0
+	if p.blockTime <= 0 {
+		return p.clamp(p.opts.Initial)
+	}
+	// the next block to parse is mined once the head moves by one block
+	lag := time.Duration(1)
+	if progress.Head > progress.Parsed {
+		lag += time.Duration(progress.Head - progress.Parsed)
+	}
+	expected := progress.ParsedTime.Add(lag * p.blockTime)
+	margin := time.Duration(float64(p.blockTime) * pollMarginRatio)
+	return p.clamp(expected.Add(margin).Sub(now))
+}
+
+// observe updates the block time with the timestamp of a parsed block
+func (p *Pacer) observe(number uint64, timestamp time.Time) {
+	if p.last > 0 && !timestamp.Before(p.lastTime) {
+		sample := timestamp.Sub(p.lastTime) / time.Duration(number-p.last)
+		if p.blockTime <= 0 {
+			p.blockTime = sample
+		} else {
+			p.blockTime += time.Duration(blockTimeWeight * float64(sample-p.blockTime))
+		}
+		estimatedBlockTime.With(p.opts.Chain).Set(p.blockTime.Seconds())
+	}
+	p.last, p.lastTime = number, timestamp
+}
+
+// BlockTime return the learned block time, the expected one until it is learned
+func (p *Pacer) BlockTime() time.Duration {
+	return p.blockTime
+}
+
+func (p *Pacer) clamp(d time.Duration) time.Duration {
+	if d < p.opts.Min {
+		return p.opts.Min
+	}
+	if d > p.opts.Max {
+		return p.opts.Max
+	}
+	return d
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pacerOpts = PacerOptions{Chain: "test", Min: time.Second, Max: time.Minute, Initial: 4 * time.Second}
+
+func TestPacer_learnsBlockTime(t *testing.T) {
+	pacer := NewPacer(pacerOpts)
+	start := time.Unix(1700000000, 0)
+
+	// the block time is unknown after the first block
+	progress := Progress{Head: 100, Parsed: 100, ParsedTime: start}
+	assert.Equal(t, 4*time.Second, pacer.Next(progress, nil, start.Add(time.Second)))
+
+	for i := 1; i <= 10; i++ {
+		parsedTime := start.Add(time.Duration(i) * 12 * time.Second)
+		progress = Progress{Head: 100 + uint64(i), Parsed: 100 + uint64(i), ParsedTime: parsedTime}
+		pacer.Next(progress, nil, parsedTime.Add(time.Second))
+	}
+	assert.Equal(t, 12*time.Second, pacer.BlockTime())
+
+	// the next block is expected 12s after the last one, polled 10% of the block time later
+	last := progress.ParsedTime.Add(12 * time.Second)
+	progress = Progress{Head: 111, Parsed: 111, ParsedTime: last}
+	assert.Equal(t, 11200*time.Millisecond, pacer.Next(progress, nil, last.Add(2*time.Second)))
+}
+
+func TestPacer_confirmations(t *testing.T) {
+	pacer := NewPacer(PacerOptions{Min: time.Second, Max: 5 * time.Minute, BlockTime: 12 * time.Second})
+	parsedTime := time.Unix(1700000000, 0)
+
+	// the block following the parsed one is confirmed once the head moves by one block
+	progress := Progress{Head: 105, Parsed: 100, ParsedTime: parsedTime}
+	assert.Equal(t, 6*12*time.Second+1200*time.Millisecond-5*time.Second, pacer.Next(progress, nil, parsedTime.Add(5*time.Second)))
+}
+
+func TestPacer_behind(t *testing.T) {
+	pacer := NewPacer(pacerOpts)
+	parsedTime := time.Unix(1700000000, 0)
+
+	progress := Progress{Head: 200, Parsed: 100, ParsedTime: parsedTime, Behind: true}
+	assert.Equal(t, time.Second, pacer.Next(progress, nil, time.Now()))
+}
+
+func TestPacer_backoff(t *testing.T) {
+	pacer := NewPacer(pacerOpts)
+	parsedTime := time.Unix(1700000000, 0)
+	progress := Progress{Head: 100, Parsed: 100, ParsedTime: parsedTime}
+	pacer.Next(progress, nil, parsedTime)
+
+	// the head does not move
+	var delays []time.Duration
+	for i := 0; i < 8; i++ {
+		delays = append(delays, pacer.Next(progress, nil, parsedTime))
+	}
+	assert.Equal(t, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		16 * time.Second, 32 * time.Second, time.Minute, time.Minute,
+	}, delays)
+
+	// a new block resets the backoff, a failure starts it again
+	progress = Progress{Head: 101, Parsed: 101, ParsedTime: parsedTime.Add(12 * time.Second)}
+	assert.Equal(t, 12*time.Second+1200*time.Millisecond, pacer.Next(progress, nil, progress.ParsedTime))
+	assert.Equal(t, time.Second, pacer.Next(progress, errors.New("connection refused"), progress.ParsedTime))
+	assert.Equal(t, 2*time.Second, pacer.Next(progress, errors.New("connection refused"), progress.ParsedTime))
+}
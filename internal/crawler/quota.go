@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// quotaUsage is the usage of the quota window of an endpoint
+type quotaUsage struct {
+	Window time.Time `json:"window"`
+	Used   float64   `json:"used"`
+}
+
+// QuotaFile is a QuotaStore keeping the usage of the endpoints in a JSON file
+type QuotaFile struct {
+	path string
+
+	mu    sync.Mutex
+	usage map[string]quotaUsage
+}
+
+// OpenQuotaFile loads the usage saved to path, a missing file holds no usage
+func OpenQuotaFile(path string) (*QuotaFile, error) {
+	f := &QuotaFile{path: path, usage: make(map[string]quotaUsage)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &f.usage); err != nil {
+		return nil, fmt.Errorf("parse quota file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *QuotaFile) LoadQuota(endpoint string, window time.Time) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	usage, ok := f.usage[endpoint]
+	if !ok || !usage.Window.Equal(window) {
+		return 0
+	}
+	return usage.Used
+}
+
+// SaveQuota replaces the file atomically, so that a crash never leaves a partial file
+func (f *QuotaFile) SaveQuota(endpoint string, window time.Time, used float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.usage[endpoint] = quotaUsage{Window: window, Used: used}
+	b, err := json.MarshalIndent(f.usage, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
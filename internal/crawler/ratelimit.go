@@ -0,0 +1,361 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+// Lane is the priority of an RPC call when the request budget of an endpoint runs low
+type Lane int
+
+const (
+	// LaneHead is the lane of the calls tracking the chain head, they are served first
+	// and can use the whole budget
+	LaneHead Lane = iota
+	// LaneBackfill is the lane of the calls fetching blocks, they wait while head calls
+	// are waiting and leave headReserveRatio of the burst to the head calls
+	LaneBackfill
+	numLanes
+)
+
+func (l Lane) String() string {
+	if l == LaneHead {
+		return "head"
+	}
+	return "backfill"
+}
+
+// headReserveRatio is the part of the burst of an endpoint only the head calls can use
+const headReserveRatio = 0.2
+
+// defaultMethodWeights are the relative costs of the methods, the blocks with their
+// transactions cost more than the head number. The other methods weigh 1.
+var defaultMethodWeights = map[method]float64{
+	blockNumberMethod:       1,
+	chainIDMethod:           1,
+	getBlockByNumberMethod:  5,
+	getBlockCountMethod:     1,
+	getBlockHashMethod:      1,
+	getBlockMethod:          5,
+	getRawTransactionMethod: 2,
+
+	// a block trace replays every transaction of the block
+	traceBlockByNumberMethod: 20,
+}
+
+// headMethods are always called in the head lane
+var headMethods = map[method]bool{
+	blockNumberMethod:   true,
+	chainIDMethod:       true,
+	getBlockCountMethod: true,
+}
+
+type laneKey struct{}
+
+// WithLane return a context whose RPC calls are made in the lane. By default the head
+// methods are called in LaneHead and the other ones in LaneBackfill.
+func WithLane(ctx context.Context, lane Lane) context.Context {
+	return context.WithValue(ctx, laneKey{}, lane)
+}
+
+func laneOf(ctx context.Context, m method) Lane {
+	if lane, ok := ctx.Value(laneKey{}).(Lane); ok {
+		return lane
+	}
+	if headMethods[m] {
+		return LaneHead
+	}
+	return LaneBackfill
+}
+
+// RateLimit is the request budget of an RPC endpoint, in units weighted by method
+type RateLimit struct {
+	// Rate is the number of units refilled per second and Burst the number of units
+	// which can be spent at once
+	Rate  float64
+	Burst float64
+	// Quota is the number of units allowed per QuotaPeriod, e.g. the monthly compute
+	// units of a provider plan, zero is unlimited
+	Quota       float64
+	QuotaPeriod time.Duration
+	// QuotaStart anchors the quota windows on the billing cycle of the provider, the Unix epoch
+	// by default. QuotaMonthly makes the windows calendar months starting on the day and the time
+	// of QuotaStart, in UTC, instead of QuotaPeriod long.
+	QuotaStart   time.Time
+	QuotaMonthly bool
+	// Weights override the default weights of the methods
+	Weights map[string]float64
+}
+
+// quotaWindow return the quota window holding now
+func (l RateLimit) quotaWindow(now time.Time) (start, end time.Time) {
+	anchor := l.QuotaStart.UTC()
+	if l.QuotaStart.IsZero() {
+		anchor = time.Unix(0, 0).UTC()
+	}
+
+	if l.QuotaMonthly {
+		now = now.UTC()
+		start = monthlyWindow(anchor, now.Year(), now.Month())
+		if start.After(now) {
+			start = monthlyWindow(anchor, now.Year(), now.Month()-1)
+		}
+		return start, monthlyWindow(anchor, start.Year(), start.Month()+1)
+	}
+
+	n := now.Sub(anchor) / l.QuotaPeriod
+	if now.Before(anchor) && now.Sub(anchor)%l.QuotaPeriod != 0 {
+		n--
+	}
+	start = anchor.Add(n * l.QuotaPeriod)
+	return start, start.Add(l.QuotaPeriod)
+}
+
+// monthlyWindow return the start of the window of the month, on the day of the anchor or
+// the last day of a shorter month
+func monthlyWindow(anchor time.Time, year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	day := anchor.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, anchor.Hour(), anchor.Minute(), anchor.Second(), 0, time.UTC)
+}
+
+// quotaSaveRatio is the part of the quota spent between two saves of the usage to the store,
+// the usage a crash loses
+const quotaSaveRatio = 0.01
+
+// QuotaStore keeps the units spent by the endpoints in their quota window across restarts
+type QuotaStore interface {
+	// LoadQuota return the units spent by the endpoint in the window starting at window, zero when unknown
+	LoadQuota(endpoint string, window time.Time) float64
+	SaveQuota(endpoint string, window time.Time, used float64) error
+}
+
+// RateLimiterOption configures a RateLimiter
+type RateLimiterOption func(*RateLimiter)
+
+// WithQuotaStore resumes the quota usage of the endpoints from the store and saves it there,
+// by default the usage is kept by the process only
+func WithQuotaStore(store QuotaStore) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.store = store
+	}
+}
+
+// RateLimiter throttles the RPC calls of the clients sharing it with a token bucket per
+// endpoint host. The calls to the endpoints without limit are not throttled.
+type RateLimiter struct {
+	buckets map[string]*bucket
+	store   QuotaStore
+}
+
+func NewRateLimiter(limits map[string]RateLimit, opts ...RateLimiterOption) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[string]*bucket, len(limits))}
+	for _, opt := range opts {
+		opt(l)
+	}
+	for host, limit := range limits {
+		b := newBucket(host, limit, time.Now)
+		b.store = l.store
+		l.buckets[host] = b
+	}
+	return l
+}
+
+// Flush saves the quota usage of the endpoints to the store
+func (l *RateLimiter) Flush(ctx context.Context) error {
+	if l == nil || l.store == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, b := range l.buckets {
+		b.mu.Lock()
+		if b.used != b.saved {
+			if err := b.save(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		b.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// wait blocks until the call can be sent to the node or ctx is done. It fails with
+// ErrQuotaExhausted when the quota of the node is spent.
+func (l *RateLimiter) wait(ctx context.Context, rpcNode string, m method) error {
+	if l == nil {
+		return nil
+	}
+	b, ok := l.buckets[endpointLabel(rpcNode)]
+	if !ok {
+		return nil
+	}
+	return b.wait(ctx, laneOf(ctx, m), b.weight(m))
+}
+
+// bucket is the token bucket and the quota of one endpoint
+type bucket struct {
+	endpoint string
+	limit    RateLimit
+	now      func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	// waiting is the number of calls waiting for tokens by lane
+	waiting [numLanes]int
+	// used is the number of units spent in the quota window from window to windowEnd,
+	// saved the number of units last saved to store
+	used      float64
+	window    time.Time
+	windowEnd time.Time
+	store     QuotaStore
+	saved     float64
+}
+
+func newBucket(endpoint string, limit RateLimit, now func() time.Time) *bucket {
+	b := &bucket{endpoint: endpoint, limit: limit, now: now, tokens: limit.Burst, last: now()}
+	rateLimitTokens.With(endpoint).Set(b.tokens)
+	if limit.Quota > 0 {
+		quotaRemaining.With(endpoint).Set(limit.Quota)
+	}
+	return b
+}
+
+func (b *bucket) weight(m method) float64 {
+	if w, ok := b.limit.Weights[string(m)]; ok {
+		return w
+	}
+	if w, ok := defaultMethodWeights[m]; ok {
+		return w
+	}
+	return 1
+}
+
+func (b *bucket) wait(ctx context.Context, lane Lane, weight float64) error {
+	start := b.now()
+	waiting := false
+	defer func() {
+		if waiting {
+			b.mu.Lock()
+			b.waiting[lane]--
+			b.mu.Unlock()
+		}
+	}()
+
+	for {
+		b.mu.Lock()
+		delay, err := b.take(lane, weight)
+		switch {
+		case delay > 0 && !waiting:
+			b.waiting[lane]++
+			waiting = true
+		case delay == 0 && waiting:
+			b.waiting[lane]--
+			waiting = false
+		}
+		b.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if delay == 0 {
+			rateLimitWait.With(b.endpoint, lane.String()).Observe(b.now().Sub(start).Seconds())
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take spends the weight of a call when the lane may, otherwise it return the delay
+// before the next attempt. The caller holds the lock.
+func (b *bucket) take(lane Lane, weight float64) (time.Duration, error) {
+	now := b.now()
+	b.refill(now)
+
+	if b.limit.Quota > 0 {
+		if b.window.IsZero() || !now.Before(b.windowEnd) {
+			b.window, b.windowEnd = b.limit.quotaWindow(now)
+			b.used = 0
+			if b.store != nil {
+				b.used = b.store.LoadQuota(b.endpoint, b.window)
+			}
+			b.saved = b.used
+		}
+		if b.used+weight > b.limit.Quota {
+			return 0, fmt.Errorf("%w: %s until %s", ErrQuotaExhausted, b.endpoint, b.windowEnd.Format(time.RFC3339))
+		}
+	}
+
+	reserve := 0.0
+	if lane != LaneHead {
+		reserve = b.limit.Burst * headReserveRatio
+	}
+	// a call heavier than the bucket would wait forever, it only draws what the bucket holds but its
+	// whole weight is spent from the quota
+	draw := weight
+	if draw > b.limit.Burst-reserve {
+		draw = b.limit.Burst - reserve
+	}
+
+	preempted := false
+	for higher := LaneHead; higher < lane; higher++ {
+		preempted = preempted || b.waiting[higher] > 0
+	}
+	if !preempted && b.tokens-draw >= reserve {
+		b.tokens -= draw
+		b.used += weight
+		rpcUnits.With(b.endpoint, lane.String()).Add(weight)
+		rateLimitTokens.With(b.endpoint).Set(b.tokens)
+		if b.limit.Quota > 0 {
+			quotaRemaining.With(b.endpoint).Set(b.limit.Quota - b.used)
+			if b.store != nil && b.used-b.saved >= b.limit.Quota*quotaSaveRatio {
+				if err := b.save(); err != nil {
+					slog.Warn("error saving quota usage", slog.String(logging.KeyEndpoint, b.endpoint), logging.Err(err))
+				}
+			}
+		}
+		return 0, nil
+	}
+
+	missing := draw + reserve - b.tokens
+	if missing <= 0 {
+		// preempted by a higher lane, retry once it had a chance to take its tokens
+		missing = draw
+	}
+	return time.Duration(missing / b.limit.Rate * float64(time.Second)), nil
+}
+
+// save records the usage of the quota window to the store, the caller holds the lock
+func (b *bucket) save() error {
+	if err := b.store.SaveQuota(b.endpoint, b.window, b.used); err != nil {
+		return fmt.Errorf("save quota of %s: %w", b.endpoint, err)
+	}
+	b.saved = b.used
+	return nil
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.limit.Rate
+		if b.tokens > b.limit.Burst {
+			b.tokens = b.limit.Burst
+		}
+	}
+	b.last = now
+}
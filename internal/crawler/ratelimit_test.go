@@ -0,0 +1,231 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a settable clock for the buckets
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestBucket_take(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	b := newBucket("node.example", RateLimit{Rate: 10, Burst: 10}, clock.Now)
+
+	// backfill calls leave the head reserve, 2 units, untouched
+	delay, err := b.take(LaneBackfill, b.weight(getBlockByNumberMethod))
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+	delay, err = b.take(LaneBackfill, b.weight(getBlockByNumberMethod))
+	assert.NoError(t, err)
+	assert.Equal(t, 200*time.Millisecond, delay)
+
+	// head calls can use the reserve
+	delay, err = b.take(LaneHead, b.weight(blockNumberMethod))
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+	assert.Equal(t, float64(4), b.tokens)
+
+	// the tokens are refilled over time
+	clock.now = clock.now.Add(300 * time.Millisecond)
+	delay, err = b.take(LaneBackfill, b.weight(getBlockByNumberMethod))
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+	assert.InDelta(t, float64(2), b.tokens, 1e-9)
+	assert.InDelta(t, float64(5+1+5), rpcUnits.With("node.example", "backfill").Value()+
+		rpcUnits.With("node.example", "head").Value(), 1e-9)
+}
+
+func TestBucket_take_preempted(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	b := newBucket("preempted.example", RateLimit{Rate: 10, Burst: 10}, clock.Now)
+
+	// a waiting head call goes before the backfill calls
+	b.waiting[LaneHead] = 1
+	delay, err := b.take(LaneBackfill, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 100*time.Millisecond, delay)
+	delay, err = b.take(LaneHead, 1)
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+}
+
+func TestBucket_take_weights(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	b := newBucket("weights.example", RateLimit{Rate: 1, Burst: 100, Weights: map[string]float64{"eth_getBlockByNumber": 20}}, clock.Now)
+
+	assert.Equal(t, float64(20), b.weight(getBlockByNumberMethod))
+	assert.Equal(t, float64(1), b.weight(blockNumberMethod))
+	assert.Equal(t, float64(1), b.weight("eth_getLogs"))
+}
+
+func TestBucket_take_heavierThanBurst(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	b := newBucket("heavy.example", RateLimit{Rate: 10, Burst: 10, Quota: 100, QuotaPeriod: time.Hour}, clock.Now)
+
+	// the call draws the whole bucket but the head reserve, its full weight is spent from the quota
+	delay, err := b.take(LaneBackfill, 25)
+	assert.NoError(t, err)
+	assert.Zero(t, delay)
+	assert.InDelta(t, float64(2), b.tokens, 1e-9)
+	assert.Equal(t, float64(25), b.used)
+	assert.Equal(t, float64(75), quotaRemaining.With("heavy.example").Value())
+	assert.Equal(t, float64(25), rpcUnits.With("heavy.example", "backfill").Value())
+
+	// the next one waits for the bucket to refill what it draws
+	delay, err = b.take(LaneBackfill, 25)
+	assert.NoError(t, err)
+	assert.Equal(t, 800*time.Millisecond, delay)
+
+	// the quota refuses a call heavier than what is left of it
+	clock.now = clock.now.Add(time.Second)
+	_, err = b.take(LaneBackfill, 80)
+	assert.ErrorIs(t, err, ErrQuotaExhausted)
+}
+
+func TestBucket_take_quota(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)}
+	b := newBucket("quota.example", RateLimit{Rate: 100, Burst: 100, Quota: 3, QuotaPeriod: time.Hour}, clock.Now)
+
+	for i := 0; i < 3; i++ {
+		_, err := b.take(LaneHead, 1)
+		assert.NoError(t, err)
+	}
+	_, err := b.take(LaneHead, 1)
+	assert.ErrorIs(t, err, ErrQuotaExhausted)
+	assert.ErrorContains(t, err, "until 2024-01-01T11:00:00Z")
+	assert.Equal(t, float64(0), quotaRemaining.With("quota.example").Value())
+
+	// the quota is renewed every period
+	clock.now = clock.now.Add(30 * time.Minute)
+	_, err = b.take(LaneHead, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), quotaRemaining.With("quota.example").Value())
+}
+
+func TestRateLimit_quotaWindow(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		assert.NoError(t, err)
+		return v
+	}
+	tests := []struct {
+		name       string
+		limit      RateLimit
+		now        string
+		start, end string
+	}{
+		{"epoch", RateLimit{QuotaPeriod: 24 * time.Hour}, "2024-03-10T15:00:00Z", "2024-03-10T00:00:00Z", "2024-03-11T00:00:00Z"},
+		{"anchored", RateLimit{QuotaPeriod: 720 * time.Hour, QuotaStart: at("2024-01-15T00:00:00Z")},
+			"2024-03-10T15:00:00Z", "2024-02-14T00:00:00Z", "2024-03-15T00:00:00Z"},
+		{"before anchor", RateLimit{QuotaPeriod: 24 * time.Hour, QuotaStart: at("2024-01-15T06:00:00Z")},
+			"2024-01-10T01:00:00Z", "2024-01-09T06:00:00Z", "2024-01-10T06:00:00Z"},
+		{"calendar month", RateLimit{QuotaMonthly: true}, "2024-03-10T15:00:00Z", "2024-03-01T00:00:00Z", "2024-04-01T00:00:00Z"},
+		{"billing day", RateLimit{QuotaMonthly: true, QuotaStart: at("2023-06-15T12:00:00+02:00")},
+			"2024-03-10T15:00:00Z", "2024-02-15T10:00:00Z", "2024-03-15T10:00:00Z"},
+		// a billing day missing from a month is its last day
+		{"short month", RateLimit{QuotaMonthly: true, QuotaStart: at("2024-01-31T00:00:00Z")},
+			"2024-03-10T15:00:00Z", "2024-02-29T00:00:00Z", "2024-03-31T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.limit.quotaWindow(at(tt.now))
+			assert.Equal(t, at(tt.start), start)
+			assert.Equal(t, at(tt.end), end)
+		})
+	}
+}
+
+func TestRateLimiter_quotaStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	limits := map[string]RateLimit{"store.example": {Rate: 1000, Burst: 1000, Quota: 1000, QuotaMonthly: true}}
+	store, err := OpenQuotaFile(path)
+	assert.NoError(t, err)
+	limiter := NewRateLimiter(limits, WithQuotaStore(store))
+
+	// the usage is saved every 1% of the quota
+	for i := 0; i < 12; i++ {
+		assert.NoError(t, limiter.wait(context.TODO(), "https://store.example/v3/key", blockNumberMethod))
+	}
+	store, err = OpenQuotaFile(path)
+	assert.NoError(t, err)
+	window, _ := limits["store.example"].quotaWindow(time.Now())
+	assert.Equal(t, float64(10), store.LoadQuota("store.example", window))
+
+	// and when the limiter is flushed, a restarted limiter resumes it
+	assert.NoError(t, limiter.Flush(context.TODO()))
+	store, err = OpenQuotaFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(12), store.LoadQuota("store.example", window))
+	assert.Zero(t, store.LoadQuota("store.example", window.AddDate(0, -1, 0)))
+
+	limiter = NewRateLimiter(limits, WithQuotaStore(store))
+	assert.NoError(t, limiter.wait(context.TODO(), "https://store.example/v3/key", blockNumberMethod))
+	assert.Equal(t, float64(987), quotaRemaining.With("store.example").Value())
+
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = OpenQuotaFile(path)
+	assert.ErrorContains(t, err, "parse quota file")
+}
+
+func TestLaneOf(t *testing.T) {
+	ctx := context.TODO()
+	assert.Equal(t, LaneHead, laneOf(ctx, blockNumberMethod))
+	assert.Equal(t, LaneBackfill, laneOf(ctx, getBlockByNumberMethod))
+	assert.Equal(t, LaneHead, laneOf(WithLane(ctx, LaneHead), getBlockByNumberMethod))
+}
+
+func TestEthereumClient_rateLimit(t *testing.T) {
+	var limitedCalls, otherCalls atomic.Int32
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limitedCalls.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer limited.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherCalls.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer other.Close()
+
+	cli := NewEthereumClient(limited.URL, other.URL)
+	cli.SetRateLimiter(NewRateLimiter(map[string]RateLimit{
+		endpointLabel(limited.URL): {Rate: 1000, Burst: 2, Quota: 2, QuotaPeriod: 24 * time.Hour},
+	}))
+
+	for i := 0; i < 3; i++ {
+		blockNumber, err := cli.BlockNumber(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(16), blockNumber)
+	}
+	// the calls fail over to the next node once the quota is spent
+	assert.Equal(t, int32(2), limitedCalls.Load())
+	assert.Equal(t, int32(1), otherCalls.Load())
+}
+
+func TestRateLimiter_wait_canceled(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{"slow.example": {Rate: 0.001, Burst: 1}})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.NoError(t, limiter.wait(ctx, "https://slow.example", blockNumberMethod))
+	assert.ErrorIs(t, limiter.wait(ctx, "https://slow.example", blockNumberMethod), context.DeadlineExceeded)
+	assert.Zero(t, limiter.buckets["slow.example"].waiting[LaneHead])
+	// the endpoints without limit are not throttled
+	assert.NoError(t, limiter.wait(ctx, "https://fast.example", blockNumberMethod))
+}
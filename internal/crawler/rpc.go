@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// rpcTransport sends JSON-RPC requests over HTTP, shared by the clients of every chain
+type rpcTransport struct {
+	// rpcNodes are tried in order, the next one is used when a request fails
+	rpcNodes []string
+	nextID   atomic.Uint64
+	// legacyErrors is set for nodes answering JSON-RPC errors with an HTTP 404 or 500 status,
+	// e.g. bitcoind before v28
+	legacyErrors bool
+	// limiter throttles the calls to the nodes, nil does not throttle
+	limiter *RateLimiter
+}
+
+// SetRateLimiter throttles the calls of the client, the limiter is shared by the clients
+// calling the same endpoints
+func (t *rpcTransport) SetRateLimiter(limiter *RateLimiter) {
+	t.limiter = limiter
+}
+
+func (t *rpcTransport) callMethod(ctx context.Context, result interface{}, method method, params interface{}) error {
+	if result != nil && reflect.TypeOf(result).Kind() != reflect.Ptr {
+		return fmt.Errorf("call result parameter must be pointer or nil interface: %v", result)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      t.nextID.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rpcNode := range t.rpcNodes {
+		if err = t.limiter.wait(ctx, rpcNode, method); err == nil {
+			err = t.callNode(ctx, rpcNode, method, body, result)
+		}
+		if err == nil || !shouldFailover(ctx, err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// shouldFailover reports whether a failed request can be retried on the next node
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var rpcErr *jsonError
+	if errors.As(err, &rpcErr) || errors.Is(err, ErrNoResult) {
+		return false
+	}
+
+	// quota, transport and decoding errors
+	return true
+}
+
+// callNode sends the JSON-RPC request to one node and decodes its result
+func (t *rpcTransport) callNode(ctx context.Context, rpcNode string, method method, body []byte, result interface{}) (err error) {
+	start := time.Now()
+	defer func() {
+		observeRPC(ctx, rpcNode, method, time.Since(start), err)
+	}()
+
+	respBody, err := t.doRequest(ctx, rpcNode, body)
+	if err != nil {
+		if rpcErr := t.legacyError(err); rpcErr != nil {
+			return rpcErr
+		}
+		return err
+	}
+	defer respBody.Close()
+
+	var respmsg jsonrpcMessage
+	if err = json.NewDecoder(respBody).Decode(&respmsg); err != nil {
+		return err
+	}
+	if respmsg.Error != nil {
+		return respmsg.Error
+	}
+	if len(respmsg.Result) == 0 {
+		return ErrNoResult
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(respmsg.Result, result)
+}
+
+// legacyError return the JSON-RPC error carried by the HTTP error of a legacy node, nil if none
+func (t *rpcTransport) legacyError(err error) *jsonError {
+	var httpErr HTTPError
+	if !t.legacyErrors || !errors.As(err, &httpErr) {
+		return nil
+	}
+	if httpErr.StatusCode != http.StatusNotFound && httpErr.StatusCode != http.StatusInternalServerError {
+		return nil
+	}
+
+	var respmsg jsonrpcMessage
+	if json.Unmarshal(httpErr.Body, &respmsg) != nil {
+		return nil
+	}
+	return respmsg.Error
+}
+
+func (t *rpcTransport) doRequest(ctx context.Context, rpcNode string, body []byte) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rpcNode, io.NopCloser(bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+
+	// do request
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		var body []byte
+		if _, err := buf.ReadFrom(resp.Body); err == nil {
+			body = buf.Bytes()
+		}
+
+		return nil, HTTPError{
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Body:       body,
+		}
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,297 @@
+// Package ethtest is an in-process fake Ethereum JSON-RPC node for integration tests. The chain
+// is scripted by the test: blocks are mined with the injected transactions, reorganized, and
+// the calls can be delayed or answered with errors and rate-limit responses.
+package ethtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Transaction is a transaction injected in the fake chain, the addresses are hex strings.
+// An empty To deploys a contract.
+type Transaction struct {
+	From  string
+	To    string
+	Value uint64
+	Input string
+	// Gas is the gas used by the transaction and its gas limit, 21000 by default
+	Gas uint64
+	// Failed reverts the transaction, its receipt has status 0x0 and no logs
+	Failed bool
+	Logs   []Log
+	// Creations are the contracts created by the internal calls, served by debug_traceBlockByNumber
+	Creations []Creation
+}
+
+// Creation is a contract created by an internal call of a transaction
+type Creation struct {
+	// Creator is the contract executing the opcode, Address the created contract
+	Creator string
+	Address string
+	// Opcode is CREATE or CREATE2, CREATE by default
+	Opcode string
+	// Reverted reverts the call creating the contract, the transaction goes on
+	Reverted bool
+}
+
+// Log is an event emitted by a transaction
+type Log struct {
+	Address string
+	Topics  []string
+	Data    string
+}
+
+// Fault is an error answered instead of the result of a call. A zero Code answers the HTTP
+// status with a plain text body, otherwise the JSON-RPC error is answered with the status.
+type Fault struct {
+	Status  int
+	Code    int
+	Message string
+}
+
+var (
+	// RateLimited is the answer of a provider when the request rate is exceeded
+	RateLimited = Fault{Status: 429, Code: -32005, Message: "rate limit exceeded"}
+	// Unavailable is the answer of an overloaded node or proxy
+	Unavailable = Fault{Status: 503, Message: "service unavailable"}
+	// InternalError is a JSON-RPC error of a healthy node
+	InternalError = Fault{Status: 200, Code: -32603, Message: "internal error"}
+)
+
+// Option tunes the fake node
+type Option func(*Node)
+
+// WithChainID sets the chain ID returned by eth_chainId, 1 by default
+func WithChainID(chainID uint64) Option {
+	return func(n *Node) { n.chainID = chainID }
+}
+
+// WithBlockTime sets the interval between the timestamps of two blocks, 12s by default
+func WithBlockTime(d time.Duration) Option {
+	return func(n *Node) { n.blockTime = d }
+}
+
+// WithGenesisTime sets the timestamp of the genesis block
+func WithGenesisTime(t time.Time) Option {
+	return func(n *Node) { n.genesisTime = t }
+}
+
+// Node is the fake node, its genesis block 0 is mined when it starts
+type Node struct {
+	URL    string
+	server *httptest.Server
+
+	chainID     uint64
+	blockTime   time.Duration
+	genesisTime time.Time
+
+	mu      sync.Mutex
+	blocks  []*block
+	pending []Transaction
+	nonces  map[string]uint64
+	// fork is incremented by every reorg, so that the new blocks get new hashes
+	fork    int
+	latency time.Duration
+	faults  map[string][]Fault
+	hooks   map[string][]func()
+	calls   map[string]int
+}
+
+type block struct {
+	number     uint64
+	hash       string
+	parentHash string
+	timestamp  uint64
+	txns       []*transaction
+}
+
+type transaction struct {
+	Transaction
+	hash  string
+	nonce uint64
+	index int
+}
+
+// NewNode starts a fake node serving JSON-RPC over HTTP until the test ends
+func NewNode(t testing.TB, opts ...Option) *Node {
+	n := &Node{
+		chainID:     1,
+		blockTime:   12 * time.Second,
+		genesisTime: time.Unix(1700000000, 0),
+		nonces:      make(map[string]uint64),
+		faults:      make(map[string][]Fault),
+		hooks:       make(map[string][]func()),
+		calls:       make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	n.mine()
+
+	n.server = httptest.NewServer(n)
+	n.URL = n.server.URL
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+// Head return the number of the latest block
+func (n *Node) Head() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return uint64(len(n.blocks) - 1)
+}
+
+// BlockHash return the hash of the block of the canonical chain
+func (n *Node) BlockHash(number uint64) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if number >= uint64(len(n.blocks)) {
+		return ""
+	}
+	return n.blocks[number].hash
+}
+
+// AddTransactions adds the transactions to the pool, they are included in the next mined block.
+// It return their hashes.
+func (n *Node) AddTransactions(txns ...Transaction) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	hashes := make([]string, len(txns))
+	for i, tx := range txns {
+		tx.From = strings.ToLower(tx.From)
+		tx.To = strings.ToLower(tx.To)
+		n.pending = append(n.pending, tx)
+		hashes[i] = n.txHash(tx, n.nonces[tx.From]+uint64(countFrom(n.pending[:len(n.pending)-1], tx.From)))
+	}
+	return hashes
+}
+
+func countFrom(txns []Transaction, from string) int {
+	count := 0
+	for _, tx := range txns {
+		if tx.From == from {
+			count++
+		}
+	}
+	return count
+}
+
+// Mine mines count blocks, the first one includes the pending transactions. It return the new head.
+func (n *Node) Mine(count int) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		n.mine()
+	}
+	return uint64(len(n.blocks) - 1)
+}
+
+// Reorg replaces the last depth blocks: they are removed from the chain and their transactions
+// return to the pool, then depth blocks of the new fork are mined, the first one including the
+// transactions. The replaced blocks keep their numbers but get new hashes.
+func (n *Node) Reorg(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if depth >= len(n.blocks) {
+		depth = len(n.blocks) - 1
+	}
+	var reorged []Transaction
+	for _, b := range n.blocks[len(n.blocks)-depth:] {
+		for _, tx := range b.txns {
+			reorged = append(reorged, tx.Transaction)
+			n.nonces[tx.From]--
+		}
+	}
+	n.blocks = n.blocks[:len(n.blocks)-depth]
+	n.pending = append(reorged, n.pending...)
+	n.fork++
+
+	for i := 0; i < depth; i++ {
+		n.mine()
+	}
+}
+
+// SetLatency delays every answer
+func (n *Node) SetLatency(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.latency = d
+}
+
+// FailNext answers the next times calls of the method, every method when empty, with the fault
+func (n *Node) FailNext(method string, times int, fault Fault) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for i := 0; i < times; i++ {
+		n.faults[method] = append(n.faults[method], fault)
+	}
+}
+
+// OnCall runs fn before answering every call of the method, every method when empty, e.g. to
+// move the head while a block is fetched. fn may script the node.
+func (n *Node) OnCall(method string, fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.hooks[method] = append(n.hooks[method], fn)
+}
+
+// Calls return the number of calls of the method answered, faults included
+func (n *Node) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.calls[method]
+}
+
+// mine appends a block with the pending transactions, the caller holds the lock
+func (n *Node) mine() {
+	number := uint64(len(n.blocks))
+	b := &block{
+		number:    number,
+		timestamp: uint64(n.genesisTime.Add(time.Duration(number) * n.blockTime).Unix()),
+	}
+	if number > 0 {
+		b.parentHash = n.blocks[number-1].hash
+	} else {
+		b.parentHash = "0x" + strings.Repeat("0", 64)
+	}
+
+	for i, tx := range n.pending {
+		nonce := n.nonces[tx.From]
+		n.nonces[tx.From]++
+		b.txns = append(b.txns, &transaction{Transaction: tx, hash: n.txHash(tx, nonce), nonce: nonce, index: i})
+	}
+	n.pending = nil
+
+	parts := []string{b.parentHash, fmt.Sprint(number), fmt.Sprint(n.fork)}
+	for _, tx := range b.txns {
+		parts = append(parts, tx.hash)
+	}
+	b.hash = hash(parts...)
+	n.blocks = append(n.blocks, b)
+}
+
+// txHash is stable across reorgs, a transaction is identified by its sender and nonce
+func (n *Node) txHash(tx Transaction, nonce uint64) string {
+	return hash(fmt.Sprint(n.chainID), tx.From, fmt.Sprint(nonce), tx.To, fmt.Sprint(tx.Value), tx.Input)
+}
+
+func hash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	return "0x" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,219 @@
+package ethtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alice    = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	bob      = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	token    = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	transfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+type response struct {
+	status int
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func call(t *testing.T, node *Node, method string, params ...interface{}) response {
+	t.Helper()
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	resp, err := http.Post(node.URL, "application/json", bytes.NewReader(body))
+	if !assert.NoError(t, err) {
+		return response{}
+	}
+	defer resp.Body.Close()
+
+	r := response{status: resp.StatusCode}
+	_ = json.NewDecoder(resp.Body).Decode(&r)
+	return r
+}
+
+func result[T any](t *testing.T, r response) T {
+	t.Helper()
+	var v T
+	assert.Nil(t, r.Error)
+	assert.NoError(t, json.Unmarshal(r.Result, &v))
+	return v
+}
+
+type jsonBlock struct {
+	Number       string            `json:"number"`
+	Hash         string            `json:"hash"`
+	ParentHash   string            `json:"parentHash"`
+	Timestamp    string            `json:"timestamp"`
+	Transactions []json.RawMessage `json:"transactions"`
+}
+
+func TestNode_chain(t *testing.T) {
+	node := NewNode(t, WithChainID(56), WithBlockTime(3*time.Second))
+	assert.Equal(t, "0x38", result[string](t, call(t, node, "eth_chainId")))
+	assert.Equal(t, "0x0", result[string](t, call(t, node, "eth_blockNumber")))
+
+	hashes := node.AddTransactions(Transaction{From: alice, To: bob, Value: 1000}, Transaction{From: alice, To: bob, Value: 2000})
+	assert.Equal(t, uint64(3), node.Mine(3))
+
+	var parent string
+	for number := uint64(0); number <= 3; number++ {
+		b := result[jsonBlock](t, call(t, node, "eth_getBlockByNumber", hexUint(number), false))
+		assert.Equal(t, hexUint(number), b.Number)
+		assert.Equal(t, node.BlockHash(number), b.Hash)
+		assert.Equal(t, hexUint(1700000000+3*number), b.Timestamp)
+		if number > 0 {
+			assert.Equal(t, parent, b.ParentHash)
+		}
+		parent = b.Hash
+	}
+
+	b := result[jsonBlock](t, call(t, node, "eth_getBlockByNumber", "0x1", true))
+	if assert.Len(t, b.Transactions, 2) {
+		var tx map[string]string
+		assert.NoError(t, json.Unmarshal(b.Transactions[1], &tx))
+		assert.Equal(t, hashes[1], tx["hash"])
+		assert.Equal(t, "0x7d0", tx["value"])
+		assert.Equal(t, "0x1", tx["nonce"])
+	}
+	latest := result[jsonBlock](t, call(t, node, "eth_getBlockByNumber", "latest", false))
+	assert.Equal(t, "0x3", latest.Number)
+	assert.Equal(t, "null", string(call(t, node, "eth_getBlockByNumber", "0x4", false).Result))
+}
+
+func TestNode_reorg(t *testing.T) {
+	node := NewNode(t)
+	node.Mine(2)
+	hashes := node.AddTransactions(Transaction{From: alice, To: bob, Value: 1})
+	node.Mine(3)
+	before := []string{node.BlockHash(2), node.BlockHash(3), node.BlockHash(4), node.BlockHash(5)}
+
+	node.Reorg(3)
+	assert.Equal(t, uint64(5), node.Head())
+	assert.Equal(t, before[0], node.BlockHash(2))
+	for i, number := range []uint64{3, 4, 5} {
+		assert.NotEqual(t, before[i+1], node.BlockHash(number))
+	}
+	b := result[jsonBlock](t, call(t, node, "eth_getBlockByNumber", "0x3", false))
+	assert.Equal(t, node.BlockHash(2), b.ParentHash)
+	// the transaction is included again in the first block of the new fork, with the same hash
+	assert.Equal(t, []json.RawMessage{json.RawMessage(fmt.Sprintf("%q", hashes[0]))}, b.Transactions)
+	// the replaced blocks are gone
+	assert.Equal(t, "null", string(call(t, node, "eth_getBlockByHash", before[1], false).Result))
+}
+
+func TestNode_receiptsAndLogs(t *testing.T) {
+	node := NewNode(t)
+	topics := []string{transfer, "0x000000000000000000000000" + alice[2:], "0x000000000000000000000000" + bob[2:]}
+	hashes := node.AddTransactions(
+		Transaction{From: alice, To: token, Gas: 51_000, Logs: []Log{{Address: token, Topics: topics, Data: "0x01"}}},
+		Transaction{From: bob, To: token, Gas: 23_000, Failed: true, Logs: []Log{{Address: token, Topics: topics}}},
+		Transaction{From: bob, Input: "0x6080"},
+	)
+	node.Mine(1)
+
+	receipt := result[map[string]interface{}](t, call(t, node, "eth_getTransactionReceipt", hashes[0]))
+	assert.Equal(t, "0x1", receipt["status"])
+	assert.Len(t, receipt["logs"], 1)
+	receipt = result[map[string]interface{}](t, call(t, node, "eth_getTransactionReceipt", hashes[1]))
+	assert.Equal(t, "0x0", receipt["status"])
+	assert.Empty(t, receipt["logs"])
+	// the gas used adds up in the block
+	assert.Equal(t, "0x59d8", receipt["gasUsed"])
+	assert.Equal(t, "0x12110", receipt["cumulativeGasUsed"])
+	receipt = result[map[string]interface{}](t, call(t, node, "eth_getTransactionReceipt", hashes[2]))
+	assert.Nil(t, receipt["to"])
+	assert.Len(t, receipt["contractAddress"], 42)
+
+	logs := result[[]map[string]interface{}](t, call(t, node, "eth_getLogs", map[string]interface{}{
+		"fromBlock": "0x0", "toBlock": "latest", "address": token, "topics": []interface{}{transfer, nil, []string{bob}},
+	}))
+	assert.Len(t, logs, 0)
+	logs = result[[]map[string]interface{}](t, call(t, node, "eth_getLogs", map[string]interface{}{
+		"blockHash": node.BlockHash(1), "topics": []interface{}{transfer, nil, []string{topics[2]}},
+	}))
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, hashes[0], logs[0]["transactionHash"])
+		assert.Equal(t, "0x0", logs[0]["logIndex"])
+	}
+}
+
+func TestNode_traces(t *testing.T) {
+	node := NewNode(t)
+	const factory, pair = "0x5c69bee701ef814a2b6a3edd4b1652cb9cc5aa6f", "0xb4e16d0168e52d35cacd2c6185b44281ec28c9dc"
+	hashes := node.AddTransactions(
+		Transaction{From: alice, To: factory, Input: "0xc9c65396", Creations: []Creation{
+			{Creator: factory, Address: pair, Opcode: "CREATE2"},
+			{Creator: factory, Address: token, Reverted: true},
+		}},
+		Transaction{From: bob, Input: "0x6080"},
+	)
+	node.Mine(1)
+
+	type frame struct {
+		Type  string  `json:"type"`
+		From  string  `json:"from"`
+		To    string  `json:"to"`
+		Error string  `json:"error"`
+		Calls []frame `json:"calls"`
+	}
+	traces := result[[]struct {
+		TxHash string `json:"txHash"`
+		Result frame  `json:"result"`
+	}](t, call(t, node, "debug_traceBlockByNumber", "0x1", map[string]string{"tracer": "callTracer"}))
+	if assert.Len(t, traces, 2) {
+		assert.Equal(t, hashes[0], traces[0].TxHash)
+		assert.Equal(t, []frame{
+			{Type: "CREATE2", From: factory, To: pair},
+			{Type: "CREATE", From: factory, To: token, Error: "execution reverted"},
+		}, traces[0].Result.Calls)
+		// a deploying transaction is a CREATE frame of its contract
+		receipt := result[map[string]interface{}](t, call(t, node, "eth_getTransactionReceipt", hashes[1]))
+		assert.Equal(t, "CREATE", traces[1].Result.Type)
+		assert.Equal(t, receipt["contractAddress"], traces[1].Result.To)
+	}
+
+	r := call(t, node, "debug_traceBlockByNumber", "0x1", map[string]string{"tracer": "prestateTracer"})
+	assert.Equal(t, -32602, r.Error.Code)
+	r = call(t, node, "debug_traceBlockByNumber", "0x9", map[string]string{"tracer": "callTracer"})
+	assert.Equal(t, -32000, r.Error.Code)
+}
+
+func TestNode_faults(t *testing.T) {
+	node := NewNode(t)
+	node.FailNext("eth_blockNumber", 1, RateLimited)
+	node.FailNext("", 1, Unavailable)
+
+	r := call(t, node, "eth_blockNumber")
+	assert.Equal(t, http.StatusTooManyRequests, r.status)
+	assert.Equal(t, -32005, r.Error.Code)
+	r = call(t, node, "eth_chainId")
+	assert.Equal(t, http.StatusServiceUnavailable, r.status)
+	r = call(t, node, "eth_blockNumber")
+	assert.Equal(t, http.StatusOK, r.status)
+	assert.Equal(t, 2, node.Calls("eth_blockNumber"))
+
+	r = call(t, node, "eth_sendRawTransaction", "0x00")
+	assert.Equal(t, -32601, r.Error.Code)
+}
+
+func TestNode_latencyAndHooks(t *testing.T) {
+	node := NewNode(t)
+	node.SetLatency(20 * time.Millisecond)
+	// the head moves while the block is fetched
+	node.OnCall("eth_getBlockByNumber", func() { node.Mine(1) })
+
+	start := time.Now()
+	b := result[jsonBlock](t, call(t, node, "eth_getBlockByNumber", "latest", false))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, "0x1", b.Number)
+}
@@ -0,0 +1,502 @@
+package ethtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errNotSupported is answered to the methods the node does not serve
+var errNotSupported = &rpcError{Code: -32601, Message: "the method does not exist/is not available"}
+
+func invalidParams(format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: -32602, Message: fmt.Sprintf(format, args...)}
+}
+
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	n.calls[req.Method]++
+	latency := n.latency
+	hooks := append(append([]func(){}, n.hooks[""]...), n.hooks[req.Method]...)
+	fault, faulty := n.nextFault(req.Method)
+	n.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for _, hook := range hooks {
+		hook()
+	}
+
+	if faulty {
+		writeFault(w, req.ID, fault)
+		return
+	}
+
+	n.mu.Lock()
+	result, rpcErr := n.call(req.Method, req.Params)
+	n.mu.Unlock()
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// nextFault pops the fault of the method, the caller holds the lock
+func (n *Node) nextFault(method string) (Fault, bool) {
+	for _, key := range []string{method, ""} {
+		if faults := n.faults[key]; len(faults) > 0 {
+			n.faults[key] = faults[1:]
+			return faults[0], true
+		}
+	}
+	return Fault{}, false
+}
+
+func writeFault(w http.ResponseWriter, id json.RawMessage, fault Fault) {
+	status := fault.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if fault.Code == 0 {
+		http.Error(w, fault.Message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   rpcError{Code: fault.Code, Message: fault.Message},
+	})
+}
+
+// call answers a JSON-RPC method, the caller holds the lock
+func (n *Node) call(method string, params []json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "eth_chainId":
+		return hexUint(n.chainID), nil
+	case "eth_blockNumber":
+		return hexUint(uint64(len(n.blocks) - 1)), nil
+	case "eth_getBlockByNumber":
+		var tag string
+		var full bool
+		if err := decodeParams(params, &tag, &full); err != nil {
+			return nil, err
+		}
+		number, err := n.blockNumber(tag)
+		if err != nil {
+			return nil, err
+		}
+		if number >= uint64(len(n.blocks)) {
+			return nil, nil
+		}
+		return n.blocks[number].toJSON(full), nil
+	case "eth_getBlockByHash":
+		var hash string
+		var full bool
+		if err := decodeParams(params, &hash, &full); err != nil {
+			return nil, err
+		}
+		if b := n.blockByHash(hash); b != nil {
+			return b.toJSON(full), nil
+		}
+		return nil, nil
+	case "eth_getTransactionByHash":
+		var hash string
+		if err := decodeParams(params, &hash); err != nil {
+			return nil, err
+		}
+		if b, tx := n.transaction(hash); tx != nil {
+			return tx.toJSON(b), nil
+		}
+		return nil, nil
+	case "eth_getTransactionReceipt":
+		var hash string
+		if err := decodeParams(params, &hash); err != nil {
+			return nil, err
+		}
+		if b, tx := n.transaction(hash); tx != nil {
+			return tx.receipt(b), nil
+		}
+		return nil, nil
+	case "debug_traceBlockByNumber":
+		var tag string
+		var config struct {
+			Tracer string `json:"tracer"`
+		}
+		if err := decodeParams(params, &tag, &config); err != nil {
+			return nil, err
+		}
+		if config.Tracer != "callTracer" {
+			return nil, invalidParams("unsupported tracer %q", config.Tracer)
+		}
+		number, err := n.blockNumber(tag)
+		if err != nil {
+			return nil, err
+		}
+		if number >= uint64(len(n.blocks)) {
+			return nil, &rpcError{Code: -32000, Message: fmt.Sprintf("block #%d not found", number)}
+		}
+		traces := make([]map[string]interface{}, len(n.blocks[number].txns))
+		for i, tx := range n.blocks[number].txns {
+			traces[i] = map[string]interface{}{"txHash": tx.hash, "result": tx.trace()}
+		}
+		return traces, nil
+	case "eth_getLogs":
+		var filter logFilter
+		if err := decodeParams(params, &filter); err != nil {
+			return nil, err
+		}
+		return n.logs(filter)
+	default:
+		return nil, errNotSupported
+	}
+}
+
+func decodeParams(params []json.RawMessage, values ...interface{}) *rpcError {
+	if len(params) < len(values) {
+		return invalidParams("missing value for required argument %d", len(params))
+	}
+	for i, v := range values {
+		if err := json.Unmarshal(params[i], v); err != nil {
+			return invalidParams("invalid argument %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// blockNumber resolves a block number or tag, the caller holds the lock
+func (n *Node) blockNumber(tag string) (uint64, *rpcError) {
+	switch tag {
+	case "latest", "safe", "finalized", "pending", "":
+		return uint64(len(n.blocks) - 1), nil
+	case "earliest":
+		return 0, nil
+	}
+	number, err := strconv.ParseUint(strings.TrimPrefix(tag, "0x"), 16, 64)
+	if err != nil || !strings.HasPrefix(tag, "0x") {
+		return 0, invalidParams("invalid block number %q", tag)
+	}
+	return number, nil
+}
+
+func (n *Node) blockByHash(hash string) *block {
+	for _, b := range n.blocks {
+		if strings.EqualFold(b.hash, hash) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (n *Node) transaction(hash string) (*block, *transaction) {
+	for _, b := range n.blocks {
+		for _, tx := range b.txns {
+			if strings.EqualFold(tx.hash, hash) {
+				return b, tx
+			}
+		}
+	}
+	return nil, nil
+}
+
+// logFilter is the filter of eth_getLogs, Address is a string or a list and every topic
+// is null, a string or a list of alternatives
+type logFilter struct {
+	FromBlock string            `json:"fromBlock"`
+	ToBlock   string            `json:"toBlock"`
+	BlockHash string            `json:"blockHash"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+func (n *Node) logs(filter logFilter) (interface{}, *rpcError) {
+	addresses, err := stringOrList(filter.Address)
+	if err != nil {
+		return nil, invalidParams("invalid address: %v", err)
+	}
+	topics := make([][]string, len(filter.Topics))
+	for i, topic := range filter.Topics {
+		if topics[i], err = stringOrList(topic); err != nil {
+			return nil, invalidParams("invalid topic %d: %v", i, err)
+		}
+	}
+
+	var blocks []*block
+	if filter.BlockHash != "" {
+		if b := n.blockByHash(filter.BlockHash); b != nil {
+			blocks = append(blocks, b)
+		}
+	} else {
+		from, rpcErr := n.blockNumber(filter.FromBlock)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		to, rpcErr := n.blockNumber(filter.ToBlock)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		for number := from; number <= to && number < uint64(len(n.blocks)); number++ {
+			blocks = append(blocks, n.blocks[number])
+		}
+	}
+
+	logs := []map[string]interface{}{}
+	for _, b := range blocks {
+		logIndex := 0
+		for _, tx := range b.txns {
+			for _, l := range tx.logs() {
+				if matchLog(l, addresses, topics) {
+					logs = append(logs, l.toJSON(b, tx, logIndex))
+				}
+				logIndex++
+			}
+		}
+	}
+	return logs, nil
+}
+
+func stringOrList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return []string{one}, nil
+	}
+	var list []string
+	err := json.Unmarshal(raw, &list)
+	return list, err
+}
+
+func matchLog(l Log, addresses []string, topics [][]string) bool {
+	if len(addresses) > 0 && !containsFold(addresses, l.Address) {
+		return false
+	}
+	for i, alternatives := range topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		if i >= len(l.Topics) || !containsFold(alternatives, l.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func hexUint(v uint64) string {
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+func (b *block) toJSON(full bool) map[string]interface{} {
+	txns := make([]interface{}, len(b.txns))
+	for i, tx := range b.txns {
+		if full {
+			txns[i] = tx.toJSON(b)
+		} else {
+			txns[i] = tx.hash
+		}
+	}
+	return map[string]interface{}{
+		"number":       hexUint(b.number),
+		"hash":         b.hash,
+		"parentHash":   b.parentHash,
+		"timestamp":    hexUint(b.timestamp),
+		"miner":        "0x" + strings.Repeat("0", 40),
+		"gasLimit":     hexUint(30_000_000),
+		"gasUsed":      hexUint(21_000 * uint64(len(b.txns))),
+		"transactions": txns,
+		"uncles":       []string{},
+	}
+}
+
+func (tx *transaction) toJSON(b *block) map[string]interface{} {
+	var to interface{}
+	if tx.To != "" {
+		to = tx.To
+	}
+	input := tx.Input
+	if input == "" {
+		input = "0x"
+	}
+	return map[string]interface{}{
+		"blockHash":        b.hash,
+		"blockNumber":      hexUint(b.number),
+		"hash":             tx.hash,
+		"from":             tx.From,
+		"to":               to,
+		"value":            hexUint(tx.Value),
+		"gas":              hexUint(tx.gas()),
+		"gasPrice":         hexUint(20_000_000_000),
+		"input":            input,
+		"nonce":            hexUint(tx.nonce),
+		"transactionIndex": hexUint(uint64(tx.index)),
+		"type":             "0x0",
+	}
+}
+
+// gas return the gas used by the transaction
+func (tx *transaction) gas() uint64 {
+	if tx.Gas == 0 {
+		return 21_000
+	}
+	return tx.Gas
+}
+
+// logs return the logs of the transaction, a failed transaction emits none
+func (tx *transaction) logs() []Log {
+	if tx.Failed {
+		return nil
+	}
+	return tx.Logs
+}
+
+// contractAddress is the address of the contract deployed by the transaction, empty if none
+func (tx *transaction) contractAddress() string {
+	if tx.To != "" {
+		return ""
+	}
+	return "0x" + strings.TrimPrefix(hash(tx.From, fmt.Sprint(tx.nonce)), "0x")[:40]
+}
+
+// trace is the call frame of the transaction by the callTracer, the creations are calls of the top frame
+func (tx *transaction) trace() map[string]interface{} {
+	frame := map[string]interface{}{
+		"type":  "CALL",
+		"from":  tx.From,
+		"to":    tx.To,
+		"value": hexUint(tx.Value),
+		"input": tx.Input,
+	}
+	if tx.To == "" {
+		frame["type"] = "CREATE"
+		frame["to"] = tx.contractAddress()
+	}
+	if tx.Failed {
+		frame["error"] = "execution reverted"
+	}
+
+	calls := make([]map[string]interface{}, len(tx.Creations))
+	for i, creation := range tx.Creations {
+		opcode := creation.Opcode
+		if opcode == "" {
+			opcode = "CREATE"
+		}
+		calls[i] = map[string]interface{}{
+			"type":  opcode,
+			"from":  strings.ToLower(creation.Creator),
+			"to":    strings.ToLower(creation.Address),
+			"value": "0x0",
+		}
+		if creation.Reverted {
+			calls[i]["error"] = "execution reverted"
+		}
+	}
+	if len(calls) > 0 {
+		frame["calls"] = calls
+	}
+	return frame
+}
+
+func (tx *transaction) receipt(b *block) map[string]interface{} {
+	status := "0x1"
+	if tx.Failed {
+		status = "0x0"
+	}
+	var to, contract interface{}
+	if tx.To != "" {
+		to = tx.To
+	} else {
+		contract = tx.contractAddress()
+	}
+
+	// the log index is the position of the log in the block
+	logIndex, cumulativeGas := 0, tx.gas()
+	for _, other := range b.txns[:tx.index] {
+		logIndex += len(other.logs())
+		cumulativeGas += other.gas()
+	}
+	logs := []map[string]interface{}{}
+	for i, l := range tx.logs() {
+		logs = append(logs, l.toJSON(b, tx, logIndex+i))
+	}
+
+	return map[string]interface{}{
+		"blockHash":         b.hash,
+		"blockNumber":       hexUint(b.number),
+		"transactionHash":   tx.hash,
+		"transactionIndex":  hexUint(uint64(tx.index)),
+		"from":              tx.From,
+		"to":                to,
+		"contractAddress":   contract,
+		"cumulativeGasUsed": hexUint(cumulativeGas),
+		"gasUsed":           hexUint(tx.gas()),
+		"effectiveGasPrice": hexUint(20_000_000_000),
+		"logs":              logs,
+		"logsBloom":         "0x" + strings.Repeat("0", 512),
+		"status":            status,
+		"type":              "0x0",
+	}
+}
+
+func (l Log) toJSON(b *block, tx *transaction, logIndex int) map[string]interface{} {
+	topics := l.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+	data := l.Data
+	if data == "" {
+		data = "0x"
+	}
+	return map[string]interface{}{
+		"address":          strings.ToLower(l.Address),
+		"topics":           topics,
+		"data":             data,
+		"blockNumber":      hexUint(b.number),
+		"blockHash":        b.hash,
+		"transactionHash":  tx.hash,
+		"transactionIndex": hexUint(uint64(tx.index)),
+		"logIndex":         hexUint(uint64(logIndex)),
+		"removed":          false,
+	}
+}
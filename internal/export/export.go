@@ -0,0 +1,157 @@
+// Package export writes the transaction history of an address as a CSV or NDJSON statement,
+// one row per transaction as they are read
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Format is the file format of a statement
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// ParseFormat return the format of its name, csv or ndjson
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatCSV, FormatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, one of: csv, ndjson", name)
+	}
+}
+
+// ContentType return the media type of the format
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Options configures a statement
+type Options struct {
+	Format Format
+	// Address is the address of the statement, the direction of the rows is the one of the address
+	Address string
+	// Decimals of the native unit the values are converted to, e.g. 18 to convert wei to ETH
+	Decimals int
+	// Location is the time zone of the timestamps, UTC when nil
+	Location *time.Location
+}
+
+// Row is a transaction of a statement
+type Row struct {
+	Block     uint64          `json:"block"`
+	Timestamp string          `json:"timestamp"`
+	Hash      string          `json:"hash"`
+	Direction types.Direction `json:"direction"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	// Value is a decimal of the native unit, kept as a string so that no precision is lost
+	Value  string         `json:"value"`
+	Status types.TxStatus `json:"status"`
+	Type   string         `json:"type"`
+}
+
+var csvHeader = []string{"block", "timestamp", "hash", "direction", "from", "to", "value", "status", "type"}
+
+func (r Row) record() []string {
+	return []string{
+		strconv.FormatUint(r.Block, 10), r.Timestamp, r.Hash, string(r.Direction),
+		r.From, r.To, r.Value, string(r.Status), r.Type,
+	}
+}
+
+// Writer writes the rows of a statement, nothing is buffered across rows
+type Writer struct {
+	opts Options
+	csv  *csv.Writer
+	json *json.Encoder
+	// started is set once the CSV header is written
+	started bool
+}
+
+// NewWriter creates the writer of a statement to w
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	writer := &Writer{opts: opts}
+	if opts.Format == FormatNDJSON {
+		writer.json = json.NewEncoder(w)
+	} else {
+		writer.csv = csv.NewWriter(w)
+	}
+
+	return writer
+}
+
+// NewRow converts a transaction to a row of the statement
+func (w *Writer) NewRow(tx types.Transaction) (Row, error) {
+	value, err := tx.ValueInt()
+	if err != nil {
+		return Row{}, fmt.Errorf("transaction %s: %w", tx.Hash, err)
+	}
+	var timestamp string
+	if tx.Timestamp > 0 {
+		timestamp = time.Unix(int64(tx.Timestamp), 0).In(w.opts.Location).Format(time.RFC3339)
+	}
+
+	return Row{
+		Block:     uint64(tx.BlockNumber),
+		Timestamp: timestamp,
+		Hash:      tx.Hash,
+		Direction: tx.Direction(w.opts.Address),
+		From:      tx.From,
+		To:        tx.To,
+		Value:     utils.FormatUnits(value, w.opts.Decimals),
+		Status:    tx.Status,
+		Type:      tx.Type,
+	}, nil
+}
+
+// Write writes the row of a transaction
+func (w *Writer) Write(tx types.Transaction) error {
+	row, err := w.NewRow(tx)
+	if err != nil {
+		return err
+	}
+	if w.json != nil {
+		return w.json.Encode(row)
+	}
+
+	w.writeHeader()
+	w.csv.Write(row.record())
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// Close ends the statement, the CSV header is written when there was no row
+func (w *Writer) Close() error {
+	if w.csv == nil {
+		return nil
+	}
+
+	w.writeHeader()
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *Writer) writeHeader() {
+	if !w.started {
+		w.csv.Write(csvHeader)
+		w.started = true
+	}
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alice = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	bob   = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+)
+
+var txns = []types.Transaction{
+	{
+		BlockNumber: 19041293, Timestamp: 1705680731, Hash: "0x01", From: bob, To: alice,
+		Value: "0x14d1120d7b160000", Status: types.StatusSuccess, Type: "0x2",
+	},
+	{
+		BlockNumber: 19041294, Timestamp: 1705680743, Hash: "0x02", From: alice, To: bob,
+		Value: "0x1", Status: types.StatusFailed, Type: "0x0",
+	},
+}
+
+func TestWriter_csv(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{Format: FormatCSV, Address: alice, Decimals: 18, Location: paris})
+	for _, tx := range txns {
+		assert.NoError(t, w.Write(tx))
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "block,timestamp,hash,direction,from,to,value,status,type\n"+
+		"19041293,2024-01-19T17:12:11+01:00,0x01,inbound,"+bob+","+alice+",1.5,success,0x2\n"+
+		"19041294,2024-01-19T17:12:23+01:00,0x02,outbound,"+alice+","+bob+",0.000000000000000001,failed,0x0\n",
+		buf.String())
+
+	// an empty statement has the header only
+	buf.Reset()
+	assert.NoError(t, NewWriter(&buf, Options{Format: FormatCSV, Address: alice}).Close())
+	assert.Equal(t, "block,timestamp,hash,direction,from,to,value,status,type\n", buf.String())
+}
+
+func TestWriter_ndjson(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{Format: FormatNDJSON, Address: alice, Decimals: 18})
+	for _, tx := range txns {
+		assert.NoError(t, w.Write(tx))
+	}
+	assert.NoError(t, w.Close())
+
+	dec := json.NewDecoder(&buf)
+	var rows []Row
+	for dec.More() {
+		var row Row
+		assert.NoError(t, dec.Decode(&row))
+		rows = append(rows, row)
+	}
+	assert.Equal(t, []Row{
+		{Block: 19041293, Timestamp: "2024-01-19T16:12:11Z", Hash: "0x01", Direction: types.DirectionInbound,
+			From: bob, To: alice, Value: "1.5", Status: types.StatusSuccess, Type: "0x2"},
+		{Block: 19041294, Timestamp: "2024-01-19T16:12:23Z", Hash: "0x02", Direction: types.DirectionOutbound,
+			From: alice, To: bob, Value: "0.000000000000000001", Status: types.StatusFailed, Type: "0x0"},
+	}, rows)
+}
+
+func TestWriter_invalidValue(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{Format: FormatNDJSON, Address: alice})
+	assert.ErrorContains(t, w.Write(types.Transaction{Hash: "0x03", Value: "0xzz"}), "transaction 0x03")
+	assert.Empty(t, buf.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatNDJSON, format)
+	assert.Equal(t, "application/x-ndjson", format.ContentType())
+
+	_, err = ParseFormat("xlsx")
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// checkTimeout bounds the duration of each readiness check
+const checkTimeout = 2 * time.Second
+
+// CrawlStatus reports the progress of the crawler
+type CrawlStatus interface {
+	// LastSuccess return the time of the last successful crawl, zero if none
+	LastSuccess() time.Time
+	// Head return the latest block reported by the node at the last crawl, zero if none, so that
+	// the probes do not spend the rate limit of the node
+	Head() uint64
+}
+
+// ParsedSource reports the last parsed block
+type ParsedSource interface {
+	GetCurrentBlock(ctx context.Context) (uint64, error)
+}
+
+// Options are the readiness thresholds
+type Options struct {
+	// MaxCrawlAge is the maximum age of the last successful crawl
+	MaxCrawlAge time.Duration
+	// MaxBlockLag is the maximum number of blocks the parsed block lags the node head,
+	// on top of the confirmations of the chain
+	MaxBlockLag uint64
+}
+
+// Chain is the crawler and the storage of one crawled chain
+type Chain struct {
+	Name    string
+	Crawler CrawlStatus
+	Repo    ParsedSource
+	// Confirmations is the number of blocks the crawler deliberately stays behind the head
+	Confirmations uint64
+}
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Name    string `json:"name"`
+	Chain   string `json:"chain,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the body of the health endpoints
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+type checker struct {
+	chains    []Chain
+	opts      Options
+	startedAt time.Time
+}
+
+func NewChecker(opts Options, chains ...Chain) *checker {
+	return &checker{
+		chains:    chains,
+		opts:      opts,
+		startedAt: time.Now(),
+	}
+}
+
+// LivenessHandler reports the process is alive
+func (c *checker) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, Report{Status: StatusOK})
+}
+
+// ReadinessHandler reports whether the instance is fit to serve, every failing check is explained
+func (c *checker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Check(r.Context()))
+}
+
+// Check runs the readiness checks of every chain, the chains are checked concurrently
+func (c *checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusOK}
+	chainChecks := make([][]CheckResult, len(c.chains))
+	var wg sync.WaitGroup
+	wg.Add(len(c.chains))
+	for i, chain := range c.chains {
+		i, chain := i, chain
+		go func() {
+			defer wg.Done()
+			parsed, storage := c.checkStorage(ctx, chain)
+			chainChecks[i] = []CheckResult{
+				c.checkCrawl(chain),
+				storage,
+				c.checkLag(chain, parsed, storage.Status == StatusOK),
+			}
+		}()
+	}
+	wg.Wait()
+
+	var checks []CheckResult
+	for _, results := range chainChecks {
+		checks = append(checks, results...)
+	}
+
+	for _, check := range checks {
+		if check.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	report.Checks = checks
+
+	return report
+}
+
+// checkCrawl fails when the last successful crawl is too old, the process start
+// time stands for the last crawl until the first one completes
+func (c *checker) checkCrawl(chain Chain) CheckResult {
+	result := CheckResult{Name: "crawler", Chain: chain.Name, Status: StatusOK}
+	last := chain.Crawler.LastSuccess()
+	if last.IsZero() {
+		last = c.startedAt
+	}
+
+	age := time.Since(last)
+	if age > c.opts.MaxCrawlAge {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("last successful crawl %s ago, threshold is %s",
+			age.Truncate(time.Second), c.opts.MaxCrawlAge)
+	}
+
+	return result
+}
+
+func (c *checker) checkStorage(ctx context.Context, chain Chain) (uint64, CheckResult) {
+	result := CheckResult{Name: "storage", Chain: chain.Name, Status: StatusOK}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	parsed, err := chain.Repo.GetCurrentBlock(ctx)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("storage unreachable: %v", err)
+	}
+
+	return parsed, result
+}
+
+// checkLag compares the parsed block with the head the crawler last observed, the lag is unknown
+// until the crawler reached the node, checkCrawl fails when it never does
+func (c *checker) checkLag(chain Chain, parsed uint64, parsedKnown bool) CheckResult {
+	result := CheckResult{Name: "block_lag", Chain: chain.Name, Status: StatusOK}
+
+	maxLag := c.opts.MaxBlockLag + chain.Confirmations
+	head := chain.Crawler.Head()
+	switch {
+	case head == 0:
+	case !parsedKnown:
+		result.Status = StatusFail
+		result.Message = "last parsed block unknown"
+	case head > parsed && head-parsed > maxLag:
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("parsed block %d lags node head %d by %d blocks, threshold is %d",
+			parsed, head, head-parsed, maxLag)
+	}
+
+	return result
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	response, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,162 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeCrawl struct {
+	last time.Time
+	head uint64
+}
+
+func (f fakeCrawl) LastSuccess() time.Time {
+	return f.last
+}
+
+func (f fakeCrawl) Head() uint64 {
+	return f.head
+}
+
+var opts = Options{MaxCrawlAge: time.Minute, MaxBlockLag: 5}
+
+func readiness(t *testing.T, c *checker) (int, Report) {
+	w := httptest.NewRecorder()
+	c.ReadinessHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func failing(report Report) map[string]string {
+	failed := make(map[string]string)
+	for _, check := range report.Checks {
+		if check.Status != StatusOK {
+			failed[check.Name] = check.Message
+		}
+	}
+	return failed
+}
+
+func TestLivenessHandler(t *testing.T) {
+	c := NewChecker(opts, Chain{Name: "ethereum", Crawler: fakeCrawl{}, Repo: mocks.NewRepository(t)})
+
+	w := httptest.NewRecorder()
+	c.LivenessHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadinessHandler_ready(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(100), nil)
+
+	c := NewChecker(opts, Chain{Name: "ethereum", Crawler: fakeCrawl{last: time.Now(), head: 105}, Repo: repo})
+	code, report := readiness(t, c)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 3)
+}
+
+func TestReadinessHandler_notReady(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(100), nil)
+
+	c := NewChecker(opts, Chain{Name: "ethereum", Crawler: fakeCrawl{last: time.Now().Add(-2 * time.Minute), head: 106}, Repo: repo})
+	code, report := readiness(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, map[string]string{
+		"crawler":   "last successful crawl 2m0s ago, threshold is 1m0s",
+		"block_lag": "parsed block 100 lags node head 106 by 6 blocks, threshold is 5",
+	}, failing(report))
+}
+
+func TestReadinessHandler_unreachable(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(0), fmt.Errorf("connection refused"))
+
+	// no crawl completed yet, the start time stands for the last crawl
+	c := NewChecker(opts, Chain{Name: "ethereum", Crawler: fakeCrawl{head: 100}, Repo: repo})
+	code, report := readiness(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, map[string]string{
+		"storage":   "storage unreachable: connection refused",
+		"block_lag": "last parsed block unknown",
+	}, failing(report))
+}
+
+func TestReadinessHandler_headUnknown(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(0), nil)
+
+	// the crawler did not reach the node yet, the lag is unknown until it does
+	c := NewChecker(opts, Chain{Name: "ethereum", Crawler: fakeCrawl{}, Repo: repo})
+	code, report := readiness(t, c)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, failing(report))
+}
+
+func TestReadinessHandler_chains(t *testing.T) {
+	ethRepo := mocks.NewRepository(t)
+	ethRepo.On("GetCurrentBlock", mock.Anything).Return(uint64(100), nil)
+	bscRepo := mocks.NewRepository(t)
+	bscRepo.On("GetCurrentBlock", mock.Anything).Return(uint64(100), nil)
+
+	c := NewChecker(opts,
+		// the confirmations of the chain are added to the allowed lag
+		Chain{Name: "ethereum", Crawler: fakeCrawl{last: time.Now(), head: 112}, Repo: ethRepo, Confirmations: 7},
+		Chain{Name: "bsc", Crawler: fakeCrawl{last: time.Now(), head: 112}, Repo: bscRepo},
+	)
+	code, report := readiness(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Len(t, report.Checks, 6)
+	assert.Contains(t, report.Checks, CheckResult{
+		Name:    "block_lag",
+		Chain:   "bsc",
+		Status:  StatusFail,
+		Message: "parsed block 100 lags node head 112 by 12 blocks, threshold is 5",
+	})
+	assert.Contains(t, report.Checks, CheckResult{Name: "block_lag", Chain: "ethereum", Status: StatusOK})
+}
+
+func TestReadinessHandler_concurrentChains(t *testing.T) {
+	// the storage of each chain answers once both are probed, the chains are checked concurrently
+	var probed sync.WaitGroup
+	probed.Add(2)
+	waitBoth := func(mock.Arguments) {
+		probed.Done()
+		probed.Wait()
+	}
+	ethRepo := mocks.NewRepository(t)
+	ethRepo.On("GetCurrentBlock", mock.Anything).Run(waitBoth).Return(uint64(100), nil)
+	bscRepo := mocks.NewRepository(t)
+	bscRepo.On("GetCurrentBlock", mock.Anything).Run(waitBoth).Return(uint64(100), nil)
+
+	c := NewChecker(opts,
+		Chain{Name: "ethereum", Crawler: fakeCrawl{last: time.Now(), head: 100}, Repo: ethRepo},
+		Chain{Name: "bsc", Crawler: fakeCrawl{last: time.Now(), head: 100}, Repo: bscRepo},
+	)
+	report := make(chan Report)
+	go func() { report <- c.Check(context.Background()) }()
+	select {
+	case r := <-report:
+		assert.Equal(t, StatusOK, r.Status)
+		// the checks keep the order of the chains
+		assert.Equal(t, "ethereum", r.Checks[0].Chain)
+		assert.Equal(t, "bsc", r.Checks[3].Chain)
+	case <-time.After(time.Second):
+		t.Fatal("the chains are checked one after the other")
+	}
+}
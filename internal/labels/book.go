@@ -0,0 +1,125 @@
+// Package labels is the global label book of the well-known addresses, e.g. the exchanges and the bridges,
+// which label the counterparties of the subscribed addresses
+package labels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Entry is a labeled address of the book file, Chains restrict it to these chains, all when empty
+type Entry struct {
+	Address string   `json:"address"`
+	Label   string   `json:"label"`
+	Tags    []string `json:"tags,omitempty"`
+	Chains  []string `json:"chains,omitempty"`
+}
+
+// bookFile is the JSON document of the book
+type bookFile struct {
+	Labels []Entry `json:"labels"`
+}
+
+// Book is a read-only set of labels, a nil book has no label
+type Book struct {
+	entries []Entry
+	// labels by normalized address
+	labels map[string]types.AddressLabel
+}
+
+// LoadBook reads the book of a JSON file
+func LoadBook(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open label book: %w", err)
+	}
+	defer f.Close()
+
+	book, err := ParseBook(f)
+	if err != nil {
+		return nil, fmt.Errorf("label book %s: %w", path, err)
+	}
+	return book, nil
+}
+
+// ParseBook decodes the book of a JSON document, {"labels": [{"address": ..., "label": ..., "tags": [...]}]}
+func ParseBook(r io.Reader) (*Book, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var file bookFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse label book: %w", err)
+	}
+
+	var errs []error
+	for i, entry := range file.Labels {
+		if entry.Address == "" {
+			errs = append(errs, fmt.Errorf("labels[%d]: address is required", i))
+		}
+		if entry.Label == "" {
+			errs = append(errs, fmt.Errorf("labels[%d]: label is required", i))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return newBook(file.Labels, ""), nil
+}
+
+// newBook indexes the entries of the chain, every entry when chain is empty. The later entries
+// of an address replace the earlier ones.
+func newBook(entries []Entry, chain string) *Book {
+	b := &Book{entries: entries, labels: make(map[string]types.AddressLabel)}
+	for _, entry := range entries {
+		if chain != "" && len(entry.Chains) > 0 && !contains(entry.Chains, chain) {
+			continue
+		}
+		b.labels[utils.NormalizeAddress(entry.Address)] = types.AddressLabel{Label: entry.Label, Tags: entry.Tags}
+	}
+
+	return b
+}
+
+// ForChain return the book of the labels of a chain
+func (b *Book) ForChain(chain string) *Book {
+	if b == nil {
+		return nil
+	}
+	return newBook(b.entries, chain)
+}
+
+// Lookup return the label of an address, compared case-insensitively
+func (b *Book) Lookup(address string) (types.AddressLabel, bool) {
+	if b == nil {
+		return types.AddressLabel{}, false
+	}
+	label, ok := b.labels[utils.NormalizeAddress(address)]
+	if ok {
+		label.Tags = append([]string(nil), label.Tags...)
+	}
+	return label, ok
+}
+
+// Len return the number of labeled addresses
+func (b *Book) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.labels)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package labels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	binance = "0x28C6c06298d514Db089934071355E5743bf21d60"
+	bridge  = "0x3ee18b2214aff97000d974cf647e7c347e8fa585"
+)
+
+func TestLoadBook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"labels": [
+		{"address": "`+binance+`", "label": "Binance 14", "tags": ["exchange"]},
+		{"address": "`+bridge+`", "label": "Wormhole bridge", "tags": ["bridge"], "chains": ["ethereum"]}
+	]}`), 0o600))
+
+	book, err := LoadBook(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, book.Len())
+
+	label, ok := book.Lookup(strings.ToLower(binance))
+	assert.True(t, ok)
+	assert.Equal(t, types.AddressLabel{Label: "Binance 14", Tags: []string{"exchange"}}, label)
+
+	// the entries of another chain are left out
+	_, ok = book.ForChain("ethereum").Lookup(bridge)
+	assert.True(t, ok)
+	_, ok = book.ForChain("bsc").Lookup(bridge)
+	assert.False(t, ok)
+	_, ok = book.ForChain("bsc").Lookup(binance)
+	assert.True(t, ok)
+
+	// a nil book has no label
+	var none *Book
+	_, ok = none.ForChain("ethereum").Lookup(binance)
+	assert.False(t, ok)
+
+	_, err = LoadBook(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "open label book")
+}
+
+func TestParseBook_invalid(t *testing.T) {
+	_, err := ParseBook(strings.NewReader(`{"labels": [{"address": "", "label": ""}]}`))
+	assert.ErrorContains(t, err, "labels[0]: address is required")
+	assert.ErrorContains(t, err, "labels[0]: label is required")
+
+	_, err = ParseBook(strings.NewReader(`{"labels": [{"address": "0x1", "label": "a", "name": "b"}]}`))
+	assert.ErrorContains(t, err, `unknown field "name"`)
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Structured field keys shared by every package
+const (
+	KeyRequestID     = "request_id"
+	KeyCorrelationID = "correlation_id"
+	KeyChain         = "chain"
+	KeyBlockNumber   = "block_number"
+	KeyBlockHash     = "block_hash"
+	KeyTxHash        = "tx_hash"
+	KeyMethod        = "method"
+	KeyEndpoint      = "endpoint"
+	KeyAddress       = "address"
+	KeyDuration      = "duration"
+	KeyError         = "error"
+)
+
+// New creates a logger writing to w at the given level ("debug", "info", "warn", "error")
+// and format ("text" or "json"). Attributes stored in the context with WithAttrs are added
+// to every record logged with a context.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+type ctxKey struct{}
+
+// WithAttrs returns a context carrying attributes added to the records logged with it
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return WithAttrs(ctx, slog.String(KeyRequestID, id))
+}
+
+// RequestID returns the request ID carried by the context, empty if none
+func RequestID(ctx context.Context) string {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if attrs[i].Key == KeyRequestID {
+			return attrs[i].Value.String()
+		}
+	}
+	return ""
+}
+
+// NewID returns a random ID used to correlate log records
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Err returns the attribute of an error
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// contextHandler adds the attributes carried by the context to the records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_json(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	assert.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithAttrs(ctx, slog.Uint64(KeyBlockNumber, 13))
+	logger.DebugContext(ctx, "hidden")
+	logger.InfoContext(ctx, "saved transactions", "count", 2)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "saved transactions", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "req-1", record[KeyRequestID])
+	assert.Equal(t, float64(13), record[KeyBlockNumber])
+	assert.Equal(t, float64(2), record["count"])
+	assert.Equal(t, "req-1", RequestID(ctx))
+	assert.Equal(t, "", RequestID(context.Background()))
+}
+
+func TestNew_text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "DEBUG", FormatText)
+	assert.NoError(t, err)
+
+	logger.With("component", "crawler").DebugContext(WithRequestID(context.Background(), "req-2"), "tick")
+	assert.Contains(t, buf.String(), "level=DEBUG msg=tick component=crawler request_id=req-2")
+}
+
+func TestNew_invalid(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "verbose", FormatText)
+	assert.ErrorContains(t, err, `invalid log level "verbose"`)
+
+	_, err = New(&bytes.Buffer{}, "info", "xml")
+	assert.ErrorContains(t, err, `invalid log format "xml"`)
+}
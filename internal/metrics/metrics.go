@@ -0,0 +1,419 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Default is the registry used by the instrumented packages and exposed on /metrics
+var Default = NewRegistry()
+
+// DefaultBuckets are the histogram buckets in seconds, suited to network latencies
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// collector writes the samples of one metric family
+type collector interface {
+	desc() *desc
+	write(w *bufio.Writer)
+}
+
+type desc struct {
+	name       string
+	help       string
+	typ        metricType
+	labelNames []string
+}
+
+// Registry holds metric families and writes them in Prometheus text exposition format.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := c.desc().name
+	if _, ok := r.collectors[name]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", name))
+	}
+	r.collectors[name] = c
+}
+
+// WriteTo writes every metric family sorted by name
+func (r *Registry) WriteTo(out io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	cw := &countingWriter{w: out}
+	w := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		d := c.desc()
+		fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.typ)
+		c.write(w)
+	}
+	err := w.Flush()
+
+	return cw.n, err
+}
+
+// Handler serves the registry in Prometheus text exposition format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// value is a float64 updated atomically
+type value struct {
+	bits uint64
+}
+
+func (v *value) Add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&v.bits, old, next) {
+			return
+		}
+	}
+}
+
+func (v *value) Set(val float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(val))
+}
+
+func (v *value) Get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
+
+// vec holds one child per combination of label values
+type vec[T any] struct {
+	d        *desc
+	mu       sync.RWMutex
+	children map[string]*child[T]
+	newChild func() *T
+}
+
+type child[T any] struct {
+	labelValues []string
+	metric      *T
+}
+
+func newVec[T any](d *desc, newChild func() *T) *vec[T] {
+	return &vec[T]{d: d, children: make(map[string]*child[T]), newChild: newChild}
+}
+
+func (v *vec[T]) desc() *desc {
+	return v.d
+}
+
+func (v *vec[T]) with(labelValues []string) *T {
+	if len(labelValues) != len(v.d.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.d.name, len(v.d.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	c, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return c.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.children[key]; ok {
+		return c.metric
+	}
+	c = &child[T]{labelValues: append([]string(nil), labelValues...), metric: v.newChild()}
+	v.children[key] = c
+	return c.metric
+}
+
+// sorted returns the children ordered by label values for a stable output
+func (v *vec[T]) sorted() []*child[T] {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	children := make([]*child[T], len(keys))
+	for i, key := range keys {
+		children[i] = v.children[key]
+	}
+	return children
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	v value
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add increases the counter, negative values are ignored
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.v.Add(delta)
+}
+
+func (c *Counter) Value() float64 {
+	return c.v.Get()
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	*vec[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	v := &CounterVec{newVec(&desc{name: name, help: help, typ: typeCounter, labelNames: labelNames},
+		func() *Counter { return &Counter{} })}
+	r.register(v)
+	return v
+}
+
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	return v.with(labelValues)
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	for _, c := range v.sorted() {
+		writeSample(w, v.d.name, v.d.labelNames, c.labelValues, "", "", c.metric.Value())
+	}
+}
+
+// Gauge is a value which can go up and down
+type Gauge struct {
+	v value
+}
+
+func (g *Gauge) Set(val float64) {
+	g.v.Set(val)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.v.Add(delta)
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.v.Get()
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	v := &GaugeVec{newVec(&desc{name: name, help: help, typ: typeGauge, labelNames: labelNames},
+		func() *Gauge { return &Gauge{} })}
+	r.register(v)
+	return v
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).With()
+}
+
+func (v *GaugeVec) With(labelValues ...string) *Gauge {
+	return v.with(labelValues)
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	for _, c := range v.sorted() {
+		writeSample(w, v.d.name, v.d.labelNames, c.labelValues, "", "", c.metric.Value())
+	}
+}
+
+// gaugeFunc is a gauge whose value is computed at scrape time
+type gaugeFunc struct {
+	d  *desc
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is computed by fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{d: &desc{name: name, help: help, typ: typeGauge}, fn: fn})
+}
+
+func (g *gaugeFunc) desc() *desc {
+	return g.d
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeSample(w, g.d.name, nil, nil, "", "", g.fn())
+}
+
+// Histogram counts observations in cumulative buckets
+type Histogram struct {
+	upperBounds []float64
+	counts      []uint64
+	count       uint64
+	sum         value
+}
+
+func (h *Histogram) Observe(v float64) {
+	// buckets are cumulative on write, only the first matching bucket is incremented
+	i := sort.SearchFloat64s(h.upperBounds, v)
+	if i < len(h.counts) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	h.sum.Add(v)
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	v := &HistogramVec{
+		vec: newVec(&desc{name: name, help: help, typ: typeHistogram, labelNames: labelNames},
+			func() *Histogram {
+				return &Histogram{upperBounds: buckets, counts: make([]uint64, len(buckets))}
+			}),
+		buckets: buckets,
+	}
+	r.register(v)
+	return v
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).With()
+}
+
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	return v.with(labelValues)
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	for _, c := range v.sorted() {
+		h := c.metric
+		var cumulative uint64
+		for i, bound := range h.upperBounds {
+			cumulative += atomic.LoadUint64(&h.counts[i])
+			writeSample(w, v.d.name+"_bucket", v.d.labelNames, c.labelValues, "le", formatFloat(bound), float64(cumulative))
+		}
+		count := atomic.LoadUint64(&h.count)
+		writeSample(w, v.d.name+"_bucket", v.d.labelNames, c.labelValues, "le", "+Inf", float64(count))
+		writeSample(w, v.d.name+"_sum", v.d.labelNames, c.labelValues, "", "", h.sum.Get())
+		writeSample(w, v.d.name+"_count", v.d.labelNames, c.labelValues, "", "", float64(count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, val float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, labelName, escapeLabelValue(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(val))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
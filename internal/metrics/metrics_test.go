@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.NewCounterVec("test_requests_total", "Requests by method.", "method", "code")
+	requests.With("eth_blockNumber", "200").Inc()
+	requests.With("eth_blockNumber", "200").Add(2)
+	requests.With("eth_getBlockByNumber", `a"b\c`).Inc()
+	requests.With("eth_getBlockByNumber", "200").Add(-1)
+
+	head := reg.NewGauge("test_head_block", "Chain head\nblock.")
+	head.Set(100)
+	head.Inc()
+
+	latency := reg.NewHistogramVec("test_latency_seconds", "Latency.", []float64{1, 0.1}, "method")
+	latency.With("a").Observe(0.05)
+	latency.With("a").Observe(0.1)
+	latency.With("a").Observe(0.5)
+	latency.With("a").Observe(3)
+
+	reg.NewGaugeFunc("test_subscriptions", "Subscriptions.", func() float64 { return 42 })
+
+	var sb strings.Builder
+	_, err := reg.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP test_head_block Chain head\nblock.
+# TYPE test_head_block gauge
+test_head_block 101
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{method="a",le="0.1"} 2
+test_latency_seconds_bucket{method="a",le="1"} 3
+test_latency_seconds_bucket{method="a",le="+Inf"} 4
+test_latency_seconds_sum{method="a"} 3.65
+test_latency_seconds_count{method="a"} 4
+# HELP test_requests_total Requests by method.
+# TYPE test_requests_total counter
+test_requests_total{method="eth_blockNumber",code="200"} 3
+test_requests_total{method="eth_getBlockByNumber",code="200"} 0
+test_requests_total{method="eth_getBlockByNumber",code="a\"b\\c"} 1
+# HELP test_subscriptions Subscriptions.
+# TYPE test_subscriptions gauge
+test_subscriptions 42
+`, sb.String())
+}
+
+func TestRegistry_duplicate(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("test_total", "")
+	assert.Panics(t, func() { reg.NewGauge("test_total", "") })
+}
+
+func TestCounterVec_wrongLabels(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("test_total", "", "method")
+	assert.Panics(t, func() { vec.With("a", "b") })
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("test_total", "Test.").Inc()
+
+	w := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "test_total 1\n")
+}
@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	repository "github.com/TrustWallet/tx-parser/internal/repository"
+
 	types "github.com/TrustWallet/tx-parser/internal/types"
 )
 
@@ -29,6 +31,32 @@ func (_m *Repository) AddAddress(ctx context.Context, address string) error {
 	return r0
 }
 
+// AddAddresses provides a mock function with given fields: ctx, addresses
+func (_m *Repository) AddAddresses(ctx context.Context, addresses []string) ([]bool, error) {
+	ret := _m.Called(ctx, addresses)
+
+	var r0 []bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]bool, error)); ok {
+		return rf(ctx, addresses)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []bool); ok {
+		r0 = rf(ctx, addresses)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, addresses)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAddresses provides a mock function with given fields: ctx
 func (_m *Repository) GetAddresses(ctx context.Context) ([]string, error) {
 	ret := _m.Called(ctx)
@@ -55,6 +83,32 @@ func (_m *Repository) GetAddresses(ctx context.Context) ([]string, error) {
 	return r0, r1
 }
 
+// GetBlockTransactions provides a mock function with given fields: ctx, blockNumber
+func (_m *Repository) GetBlockTransactions(ctx context.Context, blockNumber uint64) ([]repository.IndexedTransaction, error) {
+	ret := _m.Called(ctx, blockNumber)
+
+	var r0 []repository.IndexedTransaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) ([]repository.IndexedTransaction, error)); ok {
+		return rf(ctx, blockNumber)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []repository.IndexedTransaction); ok {
+		r0 = rf(ctx, blockNumber)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.IndexedTransaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, blockNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCurrentBlock provides a mock function with given fields: ctx
 func (_m *Repository) GetCurrentBlock(ctx context.Context) (uint64, error) {
 	ret := _m.Called(ctx)
@@ -79,25 +133,101 @@ func (_m *Repository) GetCurrentBlock(ctx context.Context) (uint64, error) {
 	return r0, r1
 }
 
-// GetTransactions provides a mock function with given fields: ctx, address
-func (_m *Repository) GetTransactions(ctx context.Context, address string) ([]types.Transaction, error) {
-	ret := _m.Called(ctx, address)
+// GetLabels provides a mock function with given fields: ctx, addresses
+func (_m *Repository) GetLabels(ctx context.Context, addresses []string) (map[string]types.AddressLabel, error) {
+	ret := _m.Called(ctx, addresses)
+
+	var r0 map[string]types.AddressLabel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]types.AddressLabel, error)); ok {
+		return rf(ctx, addresses)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]types.AddressLabel); ok {
+		r0 = rf(ctx, addresses)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]types.AddressLabel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, addresses)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTransactionByHash provides a mock function with given fields: ctx, hash
+func (_m *Repository) GetTransactionByHash(ctx context.Context, hash string) (repository.IndexedTransaction, error) {
+	ret := _m.Called(ctx, hash)
+
+	var r0 repository.IndexedTransaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (repository.IndexedTransaction, error)); ok {
+		return rf(ctx, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) repository.IndexedTransaction); ok {
+		r0 = rf(ctx, hash)
+	} else {
+		r0 = ret.Get(0).(repository.IndexedTransaction)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryTransactions provides a mock function with given fields: ctx, address, filter
+func (_m *Repository) QueryTransactions(ctx context.Context, address string, filter repository.TransactionFilter) ([]types.Transaction, error) {
+	ret := _m.Called(ctx, address, filter)
 
 	var r0 []types.Transaction
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]types.Transaction, error)); ok {
-		return rf(ctx, address)
+	if rf, ok := ret.Get(0).(func(context.Context, string, repository.TransactionFilter) ([]types.Transaction, error)); ok {
+		return rf(ctx, address, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []types.Transaction); ok {
-		r0 = rf(ctx, address)
+	if rf, ok := ret.Get(0).(func(context.Context, string, repository.TransactionFilter) []types.Transaction); ok {
+		r0 = rf(ctx, address, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]types.Transaction)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, address)
+	if rf, ok := ret.Get(1).(func(context.Context, string, repository.TransactionFilter) error); ok {
+		r1 = rf(ctx, address, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveAddresses provides a mock function with given fields: ctx, addresses
+func (_m *Repository) RemoveAddresses(ctx context.Context, addresses []string) ([]bool, error) {
+	ret := _m.Called(ctx, addresses)
+
+	var r0 []bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]bool, error)); ok {
+		return rf(ctx, addresses)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []bool); ok {
+		r0 = rf(ctx, addresses)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, addresses)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -119,6 +249,34 @@ func (_m *Repository) SaveTransactions(ctx context.Context, blockNumber uint64,
 	return r0
 }
 
+// ScanTransactions provides a mock function with given fields: ctx, address, filter, fn
+func (_m *Repository) ScanTransactions(ctx context.Context, address string, filter repository.TransactionFilter, fn func(types.Transaction) error) error {
+	ret := _m.Called(ctx, address, filter, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, repository.TransactionFilter, func(types.Transaction) error) error); ok {
+		r0 = rf(ctx, address, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLabel provides a mock function with given fields: ctx, address, label
+func (_m *Repository) SetLabel(ctx context.Context, address string, label types.AddressLabel) error {
+	ret := _m.Called(ctx, address, label)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, types.AddressLabel) error); ok {
+		r0 = rf(ctx, address, label)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewRepository creates a new instance of Repository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewRepository(t interface {
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+)
+
+// bulkBatchSize is the number of addresses applied to the repository at once
+const bulkBatchSize = 500
+
+// BulkAction is the operation applied by a bulk request
+type BulkAction string
+
+const (
+	BulkSubscribe   BulkAction = "subscribe"
+	BulkUnsubscribe BulkAction = "unsubscribe"
+)
+
+// SubscriptionStatus is the outcome of a bulk operation for one address
+type SubscriptionStatus string
+
+const (
+	StatusAdded             SubscriptionStatus = "added"
+	StatusAlreadySubscribed SubscriptionStatus = "already_subscribed"
+	StatusRemoved           SubscriptionStatus = "removed"
+	StatusNotSubscribed     SubscriptionStatus = "not_subscribed"
+	StatusInvalid           SubscriptionStatus = "invalid"
+	StatusFailed            SubscriptionStatus = "failed"
+)
+
+// SubscriptionResult is the per-address result of a bulk operation
+type SubscriptionResult struct {
+	Address string             `json:"address"`
+	Status  SubscriptionStatus `json:"status"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// BulkSubscribe add a list of addresses to observer
+func (p *parserService) BulkSubscribe(ctx context.Context, addresses []string) []SubscriptionResult {
+	return p.applyBulk(ctx, BulkSubscribe, addresses, nil)
+}
+
+// BulkUnsubscribe remove a list of addresses from observer
+func (p *parserService) BulkUnsubscribe(ctx context.Context, addresses []string) []SubscriptionResult {
+	return p.applyBulk(ctx, BulkUnsubscribe, addresses, nil)
+}
+
+// applyBulk validates the addresses and applies the action to the repository batch by batch,
+// progress is called after every batch with the results of that batch. The results are only
+// returned without progress, so that a large job doesn't hold all of them.
+func (p *parserService) applyBulk(ctx context.Context, action BulkAction, addresses []string,
+	progress func(batch []SubscriptionResult)) []SubscriptionResult {
+	var results []SubscriptionResult
+	if progress == nil {
+		results = make([]SubscriptionResult, 0, len(addresses))
+	}
+	for start := 0; start < len(addresses); start += bulkBatchSize {
+		end := start + bulkBatchSize
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+
+		var batch []SubscriptionResult
+		if err := ctx.Err(); err != nil {
+			// a canceled job fails its remaining addresses rather than applying them
+			batch = failBatch(addresses[start:end], err)
+		} else {
+			batch = p.applyBatch(ctx, action, addresses[start:end])
+		}
+		for _, res := range batch {
+			subscriptionOps.With(string(action), string(res.Status)).Inc()
+		}
+		if progress != nil {
+			progress(batch)
+			continue
+		}
+		results = append(results, batch...)
+	}
+
+	return results
+}
+
+func failBatch(addresses []string, err error) []SubscriptionResult {
+	results := make([]SubscriptionResult, len(addresses))
+	for i, address := range addresses {
+		results[i] = SubscriptionResult{Address: strings.TrimSpace(address), Status: StatusFailed, Error: err.Error()}
+	}
+
+	return results
+}
+
+func (p *parserService) applyBatch(ctx context.Context, action BulkAction, addresses []string) []SubscriptionResult {
+	results := make([]SubscriptionResult, len(addresses))
+	valid := make([]string, 0, len(addresses))
+	validIdx := make([]int, 0, len(addresses))
+	for i, address := range addresses {
+		address = strings.TrimSpace(address)
+		results[i].Address = address
+		if err := p.validateAddress(address); err != nil {
+			results[i].Status = StatusInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+
+		valid = append(valid, address)
+		validIdx = append(validIdx, i)
+	}
+	if len(valid) == 0 {
+		return results
+	}
+
+	var (
+		applied []bool
+		err     error
+	)
+	okStatus, noopStatus := StatusAdded, StatusAlreadySubscribed
+	if action == BulkUnsubscribe {
+		okStatus, noopStatus = StatusRemoved, StatusNotSubscribed
+		applied, err = p.repo.RemoveAddresses(ctx, valid)
+	} else {
+		applied, err = p.repo.AddAddresses(ctx, valid)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error applying bulk batch", slog.String("action", string(action)),
+			slog.Int("count", len(valid)), logging.Err(err))
+	}
+
+	for j, i := range validIdx {
+		switch {
+		case err != nil:
+			results[i].Status = StatusFailed
+			results[i].Error = err.Error()
+		case applied[j]:
+			results[i].Status = okStatus
+		default:
+			results[i].Status = noopStatus
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	addr1 = "0xf15689636571dba322b48e9ec9ba6cfb3df818e1"
+	addr2 = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+)
+
+func TestParserService_BulkSubscribe(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, []string{addr1, addr2}).Return([]bool{true, false}, nil)
+
+	parser := NewParserService(repo)
+	results := parser.BulkSubscribe(context.TODO(), []string{addr1, "hello", " " + addr2 + " ", ""})
+	assert.Equal(t, []SubscriptionResult{
+		{Address: addr1, Status: StatusAdded},
+		{Address: "hello", Status: StatusInvalid, Error: "invalid address"},
+		{Address: addr2, Status: StatusAlreadySubscribed},
+		{Address: "", Status: StatusInvalid, Error: "invalid address"},
+	}, results)
+}
+
+func TestParserService_BulkSubscribe_error(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, []string{addr1}).Return(nil, fmt.Errorf("some error"))
+
+	parser := NewParserService(repo)
+	results := parser.BulkSubscribe(context.TODO(), []string{addr1, "0x123"})
+	assert.Equal(t, []SubscriptionResult{
+		{Address: addr1, Status: StatusFailed, Error: "some error"},
+		{Address: "0x123", Status: StatusInvalid, Error: "invalid address"},
+	}, results)
+}
+
+func TestParserService_BulkUnsubscribe(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("RemoveAddresses", mock.Anything, []string{addr1, addr2}).Return([]bool{false, true}, nil)
+
+	parser := NewParserService(repo)
+	results := parser.BulkUnsubscribe(context.TODO(), []string{addr1, addr2})
+	assert.Equal(t, []SubscriptionResult{
+		{Address: addr1, Status: StatusNotSubscribed},
+		{Address: addr2, Status: StatusRemoved},
+	}, results)
+}
+
+func TestParserService_BulkSubscribe_batches(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, addresses []string) []bool {
+			return make([]bool, len(addresses))
+		}, nil)
+
+	addresses := make([]string, bulkBatchSize+1)
+	for i := range addresses {
+		addresses[i] = addr1
+	}
+
+	parser := NewParserService(repo)
+	results := parser.BulkSubscribe(context.TODO(), addresses)
+	assert.Len(t, results, bulkBatchSize+1)
+	repo.AssertNumberOfCalls(t, "AddAddresses", 2)
+}
+
+func TestParserService_StartBulkJob(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, []string{addr1, addr2}).Return([]bool{true, true}, nil)
+
+	parser := NewParserService(repo)
+	job, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, []string{addr1, addr2, "hello"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, 3, job.Total)
+
+	assert.Eventually(t, func() bool {
+		job, ok := parser.GetBulkJob(context.TODO(), job.ID)
+		return ok && job.State == JobCompleted
+	}, time.Second, 10*time.Millisecond)
+
+	job, ok := parser.GetBulkJob(context.TODO(), job.ID)
+	assert.True(t, ok)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 2, job.Counts[StatusAdded])
+	assert.Equal(t, 1, job.Counts[StatusInvalid])
+	assert.NotNil(t, job.FinishedAt)
+
+	// only the failed and the invalid results are kept, page by page
+	issues, ok := parser.GetBulkJobIssues(context.TODO(), job.ID, 0, 10)
+	assert.True(t, ok)
+	assert.Equal(t, []SubscriptionResult{{Address: "hello", Status: StatusInvalid, Error: "invalid address"}}, issues)
+	issues, ok = parser.GetBulkJobIssues(context.TODO(), job.ID, 1, 10)
+	assert.True(t, ok)
+	assert.Empty(t, issues)
+	_, ok = parser.GetBulkJobIssues(context.TODO(), "unknown", 0, 10)
+	assert.False(t, ok)
+
+	_, err = parser.StartBulkJob(context.TODO(), "unknown", nil)
+	assert.ErrorIs(t, err, ErrUnknownBulkAction)
+
+	_, ok = parser.GetBulkJob(context.TODO(), "unknown")
+	assert.False(t, ok)
+}
+
+func TestParserService_StartBulkJob_tooManyJobs(t *testing.T) {
+	release := make(chan time.Time)
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, []string{addr1}).WaitUntil(release).Return([]bool{true}, nil)
+
+	parser := NewParserService(repo)
+	ids := make([]string, maxRunningJobs)
+	for i := range ids {
+		job, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, []string{addr1})
+		assert.NoError(t, err)
+		ids[i] = job.ID
+	}
+	_, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, []string{addr1})
+	assert.ErrorIs(t, err, ErrTooManyJobs)
+
+	// a finished job frees its slot
+	close(release)
+	assert.Eventually(t, func() bool {
+		job, _ := parser.GetBulkJob(context.TODO(), ids[0])
+		return job.State == JobCompleted
+	}, time.Second, 10*time.Millisecond)
+	job, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, []string{addr1})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		job, _ := parser.GetBulkJob(context.TODO(), job.ID)
+		return job.State == JobCompleted
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestParserService_StopBulkJobs(t *testing.T) {
+	release := make(chan time.Time)
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddresses", mock.Anything, []string{addr1}).WaitUntil(release).Return([]bool{true}, nil)
+
+	parser := NewParserService(repo)
+	job, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, []string{addr1})
+	assert.NoError(t, err)
+
+	stopped := make(chan error)
+	go func() { stopped <- parser.StopBulkJobs(context.Background()) }()
+	// the running job is waited for, the new ones are rejected
+	assert.Eventually(t, func() bool {
+		_, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, []string{addr2})
+		return errors.Is(err, ErrJobsStopped)
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the job finished")
+	default:
+	}
+
+	close(release)
+	assert.NoError(t, <-stopped)
+	job, _ = parser.GetBulkJob(context.TODO(), job.ID)
+	assert.Equal(t, JobCompleted, job.State)
+	assert.Equal(t, 1, job.Counts[StatusAdded])
+}
+
+func TestParserService_StopBulkJobs_cancel(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	// the first batch waits for the job to be canceled
+	repo.On("AddAddresses", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled).Once()
+
+	addresses := make([]string, bulkBatchSize+1)
+	for i := range addresses {
+		addresses[i] = addr1
+	}
+	parser := NewParserService(repo)
+	job, err := parser.StartBulkJob(context.TODO(), BulkSubscribe, addresses)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, parser.StopBulkJobs(ctx), context.DeadlineExceeded)
+
+	// the job is over, the remaining batch failed without reaching the repository
+	job, _ = parser.GetBulkJob(context.TODO(), job.ID)
+	assert.Equal(t, JobCompleted, job.State)
+	assert.Equal(t, bulkBatchSize+1, job.Counts[StatusFailed])
+	repo.AssertNumberOfCalls(t, "AddAddresses", 1)
+}
@@ -0,0 +1,18 @@
+package parser
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidAddress      = errors.New("invalid address")
+	ErrAlreadySubscribed   = errors.New("address already subscribed")
+	ErrAddressNotFound     = errors.New("address not found")
+	ErrStorageUnavailable  = errors.New("storage unavailable")
+	ErrInvalidHash         = errors.New("invalid transaction hash")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrBlockNotFound       = errors.New("block not parsed yet")
+	ErrNodeUnavailable     = errors.New("node unavailable")
+	ErrStatsUnavailable    = errors.New("address stats are not tracked")
+	ErrInvalidLabel        = errors.New("invalid label")
+)
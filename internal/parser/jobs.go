@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// maxFinishedJobs is the number of finished jobs kept in memory for progress lookup
+	maxFinishedJobs = 100
+	// maxRunningJobs is the number of bulk jobs running at once, more are rejected until one finishes
+	maxRunningJobs = 4
+	// maxJobIssues is the number of failed and invalid results kept by a job, its counts cover all of them
+	maxJobIssues = 10_000
+)
+
+var (
+	ErrUnknownBulkAction = errors.New("unknown bulk action")
+	ErrTooManyJobs       = errors.New("too many bulk jobs running")
+	ErrJobsStopped       = errors.New("bulk jobs are stopped")
+)
+
+// JobState is the lifecycle state of an asynchronous bulk job
+type JobState string
+
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobCompleted JobState = "completed"
+)
+
+// BulkJob is a snapshot of the progress of an asynchronous bulk subscription job, its failed and
+// invalid results are paged by GetBulkJobIssues
+type BulkJob struct {
+	ID         string                     `json:"id"`
+	Action     BulkAction                 `json:"action"`
+	State      JobState                   `json:"state"`
+	Total      int                        `json:"total"`
+	Processed  int                        `json:"processed"`
+	Counts     map[SubscriptionStatus]int `json:"counts"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	FinishedAt *time.Time                 `json:"finishedAt,omitempty"`
+}
+
+// job is a bulk job with the results which need the attention of the client
+type job struct {
+	BulkJob
+	issues []SubscriptionResult
+}
+
+type jobStore struct {
+	mu       sync.RWMutex
+	jobs     map[string]*job
+	finished []string
+	running  int
+
+	// stopped rejects the new jobs, wg tracks the goroutines of the running ones and
+	// ctx is their parent, it is canceled to abort them
+	stopped bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newJobStore() *jobStore {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobStore{jobs: make(map[string]*job), ctx: ctx, cancel: cancel}
+}
+
+// add stores a new job, it fails when maxRunningJobs jobs are not finished yet or the store is stopped.
+// The job is tracked until done is called.
+func (s *jobStore) add(j *job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return ErrJobsStopped
+	}
+	if s.running >= maxRunningJobs {
+		return ErrTooManyJobs
+	}
+	s.running++
+	s.jobs[j.ID] = j
+	s.wg.Add(1)
+	return nil
+}
+
+// done releases a job tracked by add
+func (s *jobStore) done() {
+	s.wg.Done()
+}
+
+// stop rejects the new jobs and waits for the running ones, they are canceled when ctx is done first
+func (s *jobStore) stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		// the jobs observe the cancellation, wait for them to return
+		<-finished
+		return ctx.Err()
+	}
+}
+
+func (s *jobStore) update(id string, fn func(j *job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	fn(j)
+
+	if j.State == JobCompleted {
+		s.running--
+		// evict the oldest finished jobs to bound memory usage
+		s.finished = append(s.finished, id)
+		for len(s.finished) > maxFinishedJobs {
+			delete(s.jobs, s.finished[0])
+			s.finished = s.finished[1:]
+		}
+	}
+}
+
+func (s *jobStore) get(id string) (BulkJob, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return BulkJob{}, false
+	}
+
+	snapshot := j.BulkJob
+	snapshot.Counts = make(map[SubscriptionStatus]int, len(j.Counts))
+	for status, count := range j.Counts {
+		snapshot.Counts[status] = count
+	}
+
+	return snapshot, true
+}
+
+// issues return a page of the failed and invalid results of a job
+func (s *jobStore) issues(id string, offset, limit int) ([]SubscriptionResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	if offset >= len(j.issues) {
+		return []SubscriptionResult{}, true
+	}
+	end := offset + limit
+	if end > len(j.issues) {
+		end = len(j.issues)
+	}
+
+	return append([]SubscriptionResult(nil), j.issues[offset:end]...), true
+}
+
+// StartBulkJob run a bulk action in background and return the created job, ErrTooManyJobs when
+// maxRunningJobs jobs are running and ErrJobsStopped after StopBulkJobs. The job outlives the request
+// so it keeps the context values but not its cancellation, it is canceled by StopBulkJobs instead.
+func (p *parserService) StartBulkJob(ctx context.Context, action BulkAction, addresses []string) (BulkJob, error) {
+	if action != BulkSubscribe && action != BulkUnsubscribe {
+		return BulkJob{}, ErrUnknownBulkAction
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return BulkJob{}, err
+	}
+
+	j := &job{BulkJob: BulkJob{
+		ID:        id,
+		Action:    action,
+		State:     JobPending,
+		Total:     len(addresses),
+		Counts:    make(map[SubscriptionStatus]int),
+		CreatedAt: time.Now(),
+	}}
+	if err := p.jobs.add(j); err != nil {
+		return BulkJob{}, err
+	}
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stopCancel := context.AfterFunc(p.jobs.ctx, cancel)
+	go func() {
+		defer p.jobs.done()
+		defer cancel()
+		defer stopCancel()
+
+		p.jobs.update(id, func(j *job) {
+			j.State = JobRunning
+		})
+
+		p.applyBulk(jobCtx, action, addresses, func(batch []SubscriptionResult) {
+			p.jobs.update(id, func(j *job) {
+				j.Processed += len(batch)
+				for _, res := range batch {
+					j.Counts[res.Status]++
+					if (res.Status == StatusFailed || res.Status == StatusInvalid) && len(j.issues) < maxJobIssues {
+						j.issues = append(j.issues, res)
+					}
+				}
+			})
+		})
+
+		p.jobs.update(id, func(j *job) {
+			now := time.Now()
+			j.State = JobCompleted
+			j.FinishedAt = &now
+		})
+	}()
+
+	snapshot, _ := p.jobs.get(id)
+	return snapshot, nil
+}
+
+// StopBulkJobs reject the new bulk jobs and wait for the running ones to finish, they are canceled
+// when ctx is done first and fail their remaining addresses
+func (p *parserService) StopBulkJobs(ctx context.Context) error {
+	return p.jobs.stop(ctx)
+}
+
+// GetBulkJob return the progress of a bulk job
+func (p *parserService) GetBulkJob(ctx context.Context, id string) (BulkJob, bool) {
+	return p.jobs.get(id)
+}
+
+// GetBulkJobIssues return a page of the failed and invalid results of a bulk job, in the order of its addresses
+func (p *parserService) GetBulkJobIssues(ctx context.Context, id string, offset, limit int) ([]SubscriptionResult, bool) {
+	return p.jobs.issues(id, offset, limit)
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
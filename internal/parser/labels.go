@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TrustWallet/tx-parser/internal/labels"
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+const (
+	maxLabelLen = 100
+	maxTags     = 20
+	maxTagLen   = 50
+)
+
+// WithLabelBook labels the well-known addresses of the book, e.g. the exchanges, the subscribers'
+// labels replace them
+func WithLabelBook(book *labels.Book) Option {
+	return func(p *parserService) {
+		p.book = book
+	}
+}
+
+// SetLabel replace the label of a subscribed address, the label and the tags are trimmed and the empty
+// or duplicate tags dropped. A zero label removes it.
+func (p *parserService) SetLabel(ctx context.Context, address string, label types.AddressLabel) error {
+	if err := p.validateAddress(address); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+	label, err := normalizeLabel(label)
+	if err != nil {
+		return err
+	}
+
+	if err := p.repo.SetLabel(ctx, address, label); err != nil {
+		if errors.Is(err, repository.ErrAddressNotFound) {
+			return ErrAddressNotFound
+		}
+
+		slog.ErrorContext(ctx, "error setting label", slog.String(logging.KeyAddress, address), logging.Err(err))
+		return storageError(err)
+	}
+
+	return nil
+}
+
+// GetLabels return the labels of the addresses keyed by normalized address, the label a subscriber set
+// replaces the one of the book, the addresses without label are left out
+func (p *parserService) GetLabels(ctx context.Context, addresses []string) (map[string]types.AddressLabel, error) {
+	labels, err := p.repo.GetLabels(ctx, addresses)
+	if err != nil {
+		slog.ErrorContext(ctx, "error getting labels", logging.Err(err))
+		return nil, storageError(err)
+	}
+
+	for _, address := range addresses {
+		key := utils.NormalizeAddress(address)
+		if _, ok := labels[key]; ok {
+			continue
+		}
+		if label, ok := p.book.Lookup(address); ok {
+			labels[key] = label
+		}
+	}
+
+	return labels, nil
+}
+
+func normalizeLabel(label types.AddressLabel) (types.AddressLabel, error) {
+	normalized := types.AddressLabel{Label: strings.TrimSpace(label.Label)}
+	if utf8.RuneCountInString(normalized.Label) > maxLabelLen {
+		return normalized, fmt.Errorf("%w: label is longer than %d characters", ErrInvalidLabel, maxLabelLen)
+	}
+
+	seen := make(map[string]bool, len(label.Tags))
+	for _, tag := range label.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLen {
+			return normalized, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidLabel, tag, maxTagLen)
+		}
+		seen[tag] = true
+		normalized.Tags = append(normalized.Tags, tag)
+	}
+	if len(normalized.Tags) > maxTags {
+		return normalized, fmt.Errorf("%w: more than %d tags", ErrInvalidLabel, maxTags)
+	}
+
+	return normalized, nil
+}
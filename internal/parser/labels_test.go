@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/labels"
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParserService_Labels(t *testing.T) {
+	ctx := context.TODO()
+	const exchange = "0x28c6c06298d514db089934071355e5743bf21d60"
+	book, err := labels.ParseBook(strings.NewReader(`{"labels": [
+		{"address": "` + exchange + `", "label": "Binance 14", "tags": ["exchange"]},
+		{"address": "` + addr1 + `", "label": "Known", "tags": ["book"]}
+	]}`))
+	assert.NoError(t, err)
+	repo := repository.NewInMemRepo()
+	assert.NoError(t, repo.AddAddress(ctx, addr1))
+	parser := NewParserService(repo, WithLabelBook(book))
+
+	assert.NoError(t, parser.SetLabel(ctx, addr1, types.AddressLabel{Label: " hot wallet ", Tags: []string{"treasury", " ", "treasury", "ops "}}))
+	assert.ErrorIs(t, parser.SetLabel(ctx, addr2, types.AddressLabel{Label: "customer 123"}), ErrAddressNotFound)
+	assert.ErrorIs(t, parser.SetLabel(ctx, "test", types.AddressLabel{Label: "customer 123"}), ErrInvalidAddress)
+	assert.ErrorIs(t, parser.SetLabel(ctx, addr1, types.AddressLabel{Label: strings.Repeat("a", 101)}), ErrInvalidLabel)
+	var tags []string
+	for i := 0; i < 21; i++ {
+		tags = append(tags, fmt.Sprintf("tag%d", i))
+	}
+	assert.ErrorIs(t, parser.SetLabel(ctx, addr1, types.AddressLabel{Tags: tags}), ErrInvalidLabel)
+
+	got, err := parser.GetLabels(ctx, []string{strings.ToUpper(addr1), exchange, addr2})
+	assert.NoError(t, err)
+	// the subscriber's label replaces the one of the book
+	assert.Equal(t, map[string]types.AddressLabel{
+		addr1:    {Label: "hot wallet", Tags: []string{"treasury", "ops"}},
+		exchange: {Label: "Binance 14", Tags: []string{"exchange"}},
+	}, got)
+
+	// without book
+	got, err = NewParserService(repo).GetLabels(ctx, []string{exchange})
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestParserService_Labels_storageError(t *testing.T) {
+	ctx := context.TODO()
+	repo := mocks.NewRepository(t)
+	repo.On("SetLabel", mock.Anything, addr1, mock.Anything).Return(fmt.Errorf("some error"))
+	repo.On("GetLabels", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("some error"))
+	parser := NewParserService(repo)
+
+	assert.ErrorIs(t, parser.SetLabel(ctx, addr1, types.AddressLabel{Label: "a"}), ErrStorageUnavailable)
+	_, err := parser.GetLabels(ctx, []string{addr1})
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+}
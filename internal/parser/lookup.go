@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Source tells where a looked up transaction was found
+type Source string
+
+const (
+	SourceStorage Source = "storage"
+	SourceNode    Source = "node"
+)
+
+// AddressMatch is a subscribed address of a transaction
+type AddressMatch struct {
+	Address   string          `json:"address"`
+	Direction types.Direction `json:"direction"`
+}
+
+// TransactionLookup is a transaction with the subscribed addresses it matched
+type TransactionLookup struct {
+	Transaction types.Transaction `json:"transaction"`
+	Matches     []AddressMatch    `json:"matches"`
+	Source      Source            `json:"source"`
+}
+
+// TransactionFetcher return a transaction from the node, nil when the node does not know it
+type TransactionFetcher func(ctx context.Context, hash string) (*types.Transaction, error)
+
+// WithTransactionFetcher looks up on the node the transactions which are not stored
+func WithTransactionFetcher(fetch TransactionFetcher) Option {
+	return func(p *parserService) {
+		p.fetchTransaction = fetch
+	}
+}
+
+// GetTransaction return a transaction by hash from the storage, or from the node when it is not stored
+func (p *parserService) GetTransaction(ctx context.Context, hash string) (TransactionLookup, error) {
+	hash, ok := p.normalizeTxHash(hash)
+	if !ok {
+		return TransactionLookup{}, ErrInvalidHash
+	}
+
+	indexed, err := p.repo.GetTransactionByHash(ctx, hash)
+	if err == nil {
+		return newLookup(indexed.Transaction, indexed.Addresses, SourceStorage), nil
+	}
+	if !errors.Is(err, repository.ErrTransactionNotFound) {
+		slog.ErrorContext(ctx, "error getting transaction", slog.String(logging.KeyTxHash, hash), logging.Err(err))
+		return TransactionLookup{}, storageError(err)
+	}
+	if p.fetchTransaction == nil {
+		return TransactionLookup{}, ErrTransactionNotFound
+	}
+
+	tx, err := p.fetchTransaction(ctx, hash)
+	if err != nil {
+		slog.WarnContext(ctx, "error fetching transaction from node", slog.String(logging.KeyTxHash, hash), logging.Err(err))
+		return TransactionLookup{}, fmt.Errorf("%w: %w", ErrNodeUnavailable, err)
+	}
+	if tx == nil {
+		return TransactionLookup{}, ErrTransactionNotFound
+	}
+
+	// the transaction was not saved, it is matched against the current subscriptions
+	addresses, err := p.repo.GetAddresses(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error getting addresses", logging.Err(err))
+		return TransactionLookup{}, storageError(err)
+	}
+	subscribed := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		subscribed[utils.NormalizeAddress(address)] = struct{}{}
+	}
+	var matched []string
+	for _, address := range tx.Addresses() {
+		if _, ok := subscribed[utils.NormalizeAddress(address)]; ok {
+			matched = append(matched, address)
+		}
+	}
+
+	return newLookup(*tx, matched, SourceNode), nil
+}
+
+// GetBlockTransactions return the stored transactions of a parsed block
+func (p *parserService) GetBlockTransactions(ctx context.Context, blockNumber uint64) ([]TransactionLookup, error) {
+	indexed, err := p.repo.GetBlockTransactions(ctx, blockNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrBlockNotFound) {
+			return nil, ErrBlockNotFound
+		}
+
+		slog.ErrorContext(ctx, "error getting block transactions",
+			slog.Uint64(logging.KeyBlockNumber, blockNumber), logging.Err(err))
+		return nil, storageError(err)
+	}
+
+	lookups := make([]TransactionLookup, len(indexed))
+	for i, tx := range indexed {
+		lookups[i] = newLookup(tx.Transaction, tx.Addresses, SourceStorage)
+	}
+
+	return lookups, nil
+}
+
+func newLookup(tx types.Transaction, addresses []string, source Source) TransactionLookup {
+	matches := make([]AddressMatch, len(addresses))
+	for i, address := range addresses {
+		matches[i] = AddressMatch{Address: address, Direction: tx.Direction(address)}
+	}
+
+	return TransactionLookup{Transaction: tx, Matches: matches, Source: source}
+}
+
+// normalizeTxHash checks the hash is 32 hex bytes, with or without 0x prefix, and return it in the
+// form of the chain: with the 0x prefix on the EVM chains, bare as the txid of the UTXO chains
+func (p *parserService) normalizeTxHash(hash string) (string, bool) {
+	raw := strings.TrimPrefix(strings.TrimPrefix(hash, "0x"), "0X")
+	if len(raw) != 64 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(raw); err != nil {
+		return "", false
+	}
+	return p.hashPrefix + raw, true
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const txHash = "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+
+func TestParserService_GetTransaction(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	stored := types.Transaction{Hash: txHash, From: addr1, To: addr1, BlockNumber: 10}
+	repo.On("GetTransactionByHash", mock.Anything, txHash).Return(repository.IndexedTransaction{
+		Transaction: stored,
+		Addresses:   []string{addr1},
+	}, nil)
+
+	parser := NewParserService(repo)
+	lookup, err := parser.GetTransaction(context.TODO(), txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, TransactionLookup{
+		Transaction: stored,
+		Matches:     []AddressMatch{{Address: addr1, Direction: types.DirectionSelf}},
+		Source:      SourceStorage,
+	}, lookup)
+
+	_, err = parser.GetTransaction(context.TODO(), "0x1234")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+}
+
+func TestParserService_GetTransaction_node(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetTransactionByHash", mock.Anything, mock.Anything).Return(repository.IndexedTransaction{}, repository.ErrTransactionNotFound)
+	repo.On("GetAddresses", mock.Anything).Return([]string{addr1}, nil).Once()
+
+	pending := types.Transaction{Hash: txHash, From: addr2, To: strings.ToUpper(addr1)}
+	fetched := map[string]*types.Transaction{txHash: &pending}
+	parser := NewParserService(repo, WithTransactionFetcher(func(ctx context.Context, hash string) (*types.Transaction, error) {
+		if hash == "0x"+strings.Repeat("ab", 32) {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return fetched[hash], nil
+	}))
+
+	lookup, err := parser.GetTransaction(context.TODO(), txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceNode, lookup.Source)
+	assert.Equal(t, pending, lookup.Transaction)
+	assert.Equal(t, []AddressMatch{{Address: strings.ToUpper(addr1), Direction: types.DirectionInbound}}, lookup.Matches)
+
+	_, err = parser.GetTransaction(context.TODO(), "0x"+strings.Repeat("00", 32))
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	// a hash without prefix is looked up with the 0x prefix of the EVM chains
+	_, err = parser.GetTransaction(context.TODO(), strings.Repeat("ab", 32))
+	assert.ErrorIs(t, err, ErrNodeUnavailable)
+}
+
+func TestParserService_GetTransaction_bareTxHashes(t *testing.T) {
+	txid := strings.TrimPrefix(txHash, "0x")
+	repo := mocks.NewRepository(t)
+	repo.On("GetTransactionByHash", mock.Anything, txid).Return(repository.IndexedTransaction{}, repository.ErrTransactionNotFound)
+
+	var fetched []string
+	parser := NewParserService(repo, WithBareTxHashes(), WithTransactionFetcher(func(ctx context.Context, hash string) (*types.Transaction, error) {
+		fetched = append(fetched, hash)
+		return nil, nil
+	}))
+
+	// the txid of a UTXO chain is looked up without prefix, however it is given
+	for _, hash := range []string{txid, txHash, "0X" + txid} {
+		_, err := parser.GetTransaction(context.TODO(), hash)
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	}
+	assert.Equal(t, []string{txid, txid, txid}, fetched)
+}
+
+func TestParserService_GetTransaction_base58(t *testing.T) {
+	const subscribed, other = "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "1BvBMSEYstWetqTFn5Au4m4GFg7xJanvn2"
+	repo := mocks.NewRepository(t)
+	repo.On("GetTransactionByHash", mock.Anything, mock.Anything).Return(repository.IndexedTransaction{}, repository.ErrTransactionNotFound)
+	repo.On("GetAddresses", mock.Anything).Return([]string{subscribed}, nil)
+
+	pending := types.Transaction{Hash: txHash, From: other, To: subscribed,
+		Inputs: []types.UTXO{{Address: other}}, Outputs: []types.UTXO{{Address: subscribed}}}
+	parser := NewParserService(repo, WithTransactionFetcher(func(ctx context.Context, hash string) (*types.Transaction, error) {
+		return &pending, nil
+	}))
+
+	// the addresses differing only in case are distinct, the matched one is returned as is
+	lookup, err := parser.GetTransaction(context.TODO(), txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, []AddressMatch{{Address: subscribed, Direction: types.DirectionInbound}}, lookup.Matches)
+}
+
+func TestParserService_GetTransaction_notFound(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetTransactionByHash", mock.Anything, txHash).Return(repository.IndexedTransaction{}, repository.ErrTransactionNotFound).Once()
+	repo.On("GetTransactionByHash", mock.Anything, txHash).Return(repository.IndexedTransaction{}, fmt.Errorf("some error")).Once()
+
+	parser := NewParserService(repo)
+	_, err := parser.GetTransaction(context.TODO(), txHash)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	_, err = parser.GetTransaction(context.TODO(), txHash)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+}
+
+func TestParserService_GetBlockTransactions(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	repo.On("GetBlockTransactions", mock.Anything, uint64(10)).Return([]repository.IndexedTransaction{
+		{Transaction: types.Transaction{Hash: txHash, From: addr1}, Addresses: []string{addr1}},
+	}, nil)
+	repo.On("GetBlockTransactions", mock.Anything, uint64(11)).Return(nil, repository.ErrBlockNotFound)
+
+	parser := NewParserService(repo)
+	lookups, err := parser.GetBlockTransactions(context.TODO(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, lookups, 1) {
+		assert.Equal(t, txHash, lookups[0].Transaction.Hash)
+		assert.Equal(t, []AddressMatch{{Address: addr1, Direction: types.DirectionOutbound}}, lookups[0].Matches)
+		assert.Equal(t, SourceStorage, lookups[0].Source)
+	}
+
+	_, err = parser.GetBlockTransactions(context.TODO(), 11)
+	assert.ErrorIs(t, err, ErrBlockNotFound)
+}
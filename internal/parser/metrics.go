@@ -0,0 +1,8 @@
+package parser
+
+import (
+	"github.com/TrustWallet/tx-parser/internal/metrics"
+)
+
+var subscriptionOps = metrics.Default.NewCounterVec("txparser_subscription_operations_total",
+	"Subscription changes by action and outcome.", "action", "status")
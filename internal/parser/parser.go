@@ -2,59 +2,222 @@ package parser
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 
+	"github.com/TrustWallet/tx-parser/internal/analytics"
+	"github.com/TrustWallet/tx-parser/internal/labels"
+	"github.com/TrustWallet/tx-parser/internal/logging"
 	"github.com/TrustWallet/tx-parser/internal/repository"
 	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
 )
 
 type Parser interface {
 	// GetCurrentBlock return last parsed block
-	GetCurrentBlock() int
+	GetCurrentBlock(ctx context.Context) (int, error)
 
 	// Subscribe add address to observer
-	Subscribe(address string) bool
+	Subscribe(ctx context.Context, address string) error
 
-	// GetTransactions list of inbound or outbound transactions for an address
-	GetTransactions(address string) []types.Transaction
+	// GetTransactions list of inbound or outbound transactions for an address in every parsed block,
+	// the QueryTransactions without filter
+	GetTransactions(ctx context.Context, address string) ([]types.Transaction, error)
+
+	// QueryTransactions list of transactions for an address matching the filter in every parsed block
+	QueryTransactions(ctx context.Context, address string, filter repository.TransactionFilter) ([]types.Transaction, error)
+
+	// ScanTransactions calls fn with the transactions QueryTransactions would return, one at a time
+	ScanTransactions(ctx context.Context, address string, filter repository.TransactionFilter, fn func(types.Transaction) error) error
+
+	// Decimals return the number of decimals of the native unit of the chain, e.g. 18 for ETH
+	Decimals() int
+
+	// GetAddressStats return the activity aggregates of an address
+	GetAddressStats(ctx context.Context, address string) (analytics.Stats, error)
+
+	// BulkSubscribe add a list of addresses to observer
+	BulkSubscribe(ctx context.Context, addresses []string) []SubscriptionResult
+
+	// BulkUnsubscribe remove a list of addresses from observer
+	BulkUnsubscribe(ctx context.Context, addresses []string) []SubscriptionResult
+
+	// StartBulkJob run a bulk action asynchronously
+	StartBulkJob(ctx context.Context, action BulkAction, addresses []string) (BulkJob, error)
+
+	// GetBulkJob return the progress of an asynchronous bulk job
+	GetBulkJob(ctx context.Context, id string) (BulkJob, bool)
+
+	// GetBulkJobIssues return a page of the failed and invalid results of an asynchronous bulk job
+	GetBulkJobIssues(ctx context.Context, id string, offset, limit int) ([]SubscriptionResult, bool)
+
+	// GetTransaction return a transaction by hash with the subscribed addresses it matched
+	GetTransaction(ctx context.Context, hash string) (TransactionLookup, error)
+
+	// GetBlockTransactions return the stored transactions of a parsed block
+	GetBlockTransactions(ctx context.Context, blockNumber uint64) ([]TransactionLookup, error)
+
+	// SetLabel replace the label of a subscribed address, a zero label removes it
+	SetLabel(ctx context.Context, address string, label types.AddressLabel) error
+
+	// GetLabels return the labels of the subscribed and the well-known addresses among addresses
+	GetLabels(ctx context.Context, addresses []string) (map[string]types.AddressLabel, error)
 }
 
 type parserService struct {
 	repo repository.Repository
+	jobs *jobStore
+	// validateAddress checks an address of the chain
+	validateAddress func(address string) error
+	// fetchTransaction looks up on the node the transactions which are not stored, nil to disable
+	fetchTransaction TransactionFetcher
+	// decimals of the native unit of the chain
+	decimals int
+	// hashPrefix is the prefix of the transaction hashes of the chain, "0x" on the EVM chains
+	hashPrefix string
+	// book labels the well-known addresses of the chain, nil when there is none
+	book *labels.Book
 }
 
-func NewParserService(repo repository.Repository) *parserService {
-	return &parserService{repo: repo}
+// Option configures the parser service
+type Option func(p *parserService)
+
+// WithAddressValidator replaces the EVM address validation, e.g. by the one of a UTXO chain
+func WithAddressValidator(validate func(address string) error) Option {
+	return func(p *parserService) {
+		p.validateAddress = validate
+	}
+}
+
+// WithDecimals replaces the 18 decimals of ether by the ones of the native unit of the chain, e.g. 8 for bitcoin
+func WithDecimals(decimals int) Option {
+	return func(p *parserService) {
+		p.decimals = decimals
+	}
+}
+
+// WithBareTxHashes replaces the 0x-prefixed EVM transaction hashes by the bare hex txids of a UTXO chain
+func WithBareTxHashes() Option {
+	return func(p *parserService) {
+		p.hashPrefix = ""
+	}
+}
+
+func NewParserService(repo repository.Repository, opts ...Option) *parserService {
+	p := &parserService{repo: repo, jobs: newJobStore(), validateAddress: utils.ValidateAddress, decimals: 18, hashPrefix: "0x"}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
 }
 
 // GetCurrentBlock return last parsed block
-func (p *parserService) GetCurrentBlock() int {
-	blockNum, err := p.repo.GetCurrentBlock(context.Background())
+func (p *parserService) GetCurrentBlock(ctx context.Context) (int, error) {
+	blockNum, err := p.repo.GetCurrentBlock(ctx)
 	if err != nil {
-		log.Printf("Error getting current block: %v", err)
+		slog.ErrorContext(ctx, "error getting current block", logging.Err(err))
+		return 0, storageError(err)
 	}
 
-	return int(blockNum)
+	return int(blockNum), nil
 }
 
 // Subscribe add address to observer
-func (p *parserService) Subscribe(address string) bool {
-	err := p.repo.AddAddress(context.Background(), address)
+func (p *parserService) Subscribe(ctx context.Context, address string) error {
+	if err := p.validateAddress(address); err != nil {
+		subscriptionOps.With(string(BulkSubscribe), string(StatusInvalid)).Inc()
+		return fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+
+	err := p.repo.AddAddress(ctx, address)
 	if err != nil {
-		log.Printf("Error subcribe address %s: %v", address, err)
-		return false
+		if errors.Is(err, repository.ErrAddressExists) {
+			subscriptionOps.With(string(BulkSubscribe), string(StatusAlreadySubscribed)).Inc()
+			return ErrAlreadySubscribed
+		}
+
+		slog.ErrorContext(ctx, "error subscribing address", slog.String(logging.KeyAddress, address), logging.Err(err))
+		subscriptionOps.With(string(BulkSubscribe), string(StatusFailed)).Inc()
+		return storageError(err)
 	}
 
-	return true
+	subscriptionOps.With(string(BulkSubscribe), string(StatusAdded)).Inc()
+	return nil
+}
+
+// GetTransactions list of inbound or outbound transactions for an address in every parsed block
+func (p *parserService) GetTransactions(ctx context.Context, address string) ([]types.Transaction, error) {
+	return p.QueryTransactions(ctx, address, repository.TransactionFilter{})
 }
 
-// GetTransactions list of inbound or outbound transactions for an address
-func (p *parserService) GetTransactions(address string) []types.Transaction {
-	txns, err := p.repo.GetTransactions(context.Background(), address)
+// QueryTransactions list of transactions for an address matching the filter in every parsed block
+func (p *parserService) QueryTransactions(ctx context.Context, address string, filter repository.TransactionFilter) ([]types.Transaction, error) {
+	if err := p.validateQuery(address, filter); err != nil {
+		return nil, err
+	}
+
+	txns, err := p.repo.QueryTransactions(ctx, address, filter)
 	if err != nil {
-		log.Printf("Error get transactions for address %s: %v", address, err)
-		return nil
+		return nil, p.queryError(ctx, address, err)
+	}
+	if txns == nil {
+		txns = []types.Transaction{}
+	}
+
+	return txns, nil
+}
+
+// ScanTransactions calls fn with the transactions of an address matching the filter in every parsed block,
+// an error of fn is returned as is
+func (p *parserService) ScanTransactions(ctx context.Context, address string, filter repository.TransactionFilter, fn func(types.Transaction) error) error {
+	if err := p.validateQuery(address, filter); err != nil {
+		return err
+	}
+
+	var fnErr error
+	err := p.repo.ScanTransactions(ctx, address, filter, func(tx types.Transaction) error {
+		fnErr = fn(tx)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return p.queryError(ctx, address, err)
 	}
 
-	return txns
+	return err
+}
+
+// Decimals return the number of decimals of the native unit of the chain
+func (p *parserService) Decimals() int {
+	return p.decimals
+}
+
+// validateQuery checks the address and the counterparty of a query
+func (p *parserService) validateQuery(address string, filter repository.TransactionFilter) error {
+	if err := p.validateAddress(address); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+	if filter.Counterparty != "" {
+		if err := p.validateAddress(filter.Counterparty); err != nil {
+			return fmt.Errorf("%w: counterparty: %w", ErrInvalidAddress, err)
+		}
+	}
+
+	return nil
+}
+
+// queryError maps the repository error of a query
+func (p *parserService) queryError(ctx context.Context, address string, err error) error {
+	if errors.Is(err, repository.ErrAddressNotFound) {
+		return ErrAddressNotFound
+	}
+
+	slog.ErrorContext(ctx, "error querying transactions", slog.String(logging.KeyAddress, address), logging.Err(err))
+	return storageError(err)
+}
+
+// storageError wraps an unexpected repository error as ErrStorageUnavailable
+func storageError(err error) error {
+	return fmt.Errorf("%w: %w", ErrStorageUnavailable, err)
 }
@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/repository"
 	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -15,7 +18,8 @@ func TestParserService_GetCurrentBlock(t *testing.T) {
 
 	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(10), nil)
 	parser := NewParserService(repo)
-	num := parser.GetCurrentBlock()
+	num, err := parser.GetCurrentBlock(context.TODO())
+	assert.NoError(t, err)
 	assert.Equal(t, 10, num)
 }
 
@@ -24,7 +28,8 @@ func TestParserService_GetCurrentBlock_error(t *testing.T) {
 	repo.On("GetCurrentBlock", mock.Anything).Return(uint64(0), fmt.Errorf("some error"))
 
 	parser := NewParserService(repo)
-	num := parser.GetCurrentBlock()
+	num, err := parser.GetCurrentBlock(context.TODO())
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
 	assert.Equal(t, 0, num)
 }
 
@@ -34,37 +39,140 @@ func TestParserService_GetTransactions(t *testing.T) {
 	fakeTxns := []types.Transaction{
 		{
 			BlockNumber: 10,
-			From:        "test",
+			From:        addr1,
 		},
 		{
 			BlockNumber: 10,
-			To:          "test",
+			To:          addr1,
 		},
 	}
-	repo.On("GetTransactions", mock.Anything, "test").Return(fakeTxns, nil)
+	repo.On("QueryTransactions", mock.Anything, addr1, repository.TransactionFilter{}).Return(fakeTxns, nil)
 	parser := NewParserService(repo)
-	transactions := parser.GetTransactions("test")
+	transactions, err := parser.GetTransactions(context.TODO(), addr1)
+	assert.NoError(t, err)
 	assert.Len(t, transactions, 2)
 }
 
 func TestParserService_GetTransactions_error(t *testing.T) {
 	repo := mocks.NewRepository(t)
 
-	repo.On("GetTransactions", mock.Anything, "test").Return(nil, nil)
+	repo.On("QueryTransactions", mock.Anything, addr1, repository.TransactionFilter{}).Return(nil, nil)
 	parser := NewParserService(repo)
-	transactions := parser.GetTransactions("test")
+	transactions, err := parser.GetTransactions(context.TODO(), addr1)
+	assert.NoError(t, err)
+	assert.NotNil(t, transactions)
 	assert.Len(t, transactions, 0)
+
+	_, err = parser.GetTransactions(context.TODO(), "test")
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+	repo.AssertNumberOfCalls(t, "QueryTransactions", 1)
+
+	repo.On("QueryTransactions", mock.Anything, addr2, repository.TransactionFilter{}).Return(nil, repository.ErrAddressNotFound).Once()
+	_, err = parser.GetTransactions(context.TODO(), addr2)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+
+	repo.On("QueryTransactions", mock.Anything, addr2, repository.TransactionFilter{}).Return(nil, fmt.Errorf("some error")).Once()
+	_, err = parser.GetTransactions(context.TODO(), addr2)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+}
+
+func TestParserService_QueryTransactions(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	filter := repository.TransactionFilter{Direction: types.DirectionInbound, Counterparty: addr2}
+	repo.On("QueryTransactions", mock.Anything, addr1, filter).Return([]types.Transaction{{Hash: "hash1"}}, nil).Once()
+	repo.On("QueryTransactions", mock.Anything, addr1, filter).Return(nil, repository.ErrAddressNotFound).Once()
+	repo.On("QueryTransactions", mock.Anything, addr1, filter).Return(nil, fmt.Errorf("some error")).Once()
+
+	parser := NewParserService(repo)
+	transactions, err := parser.QueryTransactions(context.TODO(), addr1, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Transaction{{Hash: "hash1"}}, transactions)
+	_, err = parser.QueryTransactions(context.TODO(), addr1, filter)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+	_, err = parser.QueryTransactions(context.TODO(), addr1, filter)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+
+	_, err = parser.QueryTransactions(context.TODO(), addr1, repository.TransactionFilter{Counterparty: "test"})
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+	assert.ErrorContains(t, err, "counterparty")
+	repo.AssertNumberOfCalls(t, "QueryTransactions", 3)
+}
+
+func TestParserService_ScanTransactions(t *testing.T) {
+	repo := mocks.NewRepository(t)
+	scan := func(ctx context.Context, address string, filter repository.TransactionFilter, fn func(types.Transaction) error) error {
+		for _, hash := range []string{"hash1", "hash2"} {
+			if err := fn(types.Transaction{Hash: hash}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	repo.On("ScanTransactions", mock.Anything, addr1, repository.TransactionFilter{}, mock.Anything).Return(scan).Twice()
+	repo.On("ScanTransactions", mock.Anything, addr2, repository.TransactionFilter{}, mock.Anything).Return(repository.ErrAddressNotFound).Once()
+	repo.On("ScanTransactions", mock.Anything, addr2, repository.TransactionFilter{}, mock.Anything).Return(fmt.Errorf("some error")).Once()
+
+	parser := NewParserService(repo)
+	var scanned []string
+	err := parser.ScanTransactions(context.TODO(), addr1, repository.TransactionFilter{}, func(tx types.Transaction) error {
+		scanned = append(scanned, tx.Hash)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hash1", "hash2"}, scanned)
+
+	// the error of the callback is not a storage error
+	stop := fmt.Errorf("client gone")
+	err = parser.ScanTransactions(context.TODO(), addr1, repository.TransactionFilter{}, func(types.Transaction) error { return stop })
+	assert.Equal(t, stop, err)
+
+	err = parser.ScanTransactions(context.TODO(), addr2, repository.TransactionFilter{}, func(types.Transaction) error { return nil })
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+	err = parser.ScanTransactions(context.TODO(), addr2, repository.TransactionFilter{}, func(types.Transaction) error { return nil })
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+	err = parser.ScanTransactions(context.TODO(), "test", repository.TransactionFilter{}, func(types.Transaction) error { return nil })
+	assert.ErrorIs(t, err, ErrInvalidAddress)
 }
 
 func TestParserService_Subscribe(t *testing.T) {
 	repo := mocks.NewRepository(t)
 
-	repo.On("AddAddress", mock.Anything, "test").Return(nil)
+	repo.On("AddAddress", mock.Anything, addr1).Return(nil)
+	parser := NewParserService(repo)
+	err := parser.Subscribe(context.TODO(), addr1)
+	assert.NoError(t, err)
+
+	repo.On("AddAddress", mock.Anything, addr2).Return(repository.ErrAddressExists).Once()
+	err = parser.Subscribe(context.TODO(), addr2)
+	assert.ErrorIs(t, err, ErrAlreadySubscribed)
+
+	repo.On("AddAddress", mock.Anything, addr2).Return(fmt.Errorf("some error")).Once()
+	err = parser.Subscribe(context.TODO(), addr2)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+	assert.NotErrorIs(t, err, ErrAlreadySubscribed)
+}
+
+func TestParserService_Subscribe_invalid(t *testing.T) {
+	repo := mocks.NewRepository(t)
 	parser := NewParserService(repo)
-	ok := parser.Subscribe("test")
-	assert.True(t, ok)
 
-	repo.On("AddAddress", mock.Anything, "test1").Return(fmt.Errorf("address already exists"))
-	ok = parser.Subscribe("test1")
-	assert.False(t, ok)
+	for _, address := range []string{"", "hello", "0x1234567890", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"} {
+		err := parser.Subscribe(context.TODO(), address)
+		assert.ErrorIs(t, err, ErrInvalidAddress, address)
+	}
+	repo.AssertNotCalled(t, "AddAddress", mock.Anything, mock.Anything)
+}
+
+func TestParserService_WithAddressValidator(t *testing.T) {
+	const btcAddress = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	repo := mocks.NewRepository(t)
+	repo.On("AddAddress", mock.Anything, btcAddress).Return(nil).Once()
+	parser := NewParserService(repo, WithAddressValidator(utils.ValidateBitcoinAddress))
+
+	assert.NoError(t, parser.Subscribe(context.TODO(), btcAddress))
+	err := parser.Subscribe(context.TODO(), "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+
+	results := parser.BulkSubscribe(context.TODO(), []string{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3"})
+	assert.Equal(t, StatusInvalid, results[0].Status)
 }
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/TrustWallet/tx-parser/internal/analytics"
+	"github.com/TrustWallet/tx-parser/internal/logging"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+)
+
+// StatsSource is implemented by the repositories which track the activity of the addresses, e.g. analytics.Tracker
+type StatsSource interface {
+	Stats(ctx context.Context, address string) (analytics.Stats, error)
+}
+
+// GetAddressStats return the activity aggregates of an address, ErrStatsUnavailable when the
+// repository does not track them
+func (p *parserService) GetAddressStats(ctx context.Context, address string) (analytics.Stats, error) {
+	if err := p.validateAddress(address); err != nil {
+		return analytics.Stats{}, fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+	}
+	source, ok := p.repo.(StatsSource)
+	if !ok {
+		return analytics.Stats{}, ErrStatsUnavailable
+	}
+
+	stats, err := source.Stats(ctx, address)
+	if err != nil {
+		if errors.Is(err, repository.ErrAddressNotFound) {
+			return analytics.Stats{}, ErrAddressNotFound
+		}
+
+		slog.ErrorContext(ctx, "error getting address stats", slog.String(logging.KeyAddress, address), logging.Err(err))
+		return analytics.Stats{}, storageError(err)
+	}
+
+	return stats, nil
+}
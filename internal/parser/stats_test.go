@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/analytics"
+	"github.com/TrustWallet/tx-parser/internal/mocks"
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParserService_GetAddressStats(t *testing.T) {
+	ctx := context.TODO()
+	tracker := analytics.NewTracker(repository.NewInMemRepo())
+	_, err := tracker.AddAddresses(ctx, []string{addr1})
+	assert.NoError(t, err)
+	assert.NoError(t, tracker.SaveTransactions(ctx, 10, []types.Transaction{{Hash: "0x01", From: addr2, To: addr1, Value: "0x2a"}}))
+
+	parser := NewParserService(tracker)
+	stats, err := parser.GetAddressStats(ctx, addr1)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", stats.Received.String())
+	assert.Equal(t, 1, stats.TxCount)
+
+	_, err = parser.GetAddressStats(ctx, addr2)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+	_, err = parser.GetAddressStats(ctx, "test")
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+
+	// the repository does not track the stats
+	_, err = NewParserService(mocks.NewRepository(t)).GetAddressStats(ctx, addr1)
+	assert.ErrorIs(t, err, ErrStatsUnavailable)
+}
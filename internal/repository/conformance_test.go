@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/repository/repotest"
+)
+
+func TestInMemRepo_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewInMemRepo()
+	})
+}
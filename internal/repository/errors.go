@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"errors"
+)
+
+var (
+	ErrAddressExists       = errors.New("address already exists")
+	ErrAddressNotFound     = errors.New("address not found")
+	ErrStaleBlock          = errors.New("block is before the current block")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrBlockNotFound       = errors.New("block not found")
+)
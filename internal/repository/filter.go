@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// TransactionFilter selects the transactions of an address, the zero fields do not filter
+type TransactionFilter struct {
+	// Direction keeps the transactions going this way for the address
+	Direction types.Direction
+	// Counterparty keeps the transactions exchanged with this address
+	Counterparty string
+	// FromBlock and ToBlock bound the block number, inclusive, a zero ToBlock is unbounded
+	FromBlock uint64
+	ToBlock   uint64
+	// FromTime and ToTime bound the block timestamp, inclusive
+	FromTime time.Time
+	ToTime   time.Time
+	// MinValue and MaxValue bound the value in the smallest unit of the chain, inclusive
+	MinValue *big.Int
+	MaxValue *big.Int
+	// Status keeps the transactions of this execution status, a transaction of unknown status never matches
+	Status types.TxStatus
+	// Type keeps the EVM transactions of this type, e.g. "0x2"
+	Type string
+	// Event keeps the transactions of this kind, e.g. the deployments
+	Event types.TxEvent
+}
+
+// IsZero reports whether the filter keeps every transaction
+func (f TransactionFilter) IsZero() bool {
+	return f.Direction == "" && f.Counterparty == "" && f.FromBlock == 0 && f.ToBlock == 0 &&
+		f.FromTime.IsZero() && f.ToTime.IsZero() && f.MinValue == nil && f.MaxValue == nil &&
+		f.Status == "" && f.Type == "" && f.Event == ""
+}
+
+// Match reports whether the transaction of the address is kept by the filter, every
+// Repository applies it in QueryTransactions
+func (f TransactionFilter) Match(address string, tx types.Transaction) bool {
+	block := uint64(tx.BlockNumber)
+	if block < f.FromBlock || (f.ToBlock > 0 && block > f.ToBlock) {
+		return false
+	}
+	timestamp := time.Unix(int64(tx.Timestamp), 0)
+	if (!f.FromTime.IsZero() && timestamp.Before(f.FromTime)) || (!f.ToTime.IsZero() && timestamp.After(f.ToTime)) {
+		return false
+	}
+	if f.Direction != "" && tx.Direction(address) != f.Direction {
+		return false
+	}
+	if f.Counterparty != "" && !containsAddress(tx.Counterparties(address), f.Counterparty) {
+		return false
+	}
+	if f.Status != "" && tx.Status != f.Status {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(tx.Type, f.Type) {
+		return false
+	}
+	if f.Event != "" && tx.Event() != f.Event {
+		return false
+	}
+	if f.MinValue != nil || f.MaxValue != nil {
+		value, err := tx.ValueInt()
+		if err != nil {
+			return false
+		}
+		if (f.MinValue != nil && value.Cmp(f.MinValue) < 0) || (f.MaxValue != nil && value.Cmp(f.MaxValue) > 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsAddress(addresses []string, address string) bool {
+	for _, value := range addresses {
+		if utils.SameAddress(value, address) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,26 +3,66 @@ package repository
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/TrustWallet/tx-parser/internal/logging"
 	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
 )
 
-type addressTransactionsDict map[string][]types.Transaction
+// DefaultRetainedBlocks is the number of the latest blocks whose transactions are kept by default,
+// about two weeks of Ethereum blocks
+const DefaultRetainedBlocks = 100_000
 
 type inMemRepo struct {
-	mu              sync.RWMutex
-	txnDict         addressTransactionsDict
+	mu sync.RWMutex
+	// subscriptions holds the subscribed addresses by normalized address
+	subscriptions   map[string]struct{}
 	currentBlockNum uint64
+	// hashIndex holds every saved transaction by lower case hash, blockIndex their hashes by block
+	// and blocks the numbers of the blocks with transactions in ascending order
+	hashIndex  map[string]savedTransaction
+	blockIndex map[uint64][]string
+	blocks     []uint64
+	// retained is the number of the latest blocks whose transactions are kept, the older ones are pruned
+	retained uint64
+	// labels of the subscribed addresses by normalized address
+	labels map[string]types.AddressLabel
 }
 
-func NewInMemRepo() *inMemRepo {
-	txnDict := make(addressTransactionsDict)
-	return &inMemRepo{
-		txnDict:         txnDict,
+// savedTransaction is an indexed transaction with the block it is saved in
+type savedTransaction struct {
+	IndexedTransaction
+	block uint64
+}
+
+// InMemOption configures the in-memory repository
+type InMemOption func(*inMemRepo)
+
+// WithRetainedBlocks keeps the transactions of the latest n blocks, those of the older blocks are pruned
+// as new blocks are saved. The default is DefaultRetainedBlocks.
+func WithRetainedBlocks(n uint64) InMemOption {
+	return func(r *inMemRepo) {
+		r.retained = n
+	}
+}
+
+func NewInMemRepo(opts ...InMemOption) *inMemRepo {
+	r := &inMemRepo{
+		subscriptions:   make(map[string]struct{}),
 		currentBlockNum: 0,
+		hashIndex:       make(map[string]savedTransaction),
+		blockIndex:      make(map[uint64][]string),
+		retained:        DefaultRetainedBlocks,
+		labels:          make(map[string]types.AddressLabel),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // GetCurrentBlock return last parsed block number
@@ -38,44 +78,69 @@ func (r *inMemRepo) GetAddresses(ctx context.Context) ([]string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	addresses := make([]string, 0, len(r.txnDict))
-	for addr := range r.txnDict {
+	addresses := make([]string, 0, len(r.subscriptions))
+	for addr := range r.subscriptions {
 		addresses = append(addresses, addr)
 	}
+	sort.Strings(addresses)
 
 	return addresses, nil
 }
 
-// GetTransactions return transactions for an address
-func (r *inMemRepo) GetTransactions(ctx context.Context, address string) ([]types.Transaction, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// AddAddress add an address to list of subscription
+func (r *inMemRepo) AddAddress(ctx context.Context, address string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	address = strings.ToLower(address)
-	if txns, ok := r.txnDict[address]; ok {
-		transactions := make([]types.Transaction, len(txns))
-		for i, tx := range txns {
-			transactions[i] = tx
+	address = utils.NormalizeAddress(address)
+	if _, ok := r.subscriptions[address]; ok {
+		return ErrAddressExists
+	}
+
+	r.subscriptions[address] = struct{}{}
+	slog.DebugContext(ctx, "address added", slog.String(logging.KeyAddress, address))
+	return nil
+}
+
+// AddAddresses add a batch of addresses to list of subscription
+func (r *inMemRepo) AddAddresses(ctx context.Context, addresses []string) ([]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := make([]bool, len(addresses))
+	for i, address := range addresses {
+		address = utils.NormalizeAddress(address)
+		if _, ok := r.subscriptions[address]; ok {
+			continue
 		}
 
-		return transactions, nil
+		r.subscriptions[address] = struct{}{}
+		added[i] = true
 	}
 
-	return nil, fmt.Errorf("address not found")
+	slog.DebugContext(ctx, "addresses added", slog.Int("count", len(addresses)))
+	return added, nil
 }
 
-// AddAddress add an address to list of subscription
-func (r *inMemRepo) AddAddress(ctx context.Context, address string) error {
+// RemoveAddresses remove a batch of addresses from list of subscription
+func (r *inMemRepo) RemoveAddresses(ctx context.Context, addresses []string) ([]bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	address = strings.ToLower(address)
-	if _, ok := r.txnDict[address]; ok {
-		return fmt.Errorf("address already exists")
+	removed := make([]bool, len(addresses))
+	for i, address := range addresses {
+		address = utils.NormalizeAddress(address)
+		if _, ok := r.subscriptions[address]; !ok {
+			continue
+		}
+
+		delete(r.subscriptions, address)
+		delete(r.labels, address)
+		removed[i] = true
 	}
 
-	r.txnDict[address] = []types.Transaction{}
-	return nil
+	slog.DebugContext(ctx, "addresses removed", slog.Int("count", len(addresses)))
+	return removed, nil
 }
 
 // SaveTransactions save the list of transactions
@@ -83,27 +148,237 @@ func (r *inMemRepo) SaveTransactions(ctx context.Context, blockNumber uint64, tx
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if blockNumber < r.currentBlockNum {
+		return fmt.Errorf("%w: block %d, current block %d", ErrStaleBlock, blockNumber, r.currentBlockNum)
+	}
 	r.currentBlockNum = blockNumber
-	newTxnDict := make(addressTransactionsDict)
-	for address := range r.txnDict {
-		newTxnDict[address] = []types.Transaction{}
+
+	// saving the current block again replaces its transactions
+	for _, hash := range r.blockIndex[blockNumber] {
+		delete(r.hashIndex, hash)
 	}
+	r.removeBlock(blockNumber)
 
 	for _, tx := range txns {
-		if _, ok := newTxnDict[strings.ToLower(tx.To)]; ok {
-			newTxnDict[strings.ToLower(tx.To)] = append(newTxnDict[strings.ToLower(tx.To)], tx)
+		indexed := IndexedTransaction{Transaction: tx}
+		// addresses are distinct, a self send transaction is stored once
+		for _, address := range tx.Addresses() {
+			address = utils.NormalizeAddress(address)
+			if _, ok := r.subscriptions[address]; ok {
+				indexed.Addresses = append(indexed.Addresses, address)
+			}
 		}
 
-		// avoid duplicate on self send transactions
-		if strings.EqualFold(tx.To, tx.From) {
+		hash := strings.ToLower(tx.Hash)
+		saved, ok := r.hashIndex[hash]
+		if ok && saved.block == blockNumber {
+			// a hash repeated within the block is listed once
+			r.hashIndex[hash] = savedTransaction{IndexedTransaction: indexed, block: blockNumber}
 			continue
 		}
+		if ok {
+			// the transaction moved to this block, e.g. after a re-org, it leaves its previous block
+			r.unindex(saved.block, hash)
+		}
+		r.hashIndex[hash] = savedTransaction{IndexedTransaction: indexed, block: blockNumber}
+		r.blockIndex[blockNumber] = append(r.blockIndex[blockNumber], hash)
+	}
+	if len(r.blockIndex[blockNumber]) > 0 {
+		r.blocks = append(r.blocks, blockNumber)
+	}
+	r.prune()
+
+	slog.DebugContext(ctx, "transactions saved",
+		slog.Uint64(logging.KeyBlockNumber, blockNumber), slog.Int("count", len(txns)))
+	return nil
+}
+
+// unindex removes a hash from the index of its block, the block is dropped once it has no transaction left
+func (r *inMemRepo) unindex(blockNumber uint64, hash string) {
+	hashes := r.blockIndex[blockNumber]
+	for i, h := range hashes {
+		if h == hash {
+			hashes = append(hashes[:i:i], hashes[i+1:]...)
+			break
+		}
+	}
+	if len(hashes) > 0 {
+		r.blockIndex[blockNumber] = hashes
+		return
+	}
+	r.removeBlock(blockNumber)
+}
 
-		if _, ok := newTxnDict[strings.ToLower(tx.From)]; ok {
-			newTxnDict[strings.ToLower(tx.From)] = append(newTxnDict[strings.ToLower(tx.From)], tx)
+// removeBlock drops the index of a block
+func (r *inMemRepo) removeBlock(blockNumber uint64) {
+	delete(r.blockIndex, blockNumber)
+	i := sort.Search(len(r.blocks), func(i int) bool { return r.blocks[i] >= blockNumber })
+	if i < len(r.blocks) && r.blocks[i] == blockNumber {
+		r.blocks = append(r.blocks[:i:i], r.blocks[i+1:]...)
+	}
+}
+
+// prune drops the transactions of the blocks older than the retained ones
+func (r *inMemRepo) prune() {
+	oldest := r.oldestRetained()
+	n := 0
+	for ; n < len(r.blocks) && r.blocks[n] < oldest; n++ {
+		for _, hash := range r.blockIndex[r.blocks[n]] {
+			delete(r.hashIndex, hash)
 		}
+		delete(r.blockIndex, r.blocks[n])
 	}
+	r.blocks = r.blocks[n:]
+}
+
+// oldestRetained return the number of the oldest block whose transactions are kept
+func (r *inMemRepo) oldestRetained() uint64 {
+	if r.currentBlockNum < r.retained {
+		return 0
+	}
+	return r.currentBlockNum - r.retained + 1
+}
+
+// GetTransactionByHash return a saved transaction
+func (r *inMemRepo) GetTransactionByHash(ctx context.Context, hash string) (IndexedTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saved, ok := r.hashIndex[strings.ToLower(hash)]
+	if !ok {
+		return IndexedTransaction{}, ErrTransactionNotFound
+	}
+
+	return saved.IndexedTransaction, nil
+}
+
+// GetBlockTransactions return the saved transactions of a block
+func (r *inMemRepo) GetBlockTransactions(ctx context.Context, blockNumber uint64) ([]IndexedTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if blockNumber > r.currentBlockNum || blockNumber < r.oldestRetained() {
+		return nil, ErrBlockNotFound
+	}
+
+	hashes := r.blockIndex[blockNumber]
+	txns := make([]IndexedTransaction, len(hashes))
+	for i, hash := range hashes {
+		txns[i] = r.hashIndex[hash].IndexedTransaction
+	}
+
+	return txns, nil
+}
+
+// QueryTransactions return the saved transactions of an address matching the filter
+func (r *inMemRepo) QueryTransactions(ctx context.Context, address string, filter TransactionFilter) ([]types.Transaction, error) {
+	txns := []types.Transaction{}
+	err := r.ScanTransactions(ctx, address, filter, func(tx types.Transaction) error {
+		txns = append(txns, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txns, nil
+}
+
+// scanBatchBlocks is the number of blocks read under the lock by ScanTransactions, so that
+// a slow reader does not hold back the crawler
+const scanBatchBlocks = 256
+
+// ScanTransactions calls fn with the saved transactions of an address matching the filter, the lock
+// is only held while a batch of blocks is read
+func (r *inMemRepo) ScanTransactions(ctx context.Context, address string, filter TransactionFilter, fn func(types.Transaction) error) error {
+	r.mu.RLock()
+	_, ok := r.subscriptions[utils.NormalizeAddress(address)]
+	r.mu.RUnlock()
+	if !ok {
+		return ErrAddressNotFound
+	}
+
+	next := filter.FromBlock
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, last, more := r.scanBatch(address, filter, next)
+		for _, tx := range batch {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		if !more {
+			return nil
+		}
+		next = last + 1
+	}
+}
+
+// scanBatch return the matching transactions of up to scanBatchBlocks blocks from the block next,
+// the last block read and whether blocks remain
+func (r *inMemRepo) scanBatch(address string, filter TransactionFilter, next uint64) ([]types.Transaction, uint64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var (
+		batch []types.Transaction
+		last  uint64
+	)
+	first := sort.Search(len(r.blocks), func(i int) bool { return r.blocks[i] >= next })
+	for i, blockNumber := range r.blocks[first:] {
+		if filter.ToBlock > 0 && blockNumber > filter.ToBlock {
+			return batch, last, false
+		}
+		if i == scanBatchBlocks {
+			return batch, last, true
+		}
+		for _, hash := range r.blockIndex[blockNumber] {
+			indexed := r.hashIndex[hash].IndexedTransaction
+			if containsAddress(indexed.Addresses, address) && filter.Match(address, indexed.Transaction) {
+				batch = append(batch, indexed.Transaction)
+			}
+		}
+		last = blockNumber
+	}
+
+	return batch, last, false
+}
+
+// SetLabel replace the label of a subscribed address
+func (r *inMemRepo) SetLabel(ctx context.Context, address string, label types.AddressLabel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	address = utils.NormalizeAddress(address)
+	if _, ok := r.subscriptions[address]; !ok {
+		return ErrAddressNotFound
+	}
+
+	if label.IsZero() {
+		delete(r.labels, address)
+		return nil
+	}
+	label.Tags = append([]string(nil), label.Tags...)
+	r.labels[address] = label
 
-	r.txnDict = newTxnDict
 	return nil
 }
+
+// GetLabels return the labels of the subscribed addresses among addresses
+func (r *inMemRepo) GetLabels(ctx context.Context, addresses []string) (map[string]types.AddressLabel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	labels := make(map[string]types.AddressLabel)
+	for _, address := range addresses {
+		address = utils.NormalizeAddress(address)
+		if label, ok := r.labels[address]; ok {
+			label.Tags = append([]string(nil), label.Tags...)
+			labels[address] = label
+		}
+	}
+
+	return labels, nil
+}
@@ -40,18 +40,18 @@ func TestInMemRepo_GetCurrentBlock(t *testing.T) {
 	assert.Equal(t, uint64(13), blockNum)
 }
 
-func TestInMemRepo_SaveAndGetTransactions(t *testing.T) {
+func TestInMemRepo_SaveAndQueryTransactions(t *testing.T) {
 	repo := NewInMemRepo()
 	err := repo.AddAddress(context.TODO(), "test1")
 	assert.NoError(t, err)
 	err = repo.AddAddress(context.TODO(), "test2")
 	assert.NoError(t, err)
 
-	txns, err := repo.GetTransactions(context.TODO(), "test2")
+	txns, err := repo.QueryTransactions(context.TODO(), "test2", TransactionFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, txns, 0)
 
-	_, err = repo.GetTransactions(context.TODO(), "test3")
+	_, err = repo.QueryTransactions(context.TODO(), "test3", TransactionFilter{})
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "address not found")
 
@@ -91,18 +91,18 @@ func TestInMemRepo_SaveAndGetTransactions(t *testing.T) {
 	err = repo.SaveTransactions(context.TODO(), blockNumber, fakeTxns)
 	assert.NoError(t, err)
 
-	txns, err = repo.GetTransactions(context.TODO(), "test2")
+	txns, err = repo.QueryTransactions(context.TODO(), "test2", TransactionFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, txns, 3)
 	assert.Equal(t, "hash1", txns[0].Hash)
 	assert.Equal(t, "hash2", txns[1].Hash)
 	assert.Equal(t, "hash3", txns[2].Hash)
 
-	txns, err = repo.GetTransactions(context.TODO(), "test1")
+	txns, err = repo.QueryTransactions(context.TODO(), "test1", TransactionFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, txns, 4)
 
-	_, err = repo.GetTransactions(context.TODO(), "test3")
+	_, err = repo.QueryTransactions(context.TODO(), "test3", TransactionFilter{})
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "address not found")
 
@@ -110,3 +110,57 @@ func TestInMemRepo_SaveAndGetTransactions(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, blockNumber, num)
 }
+
+func TestInMemRepo_AddAndRemoveAddresses(t *testing.T) {
+	repo := NewInMemRepo()
+	err := repo.AddAddress(context.TODO(), "test1")
+	assert.NoError(t, err)
+
+	added, err := repo.AddAddresses(context.TODO(), []string{"TEST1", "test2", "Test3", "test2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true, true, false}, added)
+
+	addresses, err := repo.GetAddresses(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test1", "test2", "test3"}, addresses)
+
+	removed, err := repo.RemoveAddresses(context.TODO(), []string{"Test2", "test4", "test2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, removed)
+
+	_, err = repo.QueryTransactions(context.TODO(), "test2", TransactionFilter{})
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+
+	addresses, err = repo.GetAddresses(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test1", "test3"}, addresses)
+}
+
+func TestInMemRepo_retainedBlocks(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewInMemRepo(WithRetainedBlocks(3))
+	assert.NoError(t, repo.AddAddress(ctx, "test1"))
+	for block := uint64(1); block <= 5; block++ {
+		hash := "hash" + string(rune('0'+block))
+		assert.NoError(t, repo.SaveTransactions(ctx, block, []types.Transaction{
+			{BlockNumber: utils.HexUint64(block), From: "test1", To: "test2", Hash: hash},
+		}))
+	}
+
+	// the transactions of the blocks before the last 3 ones are pruned
+	txns, err := repo.QueryTransactions(ctx, "test1", TransactionFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 3) {
+		assert.Equal(t, "hash3", txns[0].Hash)
+	}
+	_, err = repo.GetBlockTransactions(ctx, 2)
+	assert.ErrorIs(t, err, ErrBlockNotFound)
+	_, err = repo.GetTransactionByHash(ctx, "hash2")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	indexed, err := repo.GetBlockTransactions(ctx, 3)
+	assert.NoError(t, err)
+	assert.Len(t, indexed, 1)
+	assert.Len(t, repo.hashIndex, 3)
+	assert.Len(t, repo.blockIndex, 3)
+	assert.Equal(t, []uint64{3, 4, 5}, repo.blocks)
+}
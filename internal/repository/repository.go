@@ -13,12 +13,56 @@ type Repository interface {
 	// GetAddresses get list of subscribed addresses
 	GetAddresses(ctx context.Context) ([]string, error)
 
-	// GetTransactions return transactions for an address
-	GetTransactions(ctx context.Context, address string) ([]types.Transaction, error)
-
-	// AddAddress add an address to list of subscription
+	// AddAddress add an address to list of subscription, ErrAddressExists when it is already subscribed
 	AddAddress(ctx context.Context, address string) error
 
-	// SaveTransactions save the list of transactions with block number
+	// AddAddresses add a batch of addresses to list of subscription,
+	// the result reports for each address whether it was newly added
+	AddAddresses(ctx context.Context, addresses []string) ([]bool, error)
+
+	// RemoveAddresses remove a batch of addresses from list of subscription,
+	// the result reports for each address whether it was subscribed
+	RemoveAddresses(ctx context.Context, addresses []string) ([]bool, error)
+
+	// SaveTransactions save the list of transactions with block number, the block becomes the
+	// current one. It fails with ErrStaleBlock when the block is before the current one. A transaction
+	// saved before in another block, e.g. after a re-org, moves to this one.
 	SaveTransactions(ctx context.Context, blockNumber uint64, txns []types.Transaction) error
+
+	// GetTransactionByHash return a saved transaction, compared case-insensitively,
+	// ErrTransactionNotFound when it is not stored
+	GetTransactionByHash(ctx context.Context, hash string) (IndexedTransaction, error)
+
+	// GetBlockTransactions return the saved transactions of a block, ErrBlockNotFound when
+	// the block is after the current one or its transactions are no longer retained
+	GetBlockTransactions(ctx context.Context, blockNumber uint64) ([]IndexedTransaction, error)
+
+	// QueryTransactions return the transactions of an address matching the filter in every saved
+	// block, oldest first, ErrAddressNotFound when it is not subscribed
+	QueryTransactions(ctx context.Context, address string, filter TransactionFilter) ([]types.Transaction, error)
+
+	// ScanTransactions calls fn with the transactions QueryTransactions would return, one at a
+	// time, so that a long history is not held in memory. The scan stops at the first error of fn,
+	// which is returned. The blocks saved during the scan may or may not be seen.
+	ScanTransactions(ctx context.Context, address string, filter TransactionFilter, fn func(types.Transaction) error) error
+
+	// SetLabel replace the label of a subscribed address, a zero label removes it, ErrAddressNotFound when
+	// it is not subscribed. The label is removed with the subscription.
+	SetLabel(ctx context.Context, address string, label types.AddressLabel) error
+
+	// GetLabels return the labels of the subscribed addresses among addresses, keyed by address as normalized by utils.NormalizeAddress,
+	// the addresses without label are left out
+	GetLabels(ctx context.Context, addresses []string) (map[string]types.AddressLabel, error)
+}
+
+// IndexedTransaction is a saved transaction with the subscribed addresses it matched when it was saved
+type IndexedTransaction struct {
+	Transaction types.Transaction
+	Addresses   []string
+}
+
+// Flusher is implemented by repositories which buffer writes,
+// Flush persists the pending data and is called before the process exits
+type Flusher interface {
+	Flush(ctx context.Context) error
 }
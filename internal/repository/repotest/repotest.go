@@ -0,0 +1,573 @@
+// Package repotest is the conformance suite of the repository.Repository implementations,
+// every backend runs it from its tests:
+//
+//	func TestMyRepo_Conformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repository.Repository { return newMyRepo(t) })
+//	}
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TrustWallet/tx-parser/internal/repository"
+	"github.com/TrustWallet/tx-parser/internal/types"
+	"github.com/TrustWallet/tx-parser/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory return an empty repository, it is called once per test
+type Factory func(t *testing.T) repository.Repository
+
+const (
+	alice = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	bob   = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+	carol = "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+	// dave is a contract, never subscribed
+	dave = "0x5c69bee701ef814a2b6a3edd4b1652cb9cc5aa6f"
+)
+
+// Run checks the repository created by newRepo behaves as the Repository contract requires
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repository.Repository)
+	}{
+		{"Empty", testEmpty},
+		{"CaseInsensitiveAddresses", testCaseInsensitiveAddresses},
+		{"DuplicateSubscriptions", testDuplicateSubscriptions},
+		{"RemoveAddresses", testRemoveAddresses},
+		{"SaveTransactions", testSaveTransactions},
+		{"SelfSend", testSelfSend},
+		{"UTXOAddresses", testUTXOAddresses},
+		{"Base58Addresses", testBase58Addresses},
+		{"BlockCursor", testBlockCursor},
+		{"TransactionByHash", testTransactionByHash},
+		{"BlockTransactions", testBlockTransactions},
+		{"QueryTransactions", testQueryTransactions},
+		{"ScanTransactions", testScanTransactions},
+		{"Labels", testLabels},
+		{"ConcurrentReads", testConcurrentReads},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+func tx(hash, from, to string, blockNumber uint64) types.Transaction {
+	return types.Transaction{Hash: hash, From: from, To: to, BlockNumber: utils.HexUint64(blockNumber)}
+}
+
+func hashes(txns []types.Transaction) []string {
+	hashes := make([]string, len(txns))
+	for i, tx := range txns {
+		hashes[i] = tx.Hash
+	}
+	return hashes
+}
+
+func indexedHashes(txns []repository.IndexedTransaction) []string {
+	hashes := make([]string, len(txns))
+	for i, tx := range txns {
+		hashes[i] = tx.Transaction.Hash
+	}
+	return hashes
+}
+
+func lower(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = utils.NormalizeAddress(v)
+	}
+	return lowered
+}
+
+func testEmpty(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	block, err := repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, block)
+
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, addresses)
+
+	_, err = repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+}
+
+func testCaseInsensitiveAddresses(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	assert.NoError(t, repo.AddAddress(ctx, strings.ToUpper(alice[2:])))
+	assert.NoError(t, repo.AddAddress(ctx, "0x4838B106FCe9647Bdf1E7877BF73cE8B0BAD5f97"))
+
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{alice[2:], bob}, lower(addresses))
+
+	assert.NoError(t, repo.SaveTransactions(ctx, 1, []types.Transaction{tx("0x01", alice[2:], strings.ToUpper(bob), 1)}))
+	for _, address := range []string{alice[2:], strings.ToUpper(alice[2:]), bob, strings.ToUpper(bob)} {
+		txns, err := repo.QueryTransactions(ctx, address, repository.TransactionFilter{})
+		assert.NoError(t, err, address)
+		assert.Equal(t, []string{"0x01"}, hashes(txns), address)
+	}
+}
+
+func testDuplicateSubscriptions(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+	assert.ErrorIs(t, repo.AddAddress(ctx, strings.ToUpper(alice)), repository.ErrAddressExists)
+
+	added, err := repo.AddAddresses(ctx, []string{strings.ToUpper(alice), bob, carol, strings.ToUpper(bob)})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true, true, false}, added)
+
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{alice, bob, carol}, lower(addresses))
+}
+
+func testRemoveAddresses(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	_, err := repo.AddAddresses(ctx, []string{alice, bob})
+	assert.NoError(t, err)
+
+	removed, err := repo.RemoveAddresses(ctx, []string{strings.ToUpper(alice), carol, alice})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, removed)
+
+	_, err = repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{bob}, lower(addresses))
+
+	// an address can be subscribed again once removed
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+}
+
+func testLabels(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+	hotWallet := types.AddressLabel{Label: "hot wallet", Tags: []string{"treasury"}}
+
+	assert.ErrorIs(t, repo.SetLabel(ctx, bob, hotWallet), repository.ErrAddressNotFound)
+	assert.NoError(t, repo.SetLabel(ctx, strings.ToUpper(alice), hotWallet))
+	// the caller keeps its slice
+	hotWallet.Tags[0] = "changed"
+
+	labels, err := repo.GetLabels(ctx, []string{strings.ToUpper(alice), bob, carol})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.AddressLabel{alice: {Label: "hot wallet", Tags: []string{"treasury"}}}, labels)
+
+	// a zero label removes it
+	assert.NoError(t, repo.SetLabel(ctx, alice, types.AddressLabel{}))
+	labels, err = repo.GetLabels(ctx, []string{alice})
+	assert.NoError(t, err)
+	assert.Empty(t, labels)
+
+	// the label is removed with the subscription
+	assert.NoError(t, repo.SetLabel(ctx, alice, types.AddressLabel{Label: "customer 123"}))
+	_, err = repo.RemoveAddresses(ctx, []string{alice})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+	labels, err = repo.GetLabels(ctx, []string{alice})
+	assert.NoError(t, err)
+	assert.Empty(t, labels)
+}
+
+func testSaveTransactions(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	_, err := repo.AddAddresses(ctx, []string{alice, bob})
+	assert.NoError(t, err)
+
+	txns, err := repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+
+	assert.NoError(t, repo.SaveTransactions(ctx, 10, []types.Transaction{
+		tx("0x01", alice, bob, 10),
+		tx("0x02", carol, alice, 10),
+		tx("0x03", carol, carol, 10),
+	}))
+	txns, err = repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01", "0x02"}, hashes(txns))
+	txns, err = repo.QueryTransactions(ctx, bob, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01"}, hashes(txns))
+	// the transactions of the addresses which are not subscribed are not stored
+	_, err = repo.QueryTransactions(ctx, carol, repository.TransactionFilter{})
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+
+	// an address subscribed after the block was saved sees the next blocks only
+	assert.NoError(t, repo.AddAddress(ctx, carol))
+	assert.NoError(t, repo.SaveTransactions(ctx, 11, []types.Transaction{tx("0x04", carol, bob, 11)}))
+	txns, err = repo.QueryTransactions(ctx, carol, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x04"}, hashes(txns))
+	assert.Equal(t, utils.HexUint64(11), txns[0].BlockNumber)
+}
+
+func testSelfSend(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+
+	assert.NoError(t, repo.SaveTransactions(ctx, 1, []types.Transaction{
+		tx("0x01", alice, strings.ToUpper(alice), 1),
+		tx("0x02", bob, alice, 1),
+	}))
+	txns, err := repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01", "0x02"}, hashes(txns))
+}
+
+func testUTXOAddresses(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	const payee = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	const change = "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"
+	_, err := repo.AddAddresses(ctx, []string{payee, change})
+	assert.NoError(t, err)
+
+	utxoTx := types.Transaction{
+		Hash:    "f1",
+		From:    change,
+		To:      payee,
+		Inputs:  []types.UTXO{{Address: change, Value: "70000000"}},
+		Outputs: []types.UTXO{{Address: payee, Value: "50000000"}, {Address: change, Value: "19000000"}},
+	}
+	assert.NoError(t, repo.SaveTransactions(ctx, 1, []types.Transaction{utxoTx}))
+
+	for _, address := range []string{payee, change} {
+		txns, err := repo.QueryTransactions(ctx, address, repository.TransactionFilter{})
+		assert.NoError(t, err)
+		if assert.Len(t, txns, 1, address) {
+			assert.Equal(t, utxoTx.Outputs, txns[0].Outputs)
+			assert.Equal(t, utxoTx.Inputs, txns[0].Inputs)
+		}
+	}
+}
+
+func testBase58Addresses(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	// Base58 addresses are case-sensitive, these two differ only in case
+	const upper = "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"
+	const lower = "1BvBMSEYstWetqTFn5Au4m4GFg7xJanvn2"
+	added, err := repo.AddAddresses(ctx, []string{upper, lower})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, added)
+
+	addresses, err := repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{upper, lower}, addresses)
+
+	sent := types.Transaction{
+		Hash:    "f1",
+		From:    upper,
+		To:      dave,
+		Inputs:  []types.UTXO{{Address: upper, Value: "70000000"}},
+		Outputs: []types.UTXO{{Address: dave, Value: "69000000"}},
+	}
+	assert.NoError(t, repo.SaveTransactions(ctx, 1, []types.Transaction{sent}))
+	txns, err := repo.QueryTransactions(ctx, upper, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"f1"}, hashes(txns))
+	txns, err = repo.QueryTransactions(ctx, lower, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+	indexed, err := repo.GetTransactionByHash(ctx, "f1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{upper}, indexed.Addresses)
+
+	assert.NoError(t, repo.SetLabel(ctx, upper, types.AddressLabel{Label: "cold wallet"}))
+	labels, err := repo.GetLabels(ctx, []string{upper, lower})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.AddressLabel{upper: {Label: "cold wallet"}}, labels)
+
+	removed, err := repo.RemoveAddresses(ctx, []string{lower})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true}, removed)
+	addresses, err = repo.GetAddresses(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{upper}, addresses)
+}
+
+func testBlockCursor(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+	assert.NoError(t, repo.SaveTransactions(ctx, 100, []types.Transaction{tx("0x01", alice, bob, 100)}))
+	assert.NoError(t, repo.SaveTransactions(ctx, 102, nil))
+
+	block, err := repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(102), block)
+
+	// saving the current block again is a retry
+	assert.NoError(t, repo.SaveTransactions(ctx, 102, []types.Transaction{tx("0x02", bob, alice, 102)}))
+	txns, err := repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01", "0x02"}, hashes(txns))
+
+	// the cursor never moves backwards
+	err = repo.SaveTransactions(ctx, 101, []types.Transaction{tx("0x03", alice, bob, 101)})
+	assert.ErrorIs(t, err, repository.ErrStaleBlock)
+	block, err = repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(102), block)
+	txns, err = repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01", "0x02"}, hashes(txns))
+}
+
+func testTransactionByHash(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	_, err := repo.AddAddresses(ctx, []string{alice, bob})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SaveTransactions(ctx, 1, []types.Transaction{tx("0xAB01", alice, bob, 1)}))
+	assert.NoError(t, repo.SaveTransactions(ctx, 2, []types.Transaction{tx("0xab02", carol, strings.ToUpper(bob), 2)}))
+
+	// the transactions of the previous blocks stay indexed
+	indexed, err := repo.GetTransactionByHash(ctx, "0xab01")
+	assert.NoError(t, err)
+	assert.Equal(t, "0xAB01", indexed.Transaction.Hash)
+	assert.ElementsMatch(t, []string{alice, bob}, lower(indexed.Addresses))
+
+	indexed, err = repo.GetTransactionByHash(ctx, "0xAB02")
+	assert.NoError(t, err)
+	assert.Equal(t, utils.HexUint64(2), indexed.Transaction.BlockNumber)
+	assert.Equal(t, []string{bob}, lower(indexed.Addresses))
+
+	_, err = repo.GetTransactionByHash(ctx, "0xab03")
+	assert.ErrorIs(t, err, repository.ErrTransactionNotFound)
+}
+
+func testBlockTransactions(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	assert.NoError(t, repo.AddAddress(ctx, alice))
+	assert.NoError(t, repo.SaveTransactions(ctx, 5, []types.Transaction{
+		tx("0x01", alice, bob, 5),
+		tx("0x02", bob, alice, 5),
+	}))
+	assert.NoError(t, repo.SaveTransactions(ctx, 7, []types.Transaction{tx("0x03", alice, alice, 7)}))
+
+	txns, err := repo.GetBlockTransactions(ctx, 5)
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 2) {
+		assert.Equal(t, "0x01", txns[0].Transaction.Hash)
+		assert.Equal(t, "0x02", txns[1].Transaction.Hash)
+		assert.Equal(t, []string{alice}, lower(txns[1].Addresses))
+	}
+
+	// a parsed block without matched transactions is empty
+	txns, err = repo.GetBlockTransactions(ctx, 6)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+
+	_, err = repo.GetBlockTransactions(ctx, 8)
+	assert.ErrorIs(t, err, repository.ErrBlockNotFound)
+
+	// saving the current block again replaces its transactions
+	assert.NoError(t, repo.SaveTransactions(ctx, 7, []types.Transaction{tx("0x04", bob, alice, 7)}))
+	txns, err = repo.GetBlockTransactions(ctx, 7)
+	assert.NoError(t, err)
+	if assert.Len(t, txns, 1) {
+		assert.Equal(t, "0x04", txns[0].Transaction.Hash)
+	}
+	_, err = repo.GetTransactionByHash(ctx, "0x03")
+	assert.ErrorIs(t, err, repository.ErrTransactionNotFound)
+
+	// a transaction saved again in a later block, e.g. after a re-org, moves to it
+	assert.NoError(t, repo.SaveTransactions(ctx, 9, []types.Transaction{tx("0x02", bob, alice, 9)}))
+	txns, err = repo.GetBlockTransactions(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01"}, indexedHashes(txns))
+	txns, err = repo.GetBlockTransactions(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x02"}, indexedHashes(txns))
+	indexed, err := repo.GetTransactionByHash(ctx, "0x02")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(9), uint64(indexed.Transaction.BlockNumber))
+	history, err := repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01", "0x04", "0x02"}, hashes(history))
+
+	// and a block left without transactions is empty
+	assert.NoError(t, repo.SaveTransactions(ctx, 10, []types.Transaction{tx("0x04", bob, alice, 10)}))
+	txns, err = repo.GetBlockTransactions(ctx, 7)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+}
+
+func testQueryTransactions(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	_, err := repo.AddAddresses(ctx, []string{alice, bob})
+	assert.NoError(t, err)
+
+	withDetails := func(tx types.Transaction, value string, timestamp uint64, status types.TxStatus, txType string) types.Transaction {
+		tx.Value, tx.Timestamp, tx.Status, tx.Type = value, utils.HexUint64(timestamp), status, txType
+		return tx
+	}
+	call := withDetails(tx("0x02", carol, alice, 10), "0x3e8", 1000, types.StatusFailed, "0x0")
+	call.Input = "0xa9059cbb"
+	deployment := withDetails(tx("0x03", strings.ToUpper(alice), alice, 12), "0x0", 1024, types.StatusSuccess, "0x2")
+	deployment.Input = "0x4f7e2f0c"
+	deployment.Deployments = []types.Deployment{{Address: dave, Deployer: alice, Opcode: "CREATE2"}}
+	assert.NoError(t, repo.SaveTransactions(ctx, 10, []types.Transaction{
+		withDetails(tx("0x01", alice, bob, 10), "0x64", 1000, types.StatusSuccess, "0x2"),
+		call,
+	}))
+	assert.NoError(t, repo.SaveTransactions(ctx, 11, nil))
+	assert.NoError(t, repo.SaveTransactions(ctx, 12, []types.Transaction{
+		deployment,
+		withDetails(tx("0x04", bob, carol, 12), "0x1", 1024, "", "0x2"),
+	}))
+
+	tests := []struct {
+		name   string
+		filter repository.TransactionFilter
+		want   []string
+	}{
+		{"every block", repository.TransactionFilter{}, []string{"0x01", "0x02", "0x03"}},
+		{"inbound", repository.TransactionFilter{Direction: types.DirectionInbound}, []string{"0x02"}},
+		{"outbound", repository.TransactionFilter{Direction: types.DirectionOutbound}, []string{"0x01"}},
+		{"self", repository.TransactionFilter{Direction: types.DirectionSelf}, []string{"0x03"}},
+		{"counterparty", repository.TransactionFilter{Counterparty: strings.ToUpper(carol)}, []string{"0x02"}},
+		{"from block", repository.TransactionFilter{FromBlock: 11}, []string{"0x03"}},
+		{"block range", repository.TransactionFilter{FromBlock: 10, ToBlock: 11}, []string{"0x01", "0x02"}},
+		{"time range", repository.TransactionFilter{FromTime: time.Unix(1001, 0), ToTime: time.Unix(1024, 0)}, []string{"0x03"}},
+		{"min value", repository.TransactionFilter{MinValue: big.NewInt(100)}, []string{"0x01", "0x02"}},
+		{"value range", repository.TransactionFilter{MinValue: big.NewInt(1), MaxValue: big.NewInt(100)}, []string{"0x01"}},
+		{"failed", repository.TransactionFilter{Status: types.StatusFailed}, []string{"0x02"}},
+		{"type", repository.TransactionFilter{Type: "0x2"}, []string{"0x01", "0x03"}},
+		{"combined", repository.TransactionFilter{Direction: types.DirectionOutbound, Type: "0x0"}, []string{}},
+		{"transfers", repository.TransactionFilter{Event: types.EventTransfer}, []string{"0x01"}},
+		{"calls", repository.TransactionFilter{Event: types.EventCall}, []string{"0x02"}},
+		{"deployments", repository.TransactionFilter{Event: types.EventDeployment}, []string{"0x03"}},
+	}
+	for _, tt := range tests {
+		txns, err := repo.QueryTransactions(ctx, strings.ToUpper(alice), tt.filter)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, hashes(txns), tt.name)
+	}
+
+	// a transaction of unknown status matches no status
+	txns, err := repo.QueryTransactions(ctx, bob, repository.TransactionFilter{Status: types.StatusSuccess})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x01"}, hashes(txns))
+
+	_, err = repo.QueryTransactions(ctx, carol, repository.TransactionFilter{})
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+}
+
+// testScanTransactions scans a long history, the blocks can be saved while it is scanned
+func testScanTransactions(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	_, err := repo.AddAddresses(ctx, []string{alice})
+	assert.NoError(t, err)
+
+	const blocks = 1000
+	var want []string
+	for n := uint64(1); n <= blocks; n++ {
+		hash := fmt.Sprintf("0x%x", n)
+		assert.NoError(t, repo.SaveTransactions(ctx, n, []types.Transaction{tx(hash, alice, bob, n)}))
+		if n >= 10 && n <= 900 {
+			want = append(want, hash)
+		}
+	}
+
+	var scanned []string
+	filter := repository.TransactionFilter{FromBlock: 10, ToBlock: 900}
+	err = repo.ScanTransactions(ctx, alice, filter, func(scannedTx types.Transaction) error {
+		scanned = append(scanned, scannedTx.Hash)
+		if len(scanned) == 1 {
+			// the scan must not block the writers
+			return repo.SaveTransactions(ctx, blocks+1, []types.Transaction{tx("0xnew", alice, bob, blocks+1)})
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, want, scanned)
+
+	stop := fmt.Errorf("stop")
+	calls := 0
+	err = repo.ScanTransactions(ctx, alice, repository.TransactionFilter{}, func(types.Transaction) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+
+	err = repo.ScanTransactions(ctx, carol, repository.TransactionFilter{}, func(types.Transaction) error { return nil })
+	assert.ErrorIs(t, err, repository.ErrAddressNotFound)
+}
+
+// testConcurrentReads reads while blocks are saved, every read must see a whole block
+func testConcurrentReads(t *testing.T, repo repository.Repository) {
+	ctx := context.TODO()
+	_, err := repo.AddAddresses(ctx, []string{alice, bob})
+	assert.NoError(t, err)
+
+	const blocks, readers = 200, 4
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last uint64
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				current, err := repo.GetCurrentBlock(ctx)
+				assert.NoError(t, err)
+				assert.GreaterOrEqual(t, current, last, "the current block moved backwards")
+				last = current
+
+				txns, err := repo.QueryTransactions(ctx, alice, repository.TransactionFilter{})
+				assert.NoError(t, err)
+				assert.Zero(t, len(txns)%2, "partial block")
+
+				if current > 0 {
+					txns, err = repo.QueryTransactions(ctx, alice, repository.TransactionFilter{FromBlock: current, ToBlock: current})
+					assert.NoError(t, err)
+					assert.Len(t, txns, 2, "partial block")
+				}
+
+				_, err = repo.GetAddresses(ctx)
+				assert.NoError(t, err)
+
+				if current > 0 {
+					indexed, err := repo.GetBlockTransactions(ctx, current)
+					assert.NoError(t, err)
+					assert.Len(t, indexed, 2, "partial block")
+				}
+			}
+		}()
+	}
+
+	for n := uint64(1); n <= blocks; n++ {
+		err := repo.SaveTransactions(ctx, n, []types.Transaction{
+			tx(fmt.Sprintf("0x%x-1", n), alice, bob, n),
+			tx(fmt.Sprintf("0x%x-2", n), carol, alice, n),
+		})
+		assert.NoError(t, err)
+	}
+	close(done)
+	wg.Wait()
+
+	block, err := repo.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(blocks), block)
+}
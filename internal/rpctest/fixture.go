@@ -0,0 +1,81 @@
+// Package rpctest records JSON-RPC exchanges with a real node to fixture files and serves
+// them back from an httptest server, so that the tests run offline and deterministically.
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Exchange is a recorded JSON-RPC call
+type Exchange struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	// Status is the HTTP status of the response, zero is 200
+	Status int `json:"status,omitempty"`
+	// Response is the JSON-RPC response message without its id
+	Response json.RawMessage `json:"response"`
+}
+
+// Fixture is the content of a fixture file, the exchanges are in the order they were recorded
+type Fixture struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+func LoadFixture(path string) (*Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture, the directory is created if needed
+func (f *Fixture) Save(path string) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// request is the part of a JSON-RPC request identifying the call
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// callKey identifies the calls answered by the same exchange, whatever their id and formatting
+func callKey(method string, params json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, params); err != nil {
+		return method + string(params)
+	}
+	return method + buf.String()
+}
+
+// withID return the response message with the id of the request
+func withID(response json.RawMessage, id json.RawMessage) ([]byte, error) {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(response, &msg); err != nil {
+		return nil, err
+	}
+	if id == nil {
+		delete(msg, "id")
+	} else {
+		msg["id"] = id
+	}
+	return json.Marshal(msg)
+}
@@ -0,0 +1,77 @@
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Recorder is an http.RoundTripper capturing the JSON-RPC exchanges it forwards
+type Recorder struct {
+	transport http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder records the exchanges sent with the transport, nil uses http.DefaultTransport
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{transport: transport}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var call request
+	if json.Unmarshal(body, &call) == nil && call.Method != "" {
+		r.record(call, resp.StatusCode, respBody)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) record(call request, status int, respBody []byte) {
+	exchange := Exchange{Method: call.Method, Params: call.Params, Response: respBody}
+	if status != http.StatusOK {
+		exchange.Status = status
+	}
+	// the ids change from run to run, they are set back when replayed
+	if response, err := withID(respBody, nil); err == nil {
+		exchange.Response = response
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Exchanges = append(r.fixture.Exchanges, exchange)
+}
+
+// Fixture return the exchanges recorded so far
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Fixture{Exchanges: append([]Exchange(nil), r.fixture.Exchanges...)}
+}
@@ -0,0 +1,129 @@
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+var record = flag.Bool("rpctest.record", false,
+	"record the JSON-RPC fixtures from the live nodes instead of replaying them")
+
+// NewServer starts a JSON-RPC server replaying the fixture at path, the test fails when the fixture
+// is not recorded so that its coverage is never lost silently. With the -rpctest.record test flag, the server forwards the calls to the upstream node instead and writes them to
+// the fixture when the test ends, e.g.
+//
+//	go test ./internal/crawler -run TestEthereumClient -rpctest.record
+func NewServer(t testing.TB, path, upstream string) *httptest.Server {
+	t.Helper()
+	if *record {
+		return newRecordingServer(t, path, upstream)
+	}
+
+	fixture, err := LoadFixture(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("fixture %s is not recorded, record it with -rpctest.record", path)
+	}
+	if err != nil {
+		t.Fatalf("load fixture, record it with -rpctest.record: %v", err)
+	}
+	server := httptest.NewServer(NewReplayHandler(t, fixture))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// NewReplayHandler answers the calls with the responses of the fixture matching their method
+// and params. The exchanges of the same call are replayed in order, the last one repeatedly.
+// A call missing from the fixture fails the test.
+func NewReplayHandler(t testing.TB, fixture *Fixture) http.Handler {
+	var (
+		mu        sync.Mutex
+		exchanges = make(map[string][]Exchange)
+		next      = make(map[string]int)
+	)
+	for _, exchange := range fixture.Exchanges {
+		key := callKey(exchange.Method, exchange.Params)
+		exchanges[key] = append(exchanges[key], exchange)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call request
+		if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key := callKey(call.Method, call.Params)
+		mu.Lock()
+		recorded := exchanges[key]
+		i := next[key]
+		if i < len(recorded)-1 {
+			next[key]++
+		}
+		mu.Unlock()
+
+		if len(recorded) == 0 {
+			t.Errorf("rpctest: no recorded response for %s %s", call.Method, call.Params)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32000,"message":"no recorded response"}}`, call.ID)
+			return
+		}
+
+		exchange := recorded[i]
+		resp, err := withID(exchange.Response, call.ID)
+		if err != nil {
+			t.Errorf("rpctest: invalid recorded response for %s: %v", call.Method, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if exchange.Status != 0 {
+			w.WriteHeader(exchange.Status)
+		}
+		w.Write(resp)
+	})
+}
+
+// newRecordingServer forwards the calls to the upstream node and saves them to path when the test ends
+func newRecordingServer(t testing.TB, path, upstream string) *httptest.Server {
+	recorder := NewRecorder(nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, upstream, bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := recorder.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+
+	t.Cleanup(func() {
+		server.Close()
+		if err := recorder.Fixture().Save(path); err != nil {
+			t.Errorf("save fixture: %v", err)
+		}
+	})
+	return server
+}
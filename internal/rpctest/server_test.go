@@ -0,0 +1,111 @@
+package rpctest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func post(t *testing.T, url, body string) (int, string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	heads := []string{"0x10", "0x11"}
+	var calls int
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "eth_blockNumber"):
+			w.Write([]byte(`{"jsonrpc":"2.0","id":7,"result":"` + heads[calls] + `"}`))
+			calls++
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":8,"error":{"code":-32005,"message":"limit exceeded"}}`))
+		}
+	}))
+	defer node.Close()
+
+	// record the exchanges through the recorder
+	recorder := NewRecorder(nil)
+	client := &http.Client{Transport: recorder}
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":7,"method":"eth_blockNumber","params":[]}`,
+		`{"jsonrpc":"2.0","id":7,"method":"eth_blockNumber","params":[]}`,
+		`{"jsonrpc":"2.0","id":8,"method":"eth_getBlockByNumber","params":["0x10", true]}`,
+	} {
+		resp, err := client.Post(node.URL, "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	path := filepath.Join(t.TempDir(), "testdata", "fixture.json")
+	assert.NoError(t, recorder.Fixture().Save(path))
+
+	// replay them with the ids of the new requests
+	fixture, err := LoadFixture(path)
+	assert.NoError(t, err)
+	assert.Len(t, fixture.Exchanges, 3)
+	replay := httptest.NewServer(NewReplayHandler(t, fixture))
+	defer replay.Close()
+
+	status, body := post(t, replay.URL, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"0x10"}`, body)
+	_, body = post(t, replay.URL, `{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber","params":[]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":"0x11"}`, body)
+	// the last exchange of a call is repeated
+	_, body = post(t, replay.URL, `{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber","params":[]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":3,"result":"0x11"}`, body)
+
+	// the params match whatever their formatting
+	status, body = post(t, replay.URL, `{"jsonrpc":"2.0","id":4,"method":"eth_getBlockByNumber","params":["0x10",true]}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":4,"error":{"code":-32005,"message":"limit exceeded"}}`, body)
+}
+
+func TestReplayHandler_missingCall(t *testing.T) {
+	fake := &testing.T{}
+	replay := httptest.NewServer(NewReplayHandler(fake, &Fixture{}))
+	defer replay.Close()
+
+	status, body := post(t, replay.URL, `{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, body, "no recorded response")
+	assert.True(t, fake.Failed())
+}
+
+// fatalTB records the failure of NewServer and stops the goroutine like testing.T does
+type fatalTB struct {
+	testing.TB
+	message string
+}
+
+func (f *fatalTB) Helper() {}
+
+func (f *fatalTB) Fatalf(format string, args ...interface{}) {
+	f.message = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestNewServer_notRecorded(t *testing.T) {
+	fake := &fatalTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewServer(fake, filepath.Join(t.TempDir(), "missing.json"), "http://localhost")
+	}()
+	<-done
+	assert.Contains(t, fake.message, "missing.json is not recorded, record it with -rpctest.record")
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// BitcoinBlock is a block of a bitcoind-style node, as returned by getblock with verbosity 2
+type BitcoinBlock struct {
+	Hash              string               `json:"hash"`
+	Height            uint64               `json:"height"`
+	PreviousBlockHash string               `json:"previousblockhash"`
+	Time              uint64               `json:"time"`
+	Tx                []BitcoinTransaction `json:"tx"`
+}
+
+// BitcoinTransaction is a decoded transaction of a bitcoind-style node
+type BitcoinTransaction struct {
+	TxID string          `json:"txid"`
+	Vin  []BitcoinInput  `json:"vin"`
+	Vout []BitcoinOutput `json:"vout"`
+}
+
+// BitcoinInput spends the output Vout of transaction TxID, Coinbase is set instead
+// for the input of the coinbase transaction
+type BitcoinInput struct {
+	Coinbase string `json:"coinbase,omitempty"`
+	TxID     string `json:"txid,omitempty"`
+	Vout     uint32 `json:"vout"`
+	// Prevout is the spent output, only returned by getblock with verbosity 3
+	Prevout *BitcoinOutput `json:"prevout,omitempty"`
+}
+
+// BitcoinOutput is an output of a transaction, Value is in BTC
+type BitcoinOutput struct {
+	Value        json.Number   `json:"value"`
+	N            uint32        `json:"n"`
+	ScriptPubKey BitcoinScript `json:"scriptPubKey"`
+}
+
+type BitcoinScript struct {
+	Type    string `json:"type"`
+	Address string `json:"address,omitempty"`
+	// Addresses is returned by bitcoind before v22 instead of Address
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// PayeeAddress return the address paid by the script, empty for scripts without address, e.g. OP_RETURN
+func (s BitcoinScript) PayeeAddress() string {
+	if s.Address != "" {
+		return s.Address
+	}
+	if len(s.Addresses) == 1 {
+		return s.Addresses[0]
+	}
+	return ""
+}
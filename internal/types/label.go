@@ -0,0 +1,12 @@
+package types
+
+// AddressLabel is the name and the tags an address is known by, e.g. {"label": "hot wallet", "tags": ["treasury"]}
+type AddressLabel struct {
+	Label string   `json:"label,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// IsZero reports whether the label has neither name nor tag
+func (l AddressLabel) IsZero() bool {
+	return l.Label == "" && len(l.Tags) == 0
+}
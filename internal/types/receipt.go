@@ -0,0 +1,52 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/TrustWallet/tx-parser/internal/utils"
+)
+
+// Receipt is the outcome of a mined EVM transaction
+type Receipt struct {
+	TransactionHash string          `json:"transactionHash"`
+	BlockNumber     utils.HexUint64 `json:"blockNumber"`
+	// Status is "0x1" for success and "0x0" for failure, it is missing from the receipts
+	// of the blocks before the Byzantium fork
+	Status string `json:"status"`
+	// GasUsed and EffectiveGasPrice are hex encoded, the effective gas price is missing from the
+	// receipts of the nodes predating the London fork
+	GasUsed           string `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	// ContractAddress is the contract deployed by a transaction without recipient, empty otherwise
+	ContractAddress string `json:"contractAddress"`
+}
+
+// Fee return the fee paid for the transaction, gasPrice is the one of the transaction,
+// used when the receipt has no effective gas price
+func (r Receipt) Fee(gasPrice string) (*big.Int, error) {
+	if r.EffectiveGasPrice != "" {
+		gasPrice = r.EffectiveGasPrice
+	}
+	gasUsed, err := utils.ParseBigInt(r.GasUsed)
+	if err != nil {
+		return nil, fmt.Errorf("gas used: %w", err)
+	}
+	price, err := utils.ParseBigInt(gasPrice)
+	if err != nil {
+		return nil, fmt.Errorf("gas price: %w", err)
+	}
+	return gasUsed.Mul(gasUsed, price), nil
+}
+
+// TxStatus return the status of the transaction, empty when the receipt does not tell
+func (r Receipt) TxStatus() TxStatus {
+	switch r.Status {
+	case "":
+		return ""
+	case "0x1":
+		return StatusSuccess
+	default:
+		return StatusFailed
+	}
+}
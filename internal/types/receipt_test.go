@@ -0,0 +1,23 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceipt_Fee(t *testing.T) {
+	// 21000 gas at 20 gwei
+	fee, err := Receipt{GasUsed: "0x5208", EffectiveGasPrice: "0x4a817c800"}.Fee("0x1")
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(420000000000000), fee)
+
+	// the gas price of the transaction before the London fork
+	fee, err = Receipt{GasUsed: "0x5208"}.Fee("0x4a817c800")
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(420000000000000), fee)
+
+	_, err = Receipt{}.Fee("0x1")
+	assert.ErrorContains(t, err, "gas used")
+}
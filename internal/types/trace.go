@@ -0,0 +1,47 @@
+package types
+
+import (
+	"strings"
+)
+
+// CallFrame is a call traced by the callTracer of the node, Calls are the calls it made in turn
+type CallFrame struct {
+	// Type is the opcode of the call, e.g. CALL, DELEGATECALL, CREATE or CREATE2
+	Type string `json:"type"`
+	From string `json:"from"`
+	// To is the called contract, or the created one for CREATE and CREATE2
+	To string `json:"to"`
+	// Error is set when the call reverted, together with the calls it made
+	Error string      `json:"error,omitempty"`
+	Calls []CallFrame `json:"calls,omitempty"`
+}
+
+// TransactionTrace is the call frame of a transaction of a traced block
+type TransactionTrace struct {
+	TxHash string    `json:"txHash"`
+	Result CallFrame `json:"result"`
+}
+
+// InternalDeployments return the contracts created by the internal calls of the frame and which were
+// not reverted, the frame itself is left out
+func (f CallFrame) InternalDeployments() []Deployment {
+	var deployments []Deployment
+	var walk func(calls []CallFrame)
+	walk = func(calls []CallFrame) {
+		for _, call := range calls {
+			if call.Error != "" {
+				continue
+			}
+			opcode := strings.ToUpper(call.Type)
+			if (opcode == "CREATE" || opcode == "CREATE2") && call.To != "" {
+				deployments = append(deployments, Deployment{Address: call.To, Deployer: call.From, Opcode: opcode})
+			}
+			walk(call.Calls)
+		}
+	}
+	if f.Error == "" {
+		walk(f.Calls)
+	}
+
+	return deployments
+}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallFrame_InternalDeployments(t *testing.T) {
+	var frame CallFrame
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "CALL", "from": "`+addr1+`", "to": "`+addr2+`",
+		"calls": [
+			{"type": "CREATE2", "from": "`+addr2+`", "to": "`+addr3+`"},
+			{"type": "DELEGATECALL", "from": "`+addr2+`", "to": "0x01", "calls": [
+				{"type": "CREATE", "from": "`+addr2+`", "to": "0x02"}
+			]},
+			{"type": "CALL", "from": "`+addr2+`", "to": "0x03", "error": "execution reverted", "calls": [
+				{"type": "CREATE", "from": "0x03", "to": "0x04"}
+			]}
+		]
+	}`), &frame))
+
+	assert.Equal(t, []Deployment{
+		{Address: addr3, Deployer: addr2, Opcode: "CREATE2"},
+		{Address: "0x02", Deployer: addr2, Opcode: "CREATE"},
+	}, frame.InternalDeployments())
+
+	// a reverted transaction creates nothing
+	frame.Error = "out of gas"
+	assert.Empty(t, frame.InternalDeployments())
+}
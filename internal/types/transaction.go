@@ -1,6 +1,9 @@
 package types
 
 import (
+	"math/big"
+	"strings"
+
 	"github.com/TrustWallet/tx-parser/internal/utils"
 )
 
@@ -16,4 +19,216 @@ type Transaction struct {
 	Hash             string          `json:"hash"`
 	TransactionIndex string          `json:"transactionIndex"`
 	Timestamp        utils.HexUint64 `json:"timestamp"`
+	// Input is the call data of an EVM transaction, "0x" for a plain transfer
+	Input string `json:"input,omitempty"`
+	// Type is the EIP-2718 type of an EVM transaction, e.g. "0x2"
+	Type string `json:"type,omitempty"`
+	// Status is the execution status, empty when it is unknown
+	Status TxStatus `json:"status,omitempty"`
+	// Fee is the fee paid by the sender in the smallest unit, encoded as Value, empty when it is unknown
+	Fee string `json:"fee,omitempty"`
+	// Chain is the name of the chain the transaction belongs to
+	Chain string `json:"chain,omitempty"`
+	// Deployments are the contracts created by an EVM transaction, by the transaction itself when To is
+	// empty and by the CREATE and CREATE2 of its internal calls when the block is traced
+	Deployments []Deployment `json:"deployments,omitempty"`
+	// Inputs and Outputs are set on UTXO chains, From and To are then the addresses
+	// of the first input and output and Value is the sum of the outputs
+	Inputs  []UTXO `json:"inputs,omitempty"`
+	Outputs []UTXO `json:"outputs,omitempty"`
+}
+
+// TxStatus is the execution status of a transaction
+type TxStatus string
+
+const (
+	StatusSuccess TxStatus = "success"
+	// StatusFailed is a reverted EVM transaction, it is mined but has no effect besides its fee
+	StatusFailed TxStatus = "failed"
+)
+
+// Direction is the way a transaction goes for one of its addresses
+type Direction string
+
+const (
+	DirectionInbound  Direction = "inbound"
+	DirectionOutbound Direction = "outbound"
+	// DirectionSelf is a transaction the address sends to itself only
+	DirectionSelf Direction = "self"
+)
+
+// Deployment is a contract created by a transaction
+type Deployment struct {
+	// Address is the created contract
+	Address string `json:"address"`
+	// Deployer is the sender of a deploying transaction or the contract creating it in an internal call
+	Deployer string `json:"deployer"`
+	// Opcode is CREATE or CREATE2 for a contract created by an internal call, empty for the contract
+	// of a deploying transaction
+	Opcode string `json:"opcode,omitempty"`
+}
+
+// TxEvent is the kind of a transaction
+type TxEvent string
+
+const (
+	// EventTransfer moves the native value only, e.g. a bitcoin transaction
+	EventTransfer TxEvent = "transfer"
+	// EventCall calls a contract
+	EventCall TxEvent = "call"
+	// EventDeployment creates contracts, directly or by its internal calls
+	EventDeployment TxEvent = "deployment"
+)
+
+// UTXO is a transaction output of a UTXO chain, Value is in the smallest unit of the chain, e.g. satoshi
+type UTXO struct {
+	Address string `json:"address"`
+	Value   string `json:"value"`
+}
+
+// Addresses return the distinct addresses sending or receiving the transaction and the deployers
+// and the contracts of its deployments, compared case-insensitively
+func (tx Transaction) Addresses() []string {
+	var addresses []string
+	seen := make(map[string]struct{})
+	add := func(address string) {
+		if address == "" {
+			return
+		}
+		key := utils.NormalizeAddress(address)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		addresses = append(addresses, address)
+	}
+
+	add(tx.From)
+	add(tx.To)
+	for _, deployment := range tx.Deployments {
+		add(deployment.Deployer)
+		add(deployment.Address)
+	}
+	for _, in := range tx.Inputs {
+		add(in.Address)
+	}
+	for _, out := range tx.Outputs {
+		add(out.Address)
+	}
+
+	return addresses
+}
+
+// Direction return the direction of the transaction for one of its addresses. An address
+// sending the transaction is outbound, unless every output goes back to it, e.g. the
+// change of a UTXO transaction does not make it a self send.
+func (tx Transaction) Direction(address string) Direction {
+	sends := utils.SameAddress(tx.From, address)
+	for _, input := range tx.Inputs {
+		sends = sends || utils.SameAddress(input.Address, address)
+	}
+	if !sends {
+		return DirectionInbound
+	}
+
+	for _, receiver := range tx.receivers() {
+		if !utils.SameAddress(receiver, address) {
+			return DirectionOutbound
+		}
+	}
+
+	return DirectionSelf
+}
+
+// Counterparties return the addresses exchanging the transaction with the address, the
+// address itself for a self send
+func (tx Transaction) Counterparties(address string) []string {
+	switch tx.Direction(address) {
+	case DirectionSelf:
+		return []string{address}
+	case DirectionOutbound:
+		return distinctExcept(tx.receivers(), address)
+	default:
+		senders := []string{tx.From}
+		for _, input := range tx.Inputs {
+			senders = append(senders, input.Address)
+		}
+		return distinctExcept(senders, address)
+	}
+}
+
+// receivers return the recipient of an EVM transaction, the deployed contract of a deploying one, or the
+// output addresses of a UTXO one
+func (tx Transaction) receivers() []string {
+	if tx.creation() {
+		for _, deployment := range tx.Deployments {
+			if deployment.Opcode == "" {
+				return []string{deployment.Address}
+			}
+		}
+	}
+	if len(tx.Outputs) == 0 {
+		return []string{tx.To}
+	}
+
+	receivers := make([]string, len(tx.Outputs))
+	for i, output := range tx.Outputs {
+		receivers[i] = output.Address
+	}
+	return receivers
+}
+
+// creation reports whether the transaction deploys a contract, an EVM transaction without recipient
+func (tx Transaction) creation() bool {
+	return tx.To == "" && len(tx.Outputs) == 0 && tx.MethodSelector() != ""
+}
+
+func distinctExcept(addresses []string, except string) []string {
+	var distinct []string
+	seen := map[string]struct{}{utils.NormalizeAddress(except): {}, "": {}}
+	for _, address := range addresses {
+		key := utils.NormalizeAddress(address)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		distinct = append(distinct, address)
+	}
+	return distinct
+}
+
+// ValueInt return the value as an integer, it is hex encoded on the EVM chains and decimal
+// on the UTXO chains
+func (tx Transaction) ValueInt() (*big.Int, error) {
+	return utils.ParseBigInt(tx.Value)
+}
+
+// MethodSelector return the first 4 bytes of the call data of an EVM transaction, lower case
+// with the 0x prefix, empty for a plain transfer
+func (tx Transaction) MethodSelector() string {
+	input := strings.TrimPrefix(strings.TrimPrefix(tx.Input, "0x"), "0X")
+	if len(input) < 8 {
+		return ""
+	}
+	return "0x" + strings.ToLower(input[:8])
+}
+
+// Event return the kind of the transaction
+func (tx Transaction) Event() TxEvent {
+	switch {
+	case len(tx.Deployments) > 0 || tx.creation():
+		return EventDeployment
+	case tx.MethodSelector() != "":
+		return EventCall
+	default:
+		return EventTransfer
+	}
+}
+
+// FeeInt return the fee as an integer, zero when it is unknown
+func (tx Transaction) FeeInt() (*big.Int, error) {
+	if tx.Fee == "" {
+		return new(big.Int), nil
+	}
+	return utils.ParseBigInt(tx.Fee)
 }
@@ -0,0 +1,121 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	addr1 = "0xf15689636571dba322b48e9ec9ba6cfb3df818e1"
+	addr2 = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+	addr3 = "0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97"
+)
+
+func TestTransaction_Direction(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   Transaction
+		want Direction
+	}{
+		{"inbound", Transaction{From: addr2, To: addr1}, DirectionInbound},
+		{"outbound", Transaction{From: addr1, To: addr2}, DirectionOutbound},
+		{"self", Transaction{From: addr1, To: strings.ToUpper(addr1)}, DirectionSelf},
+		{"contract creation", Transaction{From: addr1}, DirectionOutbound},
+		{"utxo with change", Transaction{
+			Inputs:  []UTXO{{Address: addr1}},
+			Outputs: []UTXO{{Address: addr2}, {Address: addr1}},
+		}, DirectionOutbound},
+		{"utxo consolidation", Transaction{
+			Inputs:  []UTXO{{Address: addr1}, {Address: addr1}},
+			Outputs: []UTXO{{Address: addr1}},
+		}, DirectionSelf},
+		{"utxo payee", Transaction{
+			Inputs:  []UTXO{{Address: addr2}},
+			Outputs: []UTXO{{Address: addr1}, {Address: addr2}},
+		}, DirectionInbound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.tx.Direction(addr1))
+		})
+	}
+}
+
+func TestTransaction_Counterparties(t *testing.T) {
+	utxo := Transaction{
+		Inputs:  []UTXO{{Address: addr1}, {Address: addr3}},
+		Outputs: []UTXO{{Address: addr2}, {Address: addr1}, {Address: addr2}},
+	}
+	assert.Equal(t, []string{addr2}, utxo.Counterparties(addr1))
+	assert.Equal(t, []string{addr1, addr3}, utxo.Counterparties(addr2))
+
+	evm := Transaction{From: addr1, To: strings.ToUpper(addr1)}
+	assert.Equal(t, []string{addr1}, evm.Counterparties(addr1))
+	evm = Transaction{From: addr1, To: addr2}
+	assert.Equal(t, []string{addr1}, evm.Counterparties(addr2))
+	// a contract creation has no recipient
+	evm = Transaction{From: addr1}
+	assert.Empty(t, evm.Counterparties(addr1))
+	// until its contract is known
+	evm = Transaction{From: addr1, Input: "0x60806040", Deployments: []Deployment{{Address: addr2, Deployer: addr1}}}
+	assert.Equal(t, []string{addr2}, evm.Counterparties(addr1))
+	assert.Equal(t, []string{addr1}, evm.Counterparties(addr2))
+}
+
+func TestTransaction_Event(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   Transaction
+		want TxEvent
+	}{
+		{"transfer", Transaction{From: addr1, To: addr2, Input: "0x"}, EventTransfer},
+		{"utxo", Transaction{From: addr1, To: addr2, Outputs: []UTXO{{Address: addr2}}}, EventTransfer},
+		{"call", Transaction{From: addr1, To: addr2, Input: "0xa9059cbb"}, EventCall},
+		{"deployment", Transaction{From: addr1, Input: "0x60806040"}, EventDeployment},
+		{"internal deployment", Transaction{From: addr1, To: addr2, Input: "0x12345678",
+			Deployments: []Deployment{{Address: addr3, Deployer: addr2, Opcode: "CREATE2"}}}, EventDeployment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.tx.Event())
+		})
+	}
+
+	// the deployers and the contracts are addresses of the transaction
+	tx := tests[4].tx
+	assert.Equal(t, []string{addr1, addr2, addr3}, tx.Addresses())
+}
+
+func TestTransaction_ValueInt(t *testing.T) {
+	value, err := Transaction{Value: "0xde0b6b3a7640000"}.ValueInt()
+	assert.NoError(t, err)
+	assert.Equal(t, "1000000000000000000", value.String())
+
+	value, err = Transaction{Value: "19000000"}.ValueInt()
+	assert.NoError(t, err)
+	assert.Equal(t, "19000000", value.String())
+
+	_, err = Transaction{Value: ""}.ValueInt()
+	assert.Error(t, err)
+}
+
+func TestTransaction_FeeInt(t *testing.T) {
+	fee, err := Transaction{Fee: "0x135d943657a90"}.FeeInt()
+	assert.NoError(t, err)
+	assert.Equal(t, "340682231610000", fee.String())
+
+	// an unknown fee is zero
+	fee, err = Transaction{}.FeeInt()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fee.Sign())
+}
+
+func TestTransaction_MethodSelector(t *testing.T) {
+	// transfer(address,uint256)
+	tx := Transaction{Input: "0xA9059CBB000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045"}
+	assert.Equal(t, "0xa9059cbb", tx.MethodSelector())
+	assert.Equal(t, "", Transaction{Input: "0x"}.MethodSelector())
+	assert.Equal(t, "", Transaction{}.MethodSelector())
+}
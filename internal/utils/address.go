@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// AddressLength is the expected length of the address in bytes
+const AddressLength = 20
+
+var (
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrInvalidChecksum = errors.New("invalid address checksum")
+)
+
+// IsHexAddress verifies whether a string can represent a valid hex-encoded
+// address with 0x prefix.
+func IsHexAddress(s string) bool {
+	input := []byte(s)
+	if !bytesHave0xPrefix(input) {
+		return false
+	}
+	input = input[2:]
+	if len(input) != 2*AddressLength {
+		return false
+	}
+	for _, c := range input {
+		if decodeNibble(c) == badNibble {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateAddress checks the address is a 20-byte 0x-hex string, a mixed-case address
+// must also match its EIP-55 checksum. All lower or upper case addresses carry no checksum.
+func ValidateAddress(s string) error {
+	if !IsHexAddress(s) {
+		return ErrInvalidAddress
+	}
+
+	hexPart := s[2:]
+	if hexPart == strings.ToLower(hexPart) || hexPart == strings.ToUpper(hexPart) {
+		return nil
+	}
+	if hexPart != ToChecksumAddress(s)[2:] {
+		return ErrInvalidChecksum
+	}
+
+	return nil
+}
+
+// ToChecksumAddress returns the EIP-55 mixed-case checksum form of a hex address,
+// the input is returned unchanged if it is not a valid hex address.
+func ToChecksumAddress(s string) string {
+	if !IsHexAddress(s) {
+		return s
+	}
+
+	lower := []byte(strings.ToLower(s[2:]))
+	digest := hex.EncodeToString(Keccak256(lower))
+	for i, c := range lower {
+		if c >= 'a' && c <= 'f' && digest[i] >= '8' {
+			lower[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(lower)
+}
+
+// NormalizeAddress returns the form under which an address is stored and compared. The Base58
+// addresses (P2PKH, P2SH) are case-sensitive and kept as is, two of them differing only in case are
+// distinct addresses. The other addresses, 0x-hex and Bech32, are case-insensitive and lowered.
+func NormalizeAddress(s string) string {
+	if isBase58Address(s) {
+		return s
+	}
+
+	return strings.ToLower(s)
+}
+
+// SameAddress reports whether a and b are the same address once normalized
+func SameAddress(a, b string) bool {
+	return NormalizeAddress(a) == NormalizeAddress(b)
+}
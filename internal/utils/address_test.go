@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsHexAddress(t *testing.T) {
+	assert.True(t, IsHexAddress("0xf15689636571dba322b48e9ec9ba6cfb3df818e1"))
+	assert.True(t, IsHexAddress("0XF15689636571DBA322B48E9EC9BA6CFB3DF818E1"))
+	assert.False(t, IsHexAddress(""))
+	assert.False(t, IsHexAddress("hello"))
+	assert.False(t, IsHexAddress("f15689636571dba322b48e9ec9ba6cfb3df818e1"))
+	assert.False(t, IsHexAddress("0xf15689636571dba322b48e9ec9ba6cfb3df818"))
+	assert.False(t, IsHexAddress("0xg15689636571dba322b48e9ec9ba6cfb3df818e1"))
+}
+
+// test vectors from EIP-55
+var checksumAddresses = []string{
+	"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+	"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	"0x52908400098527886E0F7030069857D2E4169EE7",
+	"0xde709f2102306220921060314715629080e2fb77",
+}
+
+func TestToChecksumAddress(t *testing.T) {
+	for _, addr := range checksumAddresses {
+		assert.Equal(t, addr, ToChecksumAddress(strings.ToLower(addr)))
+		assert.Equal(t, addr, ToChecksumAddress(addr))
+	}
+	assert.Equal(t, "hello", ToChecksumAddress("hello"))
+}
+
+func TestValidateAddress(t *testing.T) {
+	for _, addr := range checksumAddresses {
+		assert.NoError(t, ValidateAddress(addr))
+		assert.NoError(t, ValidateAddress(strings.ToLower(addr)))
+		assert.NoError(t, ValidateAddress("0x"+strings.ToUpper(addr[2:])))
+	}
+
+	assert.ErrorIs(t, ValidateAddress(""), ErrInvalidAddress)
+	assert.ErrorIs(t, ValidateAddress("hello"), ErrInvalidAddress)
+	assert.ErrorIs(t, ValidateAddress("0x5aAeb6053F"), ErrInvalidAddress)
+	assert.ErrorIs(t, ValidateAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"), ErrInvalidChecksum)
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	for in, want := range map[string]string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed": "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4": "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4": "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2":         "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy":         "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn":         "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		"":                                           "",
+	} {
+		assert.Equal(t, want, NormalizeAddress(in), in)
+	}
+
+	assert.True(t, SameAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"))
+	assert.False(t, SameAddress("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "1BVBMSEYSTWETQTFN5AU4M4GFG7XJANVN2"))
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SatoshiPerBitcoin is the number of satoshis in one BTC
+const SatoshiPerBitcoin = 100_000_000
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	bech32Charset  = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// ValidateBitcoinAddress checks the address is a Base58Check (P2PKH, P2SH) or a
+// Bech32/Bech32m (SegWit) address. The version byte and the human-readable part are
+// not checked, so the addresses of every network of the Bitcoin family are accepted.
+func ValidateBitcoinAddress(s string) error {
+	if i := strings.LastIndexByte(s, '1'); i > 0 && isBech32Prefix(s[:i]) {
+		if err := validateSegwitAddress(s); err == nil || errors.Is(err, ErrInvalidChecksum) {
+			return err
+		}
+	}
+
+	return validateBase58Address(s)
+}
+
+// isBase58Address reports whether s has the shape of a Base58Check address, 25 to 35 characters of
+// the Base58 alphabet, its checksum aside. The hex and Bech32 addresses are longer.
+func isBase58Address(s string) bool {
+	if len(s) < 25 || len(s) > 35 {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if strings.IndexByte(base58Alphabet, c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isBech32Prefix(hrp string) bool {
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+// validateBase58Address checks a version byte, a 20-byte hash and a 4-byte double SHA-256 checksum
+func validateBase58Address(s string) error {
+	decoded, ok := decodeBase58(s)
+	if !ok || len(decoded) != 1+AddressLength+4 {
+		return ErrInvalidAddress
+	}
+
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if string(second[:4]) != string(checksum) {
+		return ErrInvalidChecksum
+	}
+
+	return nil
+}
+
+func decodeBase58(s string) ([]byte, bool) {
+	if s == "" {
+		return nil, false
+	}
+
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base58Alphabet, c)
+		if digit < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	// every leading '1' stands for a leading zero byte
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), true
+}
+
+// validateSegwitAddress checks a BIP-173 (witness v0) or BIP-350 (witness v1+) address
+func validateSegwitAddress(s string) error {
+	if len(s) > 90 || (s != strings.ToLower(s) && s != strings.ToUpper(s)) {
+		return ErrInvalidAddress
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	hrp, dataPart := s[:sep], s[sep+1:]
+	if len(dataPart) < 6+1 {
+		return ErrInvalidAddress
+	}
+	data := make([]byte, len(dataPart))
+	for i, c := range []byte(dataPart) {
+		v := strings.IndexByte(bech32Charset, c)
+		if v < 0 {
+			return ErrInvalidAddress
+		}
+		data[i] = byte(v)
+	}
+
+	version := data[0]
+	program, ok := convertBits(data[1:len(data)-6], 5, 8)
+	switch {
+	case !ok || version > 16 || len(program) < 2 || len(program) > 40:
+		return ErrInvalidAddress
+	case version == 0 && len(program) != 20 && len(program) != 32:
+		return ErrInvalidAddress
+	}
+
+	want := uint32(bech32Const)
+	if version > 0 {
+		want = bech32mConst
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), data...)) != want {
+		return ErrInvalidChecksum
+	}
+
+	return nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for _, c := range []byte(hrp) {
+		expanded = append(expanded, c>>5)
+	}
+	expanded = append(expanded, 0)
+	for _, c := range []byte(hrp) {
+		expanded = append(expanded, c&31)
+	}
+	return expanded
+}
+
+// convertBits regroups bits of fromBits-wide values into toBits-wide values without padding
+func convertBits(data []byte, fromBits, toBits uint) ([]byte, bool) {
+	var (
+		acc  uint
+		bits uint
+		out  []byte
+	)
+	maxValue := uint(1)<<toBits - 1
+	for _, v := range data {
+		acc = acc<<fromBits | uint(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+	if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, false
+	}
+
+	return out, true
+}
+
+// ParseBitcoinAmount converts a decimal BTC amount, e.g. "0.00100000", to satoshis
+func ParseBitcoinAmount(s string) (uint64, error) {
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || len(fraction) > 8 {
+		return 0, ErrInvalidAmount
+	}
+
+	btc, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil || btc > (1<<64-1)/SatoshiPerBitcoin {
+		return 0, ErrInvalidAmount
+	}
+	sats := uint64(0)
+	if fraction != "" {
+		sats, err = strconv.ParseUint(fraction+strings.Repeat("0", 8-len(fraction)), 10, 64)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+	}
+
+	return btc*SatoshiPerBitcoin + sats, nil
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBitcoinAddress(t *testing.T) {
+	valid := []string{
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		// test vectors from BIP-173 and BIP-350
+		"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+		"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3",
+		"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+	}
+	for _, addr := range valid {
+		assert.NoError(t, ValidateBitcoinAddress(addr), addr)
+	}
+
+	for addr, want := range map[string]error{
+		"": ErrInvalidAddress,
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed": ErrInvalidAddress,
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3":         ErrInvalidChecksum,
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN0":         ErrInvalidAddress,
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5": ErrInvalidChecksum,
+		// mixed case
+		"bc1qW508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4": ErrInvalidAddress,
+		// witness v0 with a Bech32m checksum
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh": ErrInvalidChecksum,
+		// witness v1 with a Bech32 checksum
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd": ErrInvalidChecksum,
+	} {
+		assert.ErrorIs(t, ValidateBitcoinAddress(addr), want, addr)
+	}
+}
+
+func TestParseBitcoinAmount(t *testing.T) {
+	for s, want := range map[string]uint64{
+		"0":           0,
+		"50.00000000": 5_000_000_000,
+		"0.00100000":  100_000,
+		"0.00000001":  1,
+		"21000000":    2_100_000_000_000_000,
+		"1.5":         150_000_000,
+	} {
+		sats, err := ParseBitcoinAmount(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, sats, s)
+	}
+
+	for _, s := range []string{"", ".5", "-1", "0.000000001", "1e-05", "abc"} {
+		_, err := ParseBitcoinAmount(s)
+		assert.ErrorIs(t, err, ErrInvalidAmount, s)
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// keccak256Rate is the sponge rate of Keccak-256 in bytes (1600 - 2*256 bits)
+const keccak256Rate = 136
+
+// round constants of Keccak-f[1600]
+var keccakRC = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// rotation offsets and lane permutation of the rho and pi steps
+var (
+	keccakRotc = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+	keccakPiln = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+)
+
+// keccakF1600 applies the Keccak-f[1600] permutation on the state
+func keccakF1600(a *[25]uint64) {
+	var bc [5]uint64
+	for round := 0; round < 24; round++ {
+		// theta
+		for i := 0; i < 5; i++ {
+			bc[i] = a[i] ^ a[i+5] ^ a[i+10] ^ a[i+15] ^ a[i+20]
+		}
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ bits.RotateLeft64(bc[(i+1)%5], 1)
+			for j := 0; j < 25; j += 5 {
+				a[j+i] ^= t
+			}
+		}
+
+		// rho and pi
+		t := a[1]
+		for i := 0; i < 24; i++ {
+			j := keccakPiln[i]
+			t, a[j] = a[j], bits.RotateLeft64(t, keccakRotc[i])
+		}
+
+		// chi
+		for j := 0; j < 25; j += 5 {
+			for i := 0; i < 5; i++ {
+				bc[i] = a[j+i]
+			}
+			for i := 0; i < 5; i++ {
+				a[j+i] ^= ^bc[(i+1)%5] & bc[(i+2)%5]
+			}
+		}
+
+		// iota
+		a[0] ^= keccakRC[round]
+	}
+}
+
+// keccak256 is a hash.Hash computing the legacy Keccak-256 digest used by Ethereum,
+// which differs from the standardized SHA3-256 by its padding.
+type keccak256 struct {
+	state [25]uint64
+	buf   [keccak256Rate]byte
+	n     int
+}
+
+// NewKeccak256 creates a new Keccak-256 hash.
+func NewKeccak256() hash.Hash {
+	return &keccak256{}
+}
+
+// Keccak256 calculates and returns the Keccak-256 hash of the input data.
+func Keccak256(data ...[]byte) []byte {
+	h := NewKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+
+	return h.Sum(nil)
+}
+
+func (k *keccak256) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		n := copy(k.buf[k.n:], p)
+		k.n += n
+		p = p[n:]
+		if k.n == keccak256Rate {
+			k.absorb()
+		}
+	}
+
+	return written, nil
+}
+
+func (k *keccak256) absorb() {
+	for i := 0; i < keccak256Rate/8; i++ {
+		k.state[i] ^= binary.LittleEndian.Uint64(k.buf[i*8:])
+	}
+	keccakF1600(&k.state)
+	k.n = 0
+}
+
+// Sum appends the current hash to b and returns the resulting slice,
+// it does not change the underlying hash state.
+func (k *keccak256) Sum(b []byte) []byte {
+	dup := *k
+	for i := dup.n; i < keccak256Rate; i++ {
+		dup.buf[i] = 0
+	}
+	dup.buf[dup.n] ^= 0x01
+	dup.buf[keccak256Rate-1] ^= 0x80
+	dup.n = keccak256Rate
+	dup.absorb()
+
+	var out [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], dup.state[i])
+	}
+
+	return append(b, out[:]...)
+}
+
+func (k *keccak256) Reset() {
+	*k = keccak256{}
+}
+
+func (k *keccak256) Size() int { return 32 }
+
+func (k *keccak256) BlockSize() int { return keccak256Rate }
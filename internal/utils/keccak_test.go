@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"transfer(address,uint256)", "a9059cbb2ab09eb219583f4a59a5d0623ade346d962bcd4e46b11da047c9049b"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expect, hex.EncodeToString(Keccak256([]byte(tt.input))), tt.input)
+	}
+}
+
+func TestKeccak256_multiBlock(t *testing.T) {
+	// input longer than the sponge rate must give the same digest however it is written
+	input := []byte(strings.Repeat("a", 3*keccak256Rate+7))
+	expect := Keccak256(input)
+
+	h := NewKeccak256()
+	for i := 0; i < len(input); i += 10 {
+		end := i + 10
+		if end > len(input) {
+			end = len(input)
+		}
+		h.Write(input[i:end])
+	}
+	assert.Equal(t, expect, h.Sum(nil))
+
+	h.Reset()
+	h.Write(input[:keccak256Rate])
+	assert.Equal(t, Keccak256(input[:keccak256Rate]), h.Sum(nil))
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 // EncodeUint64 encodes i as a hex string with 0x prefix.
@@ -12,6 +14,52 @@ func EncodeUint64(i uint64) string {
 	return string(strconv.AppendUint(enc, i, 16))
 }
 
+// ParseBigInt parses a 0x-prefixed hex or a decimal unsigned integer
+func ParseBigInt(s string) (*big.Int, error) {
+	digits, base := s, 10
+	if len(s) >= 2 && bytesHave0xPrefix([]byte(s)) {
+		digits, base = s[2:], 16
+	}
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return n, nil
+}
+
+// FormatUnits formats an amount of the smallest unit as a decimal of the unit with the given
+// decimals, e.g. wei as ETH with 18 decimals, without trailing zeros
+func FormatUnits(amount *big.Int, decimals int) string {
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	formatted := whole
+	if fraction != "" {
+		formatted += "." + fraction
+	}
+	if amount.Sign() < 0 {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// ParseUnits parses a non-negative decimal of the unit with the given decimals as an amount of
+// the smallest unit, e.g. "1.5" ETH as wei with 18 decimals, the inverse of FormatUnits
+func ParseUnits(s string, decimals int) (*big.Int, error) {
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || len(fraction) > decimals || strings.ContainsAny(whole+fraction, "+-") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	amount, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", decimals-len(fraction)), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
 // HexUint64 is a custom type based on uint64 that can json unmarshal hex string to uint64.
 type HexUint64 uint64
 
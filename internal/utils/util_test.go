@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/json"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +15,51 @@ func TestEncodeUint64(t *testing.T) {
 	assert.Equal(t, "0x1228c0d", hex)
 }
 
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 18, "0.000000000000000001"},
+		{"1500000000000000000", 18, "1.5"},
+		{"123456789000000000000", 18, "123.456789"},
+		{"-2500000000000000000", 18, "-2.5"},
+		{"150000000", 8, "1.5"},
+		{"42", 0, "42"},
+	}
+	for _, tt := range tests {
+		amount, _ := new(big.Int).SetString(tt.amount, 10)
+		assert.Equal(t, tt.want, FormatUnits(amount, tt.decimals), tt.amount)
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{"10", 18, "10000000000000000000"},
+		{"1.5", 18, "1500000000000000000"},
+		{"0.000000000000000001", 18, "1"},
+		{"0.5", 8, "50000000"},
+		{"42", 0, "42"},
+	}
+	for _, tt := range tests {
+		amount, err := ParseUnits(tt.value, tt.decimals)
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, amount.String(), tt.value)
+		assert.Equal(t, tt.value, FormatUnits(amount, tt.decimals))
+	}
+
+	for _, value := range []string{"", ".5", "-1", "1.123456789", "1e3", "0x10"} {
+		_, err := ParseUnits(value, 8)
+		assert.Error(t, err, value)
+	}
+}
+
 func TestHexUInt64_UnmarshalJSON(t *testing.T) {
 	data := map[string]string{
 		// 19041293